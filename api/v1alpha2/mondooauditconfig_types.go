@@ -33,6 +33,29 @@ type MondooAuditConfigSpec struct {
 
 type Filtering struct {
 	Namespaces FilteringSpec `json:"namespaces,omitempty"`
+	// Kinds limits the kinds of Kubernetes resources which are watched/scanned. Only the workload kinds
+	// scanned by the operator (Pods, Deployments, ReplicaSets, StatefulSets, DaemonSets, Jobs and CronJobs)
+	// are filtered. Custom resources configured for the admission webhook are not affected.
+	Kinds KindFilteringSpec `json:"kinds,omitempty"`
+}
+
+type KindFilteringSpec struct {
+	// Include is the list of kinds to watch/scan. Setting Include overrides anything in the Exclude list.
+	Include []GroupVersionKind `json:"include,omitempty"`
+
+	// Exclude is the list of kinds to ignore for any watching/scanning actions.
+	Exclude []GroupVersionKind `json:"exclude,omitempty"`
+}
+
+// GroupVersionKind identifies a kind of Kubernetes resources
+type GroupVersionKind struct {
+	// Group is the API group of the kind, e.g. "apps". Use an empty string for the core group.
+	Group string `json:"group,omitempty"`
+	// Version is the API version of the kind, e.g. "v1". If empty, every version matches.
+	Version string `json:"version,omitempty"`
+	// Kind is the name of the kind, e.g. "Deployment"
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
 }
 
 type FilteringSpec struct {
@@ -44,6 +67,11 @@ type FilteringSpec struct {
 	// Exclude is the list of resources to ignore for any watching/scanning actions. Use this if
 	// the goal is to watch/scan all resources except for this Exclude list.
 	Exclude []string `json:"exclude,omitempty"`
+
+	// Selector limits the watching/scanning actions to the Namespaces with matching labels. It is combined
+	// with Include and Exclude, so a Namespace has to match both.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 }
 
 type ConsoleIntegration struct {
@@ -52,9 +80,15 @@ type ConsoleIntegration struct {
 
 // CertificateProvisioning defines the certificate provisioning configuration within the cluster.
 type CertificateProvisioning struct {
-	// +kubebuilder:validation:Enum=cert-manager;openshift;manual
+	// +kubebuilder:validation:Enum=cert-manager;openshift;manual;self-signed
 	// +kubebuilder:default=manual
 	Mode CertificateProvisioningMode `json:"mode,omitempty"`
+	// ExpiryWarningDays is the remaining validity of the webhook serving certificate below which the
+	// AdmissionDegraded condition is set. In "self-signed" mode the certificate is rotated at the same time.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=180
+	// +kubebuilder:default=30
+	ExpiryWarningDays int32 `json:"expiryWarningDays,omitempty"`
 }
 
 // Scanner defines the settings for the Mondoo scanner that will be running in the cluster. The same scanner
@@ -126,7 +160,10 @@ type Admission struct {
 	// Mode represents whether the webhook will behave in a "permissive" mode (the default) which
 	// will only scan and report on k8s resources or "enforcing" mode where depending
 	// on the scan results may reject the k8s resource creation/modification.
-	// +kubebuilder:validation:Enum=permissive;enforcing
+	// The "audit" mode admits all resources, but records a Kubernetes Event for every resource
+	// "enforcing" mode would have denied.
+	// The "async" mode admits all resources right away and scans them in the background.
+	// +kubebuilder:validation:Enum=permissive;enforcing;audit;async
 	// +kubebuilder:default=permissive
 	Mode AdmissionMode `json:"mode,omitempty"`
 	// Number of replicas for the admission webhook.
@@ -140,6 +177,188 @@ type Admission struct {
 	// during its operation.
 	// +kubebuilder:default=mondoo-operator-webhook
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// ScoreThreshold is the minimum score (0-100) a resource needs to reach to be admitted in "enforcing" mode.
+	// If neither ScoreThreshold nor MaxSeverity is set, only resources with a perfect score of 100 are admitted.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	ScoreThreshold *int32 `json:"scoreThreshold,omitempty"`
+	// MaxSeverity is the highest severity of a failing check which is still admitted in "enforcing" mode.
+	// For example, "high" admits resources with low, medium and high findings, but denies resources
+	// with critical findings.
+	// +kubebuilder:validation:Enum=none;low;medium;high;critical
+	MaxSeverity AdmissionSeverity `json:"maxSeverity,omitempty"`
+	// Exemptions lists the requesters which bypass enforcement. Their changes are still scanned
+	// and reported, but always admitted.
+	Exemptions AdmissionExemptions `json:"exemptions,omitempty"`
+	// Resources lists the resources which are checked at admission. If empty, Pods, Deployments, DaemonSets,
+	// StatefulSets, Jobs and CronJobs are checked. Custom resources are supported as well.
+	Resources []AdmissionResource `json:"resources,omitempty"`
+	// Cache configures the in-memory cache of scan results in the webhook. Identical objects which are
+	// applied repeatedly, e.g. by GitOps tooling, are answered from the cache.
+	Cache AdmissionCache `json:"cache,omitempty"`
+	// FailurePolicy defines how the webhook behaves if a resource cannot be scanned, e.g. because the
+	// scan API is unavailable or too slow. "fail-open" admits the resource, "fail-open-with-event" admits
+	// the resource and records a Kubernetes Event on it, and "fail-closed" denies the resource.
+	// If not set, "fail-closed" is used in "enforcing" mode and "fail-open" otherwise.
+	// +kubebuilder:validation:Enum=fail-open;fail-closed;fail-open-with-event
+	FailurePolicy AdmissionFailurePolicy `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds is the time the API server waits for the webhook to respond. The webhook
+	// stops waiting for the scan shortly before, so it can still answer according to the FailurePolicy.
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=30
+	// +kubebuilder:default=20
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// Rollout limits "enforcing" mode to a share of the admission requests. The remaining requests
+	// are handled in the ShadowMode. Only used in "enforcing" mode.
+	// +optional
+	Rollout *AdmissionRollout `json:"rollout,omitempty"`
+	// Mutation configures an optional mutating webhook
+	// +optional
+	Mutation AdmissionMutation `json:"mutation,omitempty"`
+	// ImagePolicy restricts the images of workloads. It is evaluated by the webhook itself before
+	// the scan, so violations are denied instantly in "enforcing" mode, even if the scan API is unavailable.
+	// +optional
+	ImagePolicy AdmissionImagePolicy `json:"imagePolicy,omitempty"`
+	// ScanApiProtection limits the load the webhook puts on the scan API. Scans which are not attempted
+	// are handled according to the FailurePolicy.
+	// +optional
+	ScanApiProtection AdmissionScanApiProtection `json:"scanApiProtection,omitempty"`
+	// Async configures the background scans of the "async" mode
+	// +optional
+	Async AdmissionAsync `json:"async,omitempty"`
+	// ConnectAuditing records interactive access to Pods with exec, attach and port-forward
+	// +optional
+	ConnectAuditing AdmissionConnectAuditing `json:"connectAuditing,omitempty"`
+}
+
+// AdmissionConnectAuditing configures the auditing of exec, attach and port-forward requests to Pods
+type AdmissionConnectAuditing struct {
+	// Enable records every exec, attach and port-forward request to a Pod with the user, the Pod and the command
+	// as a Kubernetes Event on the Pod and reports it to Mondoo. It is independent of the admission mode.
+	Enable bool `json:"enable,omitempty"`
+	// DenyExecInProduction denies exec and attach requests to Pods in production Namespaces.
+	// Exempted requesters are still allowed. Requires Enable.
+	DenyExecInProduction bool `json:"denyExecInProduction,omitempty"`
+	// ProductionNamespaceSelector selects the production Namespaces. If not set, Namespaces with the
+	// label k8s.mondoo.com/environment=production are production Namespaces.
+	// +optional
+	ProductionNamespaceSelector *metav1.LabelSelector `json:"productionNamespaceSelector,omitempty"`
+}
+
+// AdmissionAsync configures the background scans of the "async" mode
+type AdmissionAsync struct {
+	// AnnotateScanResults annotates the scanned workloads with their score, the time of the scan and the
+	// policy version once the background scan finished.
+	AnnotateScanResults bool `json:"annotateScanResults,omitempty"`
+	// QueueSize is the number of resources per webhook replica waiting for a background scan. If the queue
+	// is full, the resource is not scanned.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1000
+	QueueSize int32 `json:"queueSize,omitempty"`
+	// Workers is the number of concurrent background scans per webhook replica
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=4
+	Workers int32 `json:"workers,omitempty"`
+}
+
+// AdmissionScanApiProtection configures a concurrency limit and a circuit breaker for the scans of the webhook
+type AdmissionScanApiProtection struct {
+	// MaxConcurrentScans limits the number of concurrent scans per webhook replica. Requests beyond the
+	// limit are not scanned. If not set, the number of concurrent scans is not limited.
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentScans int32 `json:"maxConcurrentScans,omitempty"`
+	// FailureThreshold is the number of consecutive failed scans after which the circuit breaker opens.
+	// While it is open, the scan API is not called. If not set, the circuit breaker is disabled.
+	// +kubebuilder:validation:Minimum=0
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+	// OpenSeconds is the time the circuit breaker stays open before a single scan probes whether
+	// the scan API recovered.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=30
+	OpenSeconds int32 `json:"openSeconds,omitempty"`
+}
+
+// AdmissionImagePolicy restricts the images workloads may use
+type AdmissionImagePolicy struct {
+	// AllowedRegistries lists the registries images may be pulled from, e.g. "registry.example.com" or "*.azurecr.io".
+	// Patterns are matched against the registry and the repository, so "ghcr.io/my-org/*" is supported as well.
+	// Docker Hub images use the registry "index.docker.io". If empty, all registries are allowed.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+	// DisallowedTags lists the image tags which are not allowed, e.g. "latest". Wildcards are supported.
+	// Images without a tag use the tag "latest".
+	DisallowedTags []string `json:"disallowedTags,omitempty"`
+	// RequireDigest only allows images which reference a digest
+	RequireDigest bool `json:"requireDigest,omitempty"`
+}
+
+// AdmissionMutation configures the mutating admission webhook
+type AdmissionMutation struct {
+	// AnnotateScanResults annotates admitted workloads with their score, the time of the scan and the policy version.
+	// The annotations are k8s.mondoo.com/score, k8s.mondoo.com/scanned-at and k8s.mondoo.com/policy-version.
+	AnnotateScanResults bool `json:"annotateScanResults,omitempty"`
+	// ImageDigestPinning replaces the image tags of workloads with digests, so the scanned image is exactly the image which runs
+	ImageDigestPinning AdmissionImageDigestPinning `json:"imageDigestPinning,omitempty"`
+}
+
+// AdmissionImageDigestPinning configures the replacement of image tags with digests
+type AdmissionImageDigestPinning struct {
+	Enable bool `json:"enable,omitempty"`
+	// Registries limits pinning to images from these registries, e.g. "ghcr.io" or "*.azurecr.io".
+	// Docker Hub images use the registry "index.docker.io". If empty, images from all registries are pinned.
+	Registries []string `json:"registries,omitempty"`
+}
+
+// AdmissionRollout configures a gradual rollout of "enforcing" mode
+type AdmissionRollout struct {
+	// Percentage of the admission requests which are enforced. Requests are selected by a hash of the
+	// object kind, namespace and name, so the same object is always treated the same.
+	// If not set, only the CanaryNamespaces are enforced.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	Percentage *int32 `json:"percentage,omitempty"`
+	// CanaryNamespaces are always enforced. Wildcards are supported.
+	CanaryNamespaces []string `json:"canaryNamespaces,omitempty"`
+	// ShadowMode is the mode for the admission requests which are not enforced.
+	// +kubebuilder:validation:Enum=audit;permissive
+	// +kubebuilder:default=audit
+	ShadowMode AdmissionMode `json:"shadowMode,omitempty"`
+}
+
+// AdmissionCache configures the cache of scan results in the admission webhook
+type AdmissionCache struct {
+	Enable bool `json:"enable,omitempty"`
+	// TTLSeconds is the time in seconds after which a cached scan result expires.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=300
+	TTLSeconds int32 `json:"ttlSeconds,omitempty"`
+	// MaxEntries is the maximum number of cached scan results per webhook replica.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1000
+	MaxEntries int32 `json:"maxEntries,omitempty"`
+}
+
+// AdmissionResource identifies a resource (GVR) which is checked by the admission webhook
+type AdmissionResource struct {
+	// Group is the API group of the resource, e.g. "networking.k8s.io". Use an empty string for the core group.
+	Group string `json:"group,omitempty"`
+	// Version is the API version of the resource, e.g. "v1"
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+	// Resource is the plural name of the resource, e.g. "ingresses"
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+}
+
+// AdmissionExemptions lists requesters which bypass admission enforcement. All entries support
+// glob patterns like the namespace filtering.
+type AdmissionExemptions struct {
+	// Users is a list of user names, e.g. "admin@example.com" or "system:*".
+	Users []string `json:"users,omitempty"`
+	// Groups is a list of groups, e.g. "system:masters".
+	Groups []string `json:"groups,omitempty"`
+	// ServiceAccounts is a list of service accounts in the form "<namespace>/<name>", e.g. "argocd/*".
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
 }
 
 type Containers struct {
@@ -164,6 +383,7 @@ const (
 	CertManagerProvisioning CertificateProvisioningMode = "cert-manager"
 	OpenShiftProvisioning   CertificateProvisioningMode = "openshift"
 	ManualProvisioning      CertificateProvisioningMode = "manual"
+	SelfSignedProvisioning  CertificateProvisioningMode = "self-signed"
 )
 
 // AdmissionMode specifies the allowed modes of operation for the webhook admission controller
@@ -172,6 +392,29 @@ type AdmissionMode string
 const (
 	Permissive AdmissionMode = "permissive"
 	Enforcing  AdmissionMode = "enforcing"
+	Audit      AdmissionMode = "audit"
+	Async      AdmissionMode = "async"
+)
+
+// AdmissionFailurePolicy specifies how the webhook behaves if a resource cannot be scanned
+type AdmissionFailurePolicy string
+
+const (
+	FailOpen          AdmissionFailurePolicy = "fail-open"
+	FailClosed        AdmissionFailurePolicy = "fail-closed"
+	FailOpenWithEvent AdmissionFailurePolicy = "fail-open-with-event"
+)
+
+// AdmissionSeverity specifies the severity of a failing check. The severity is derived from the
+// impact of the check, which is the inverse of its score.
+type AdmissionSeverity string
+
+const (
+	SeverityNone     AdmissionSeverity = "none"
+	SeverityLow      AdmissionSeverity = "low"
+	SeverityMedium   AdmissionSeverity = "medium"
+	SeverityHigh     AdmissionSeverity = "high"
+	SeverityCritical AdmissionSeverity = "critical"
 )
 
 // MondooAuditConfigStatus defines the observed state of MondooAuditConfig
@@ -186,6 +429,30 @@ type MondooAuditConfigStatus struct {
 
 	// ReconciledByOperatorVersion contains the version of the operator which reconciled this MondooAuditConfig
 	ReconciledByOperatorVersion string `json:"reconciledByOperatorVersion,omitempty"`
+
+	// Admission contains statistics reported by the admission webhook
+	Admission AdmissionStatus `json:"admission,omitempty"`
+
+	// KubernetesResources contains statistics reported by the Kubernetes resources scanning
+	KubernetesResources KubernetesResourcesStatus `json:"kubernetesResources,omitempty"`
+}
+
+// AdmissionStatus contains statistics reported by the admission webhook
+type AdmissionStatus struct {
+	// WouldBeDeniedCount is the number of resources admitted in "audit" mode which "enforcing" mode would have denied
+	WouldBeDeniedCount int64 `json:"wouldBeDeniedCount,omitempty"`
+	// LastWouldBeDeniedTime is the last time a resource was admitted in "audit" mode which "enforcing" mode would have denied
+	LastWouldBeDeniedTime *metav1.Time `json:"lastWouldBeDeniedTime,omitempty"`
+	// OptedOutCount is the number of admission requests skipped because the object is annotated with
+	// k8s.mondoo.com/scan: "false"
+	OptedOutCount int64 `json:"optedOutCount,omitempty"`
+}
+
+// KubernetesResourcesStatus contains statistics reported by the Kubernetes resources scanning
+type KubernetesResourcesStatus struct {
+	// OptedOutCount is the number of changed objects which weren't scanned because they are annotated with
+	// k8s.mondoo.com/scan: "false"
+	OptedOutCount int64 `json:"optedOutCount,omitempty"`
 }
 
 type MondooAuditConfigCondition struct {
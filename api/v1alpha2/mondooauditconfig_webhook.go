@@ -0,0 +1,231 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package v1alpha2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/robfig/cron/v3"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// MondooAuditConfigDefaulter sets the defaults of MondooAuditConfigs which cannot be expressed in the CRD
+// +kubebuilder:object:generate=false
+type MondooAuditConfigDefaulter struct {
+	// now returns the current time. Defaults to time.Now.
+	now func() time.Time
+}
+
+var _ admission.CustomDefaulter = &MondooAuditConfigDefaulter{}
+
+func (d *MondooAuditConfigDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	m, ok := obj.(*MondooAuditConfig)
+	if !ok {
+		return fmt.Errorf("expected a MondooAuditConfig but got a %T", obj)
+	}
+
+	// objects being deleted are left as they are, so e.g. the finalizer can be removed
+	if m.DeletionTimestamp != nil {
+		return nil
+	}
+
+	// The deprecated containerImageScanning would enable the container image scanning again. The conflict
+	// is left to the validation, so it can be rejected with a clear message.
+	if disablesContainerImageScanning(ctx, m) {
+		return nil
+	}
+
+	now := time.Now()
+	if d.now != nil {
+		now = d.now()
+	}
+	m.SetDefaults(now)
+	return nil
+}
+
+// disablesContainerImageScanning checks whether the admission request disables the container image scanning
+func disablesContainerImageScanning(ctx context.Context, m *MondooAuditConfig) bool {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil || req.Operation != admissionv1.Update {
+		return false
+	}
+	old := &MondooAuditConfig{}
+	if err := json.Unmarshal(req.OldObject.Raw, old); err != nil {
+		return false
+	}
+	return old.Spec.Containers.Enable && !m.Spec.Containers.Enable
+}
+
+// SetDefaults sets the schedules of the enabled scans and enables the container image scanning if the
+// deprecated containerImageScanning is set. The schedules start a minute after now, so the first scans
+// run right away. Returns true if anything was changed.
+func (m *MondooAuditConfig) SetDefaults(now time.Time) bool {
+	changed := false
+	if m.Spec.KubernetesResources.ContainerImageScanning && !m.Spec.Containers.Enable {
+		m.Spec.Containers.Enable = true
+		changed = true
+	}
+
+	cronStart := now.Add(1 * time.Minute)
+	if m.Spec.Nodes.Enable && m.Spec.Nodes.Schedule == "" {
+		m.Spec.Nodes.Schedule = fmt.Sprintf("%d * * * *", cronStart.Minute())
+		changed = true
+	}
+	if m.Spec.KubernetesResources.Enable && m.Spec.KubernetesResources.Schedule == "" {
+		m.Spec.KubernetesResources.Schedule = fmt.Sprintf("%d * * * *", cronStart.Minute())
+		changed = true
+	}
+	if m.Spec.Containers.Enable && m.Spec.Containers.Schedule == "" {
+		m.Spec.Containers.Schedule = fmt.Sprintf("%d %d * * *", cronStart.Minute(), cronStart.Hour())
+		changed = true
+	}
+	return changed
+}
+
+// MondooAuditConfigValidator rejects MondooAuditConfigs which would only fail at reconcile time or
+// silently behave differently than configured
+// +kubebuilder:object:generate=false
+type MondooAuditConfigValidator struct{}
+
+var _ admission.CustomValidator = &MondooAuditConfigValidator{}
+
+func (v *MondooAuditConfigValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	m, ok := obj.(*MondooAuditConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a MondooAuditConfig but got a %T", obj)
+	}
+	return nil, invalidError("MondooAuditConfig", m.Name, m.Validate())
+}
+
+func (v *MondooAuditConfigValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*MondooAuditConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a MondooAuditConfig but got a %T", oldObj)
+	}
+	m, ok := newObj.(*MondooAuditConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a MondooAuditConfig but got a %T", newObj)
+	}
+	// the finalizer has to be removable, no matter what the spec looks like
+	if m.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	warnings, errs := ratchet(m.Validate(), old.Validate())
+	return warnings, invalidError("MondooAuditConfig", m.Name, errs)
+}
+
+func (v *MondooAuditConfigValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate checks the MondooAuditConfig for settings which cannot be validated by the CRD schema
+func (m *MondooAuditConfig) Validate() field.ErrorList {
+	var errs field.ErrorList
+	spec := field.NewPath("spec")
+
+	errs = append(errs, validateSchedule(spec.Child("kubernetesResources", "schedule"), m.Spec.KubernetesResources.Schedule)...)
+	errs = append(errs, validateSchedule(spec.Child("nodes", "schedule"), m.Spec.Nodes.Schedule)...)
+	errs = append(errs, validateSchedule(spec.Child("containers", "schedule"), m.Spec.Containers.Schedule)...)
+
+	if m.Spec.KubernetesResources.ContainerImageScanning && !m.Spec.Containers.Enable {
+		errs = append(errs, field.Invalid(spec.Child("containers", "enable"), false,
+			"cannot be disabled while the deprecated spec.kubernetesResources.containerImageScanning is enabled, remove containerImageScanning to disable the container image scanning"))
+	}
+
+	namespaces := spec.Child("filtering", "namespaces")
+	errs = append(errs, validateGlobs(namespaces.Child("include"), m.Spec.Filtering.Namespaces.Include)...)
+	errs = append(errs, validateGlobs(namespaces.Child("exclude"), m.Spec.Filtering.Namespaces.Exclude)...)
+	if m.Spec.Filtering.Namespaces.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(m.Spec.Filtering.Namespaces.Selector); err != nil {
+			errs = append(errs, field.Invalid(namespaces.Child("selector"), m.Spec.Filtering.Namespaces.Selector, err.Error()))
+		}
+	}
+
+	admissionPath := spec.Child("admission")
+	exemptions := admissionPath.Child("exemptions")
+	errs = append(errs, validateGlobs(exemptions.Child("users"), m.Spec.Admission.Exemptions.Users)...)
+	errs = append(errs, validateGlobs(exemptions.Child("groups"), m.Spec.Admission.Exemptions.Groups)...)
+	errs = append(errs, validateGlobs(exemptions.Child("serviceAccounts"), m.Spec.Admission.Exemptions.ServiceAccounts)...)
+	if m.Spec.Admission.Rollout != nil {
+		errs = append(errs, validateGlobs(admissionPath.Child("rollout", "canaryNamespaces"), m.Spec.Admission.Rollout.CanaryNamespaces)...)
+		switch shadowMode := m.Spec.Admission.Rollout.ShadowMode; shadowMode {
+		case "", Audit, Permissive:
+		default:
+			errs = append(errs, field.NotSupported(admissionPath.Child("rollout", "shadowMode"), shadowMode,
+				[]string{string(Audit), string(Permissive)}))
+		}
+	}
+
+	if m.Spec.Admission.Enable && m.Spec.Admission.Mode == Enforcing {
+		replicas := int32(1)
+		if m.Spec.Admission.Replicas != nil {
+			replicas = *m.Spec.Admission.Replicas
+		}
+		if replicas < 2 {
+			errs = append(errs, field.Invalid(admissionPath.Child("replicas"), replicas,
+				"must be at least 2 in enforcing mode, otherwise changes are denied while the webhook Pod is unavailable"))
+		}
+	}
+
+	return errs
+}
+
+// validateSchedule checks whether the schedule is a valid cron schedule as accepted by CronJobs
+func validateSchedule(path *field.Path, schedule string) field.ErrorList {
+	if schedule == "" {
+		return nil
+	}
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return field.ErrorList{field.Invalid(path, schedule, fmt.Sprintf("must be a valid cron schedule: %s", err))}
+	}
+	return nil
+}
+
+// validateGlobs checks whether the patterns are valid glob patterns
+func validateGlobs(path *field.Path, patterns []string) field.ErrorList {
+	var errs field.ErrorList
+	for i, p := range patterns {
+		if _, err := glob.Compile(p); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), p, fmt.Sprintf("must be a valid glob pattern: %s", err)))
+		}
+	}
+	return errs
+}
+
+// ratchet turns the errors which the old object already had into warnings, so existing objects can
+// still be updated, e.g. by the operator adding its finalizer
+func ratchet(errs, oldErrs field.ErrorList) (admission.Warnings, field.ErrorList) {
+	existing := map[string]bool{}
+	for _, e := range oldErrs {
+		existing[e.Field+string(e.Type)] = true
+	}
+
+	var warnings admission.Warnings
+	var newErrs field.ErrorList
+	for _, e := range errs {
+		if existing[e.Field+string(e.Type)] {
+			warnings = append(warnings, e.Error())
+		} else {
+			newErrs = append(newErrs, e)
+		}
+	}
+	return warnings, newErrs
+}
+
+// invalidError returns an Invalid error for the errors or nil if there are none
+func invalidError(kind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind(kind).GroupKind(), name, errs)
+}
@@ -0,0 +1,230 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package v1alpha2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func testMondooAuditConfig() *MondooAuditConfig {
+	return &MondooAuditConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "mondoo-client", Namespace: "mondoo-operator"},
+		Spec: MondooAuditConfigSpec{
+			KubernetesResources: KubernetesResources{Enable: true, Schedule: "0 * * * *"},
+			Nodes:               Nodes{Enable: true, Schedule: "0 * * * *"},
+			Containers:          Containers{Enable: true, Schedule: "0 0 * * *"},
+		},
+	}
+}
+
+func TestMondooAuditConfigDefaulter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	d := &MondooAuditConfigDefaulter{now: func() time.Time { return now }}
+
+	m := testMondooAuditConfig()
+	m.Spec.KubernetesResources.Schedule = ""
+	m.Spec.Nodes.Schedule = ""
+	m.Spec.Containers.Schedule = ""
+	require.NoError(t, d.Default(context.Background(), m))
+
+	assert.Equal(t, "31 * * * *", m.Spec.KubernetesResources.Schedule)
+	assert.Equal(t, "31 * * * *", m.Spec.Nodes.Schedule)
+	assert.Equal(t, "31 10 * * *", m.Spec.Containers.Schedule)
+
+	// configured schedules and disabled scans are kept
+	m = testMondooAuditConfig()
+	m.Spec.Nodes = Nodes{}
+	require.NoError(t, d.Default(context.Background(), m))
+	assert.Equal(t, testMondooAuditConfig().Spec.KubernetesResources, m.Spec.KubernetesResources)
+	assert.Equal(t, "", m.Spec.Nodes.Schedule)
+
+	// objects being deleted are not defaulted
+	m = testMondooAuditConfig()
+	m.Spec.KubernetesResources.Schedule = ""
+	m.DeletionTimestamp = &metav1.Time{Time: now}
+	require.NoError(t, d.Default(context.Background(), m))
+	assert.Equal(t, "", m.Spec.KubernetesResources.Schedule)
+}
+
+func TestMondooAuditConfigDefaulter_ContainerImageScanning(t *testing.T) {
+	d := &MondooAuditConfigDefaulter{}
+
+	m := testMondooAuditConfig()
+	m.Spec.KubernetesResources.ContainerImageScanning = true
+	m.Spec.Containers = Containers{}
+	require.NoError(t, d.Default(context.Background(), m))
+	assert.True(t, m.Spec.Containers.Enable, "the deprecated field enables the container image scanning")
+	assert.NotEmpty(t, m.Spec.Containers.Schedule)
+
+	// disabling the container image scanning on an update is left to the validation
+	old := testMondooAuditConfig()
+	old.Spec.KubernetesResources.ContainerImageScanning = true
+	oldRaw, err := json.Marshal(old)
+	require.NoError(t, err)
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+		},
+	})
+
+	m = old.DeepCopy()
+	m.Spec.Containers.Enable = false
+	require.NoError(t, d.Default(ctx, m))
+	assert.False(t, m.Spec.Containers.Enable)
+
+	_, err = (&MondooAuditConfigValidator{}).ValidateUpdate(ctx, old, m)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.containers.enable")
+}
+
+func TestMondooAuditConfigValidator_ValidateCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(m *MondooAuditConfig)
+		field  string
+	}{
+		{
+			name:   "valid",
+			mutate: func(m *MondooAuditConfig) {},
+		},
+		{
+			name:   "invalid kubernetes resources schedule",
+			mutate: func(m *MondooAuditConfig) { m.Spec.KubernetesResources.Schedule = "every hour" },
+			field:  "spec.kubernetesResources.schedule",
+		},
+		{
+			name:   "invalid nodes schedule",
+			mutate: func(m *MondooAuditConfig) { m.Spec.Nodes.Schedule = "61 * * * *" },
+			field:  "spec.nodes.schedule",
+		},
+		{
+			name:   "invalid containers schedule",
+			mutate: func(m *MondooAuditConfig) { m.Spec.Containers.Schedule = "* * *" },
+			field:  "spec.containers.schedule",
+		},
+		{
+			name: "container image scanning conflict",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.KubernetesResources.ContainerImageScanning = true
+				m.Spec.Containers.Enable = false
+			},
+			field: "spec.containers.enable",
+		},
+		{
+			name:   "invalid namespace include glob",
+			mutate: func(m *MondooAuditConfig) { m.Spec.Filtering.Namespaces.Include = []string{"app-*", "[team"} },
+			field:  "spec.filtering.namespaces.include[1]",
+		},
+		{
+			name:   "invalid namespace exclude glob",
+			mutate: func(m *MondooAuditConfig) { m.Spec.Filtering.Namespaces.Exclude = []string{"kube-[system"} },
+			field:  "spec.filtering.namespaces.exclude[0]",
+		},
+		{
+			name: "invalid namespace selector",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.Filtering.Namespaces.Selector = &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Like"}},
+				}
+			},
+			field: "spec.filtering.namespaces.selector",
+		},
+		{
+			name: "invalid exempted service account glob",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.Admission.Exemptions.ServiceAccounts = []string{"system:serviceaccount:[ci"}
+			},
+			field: "spec.admission.exemptions.serviceAccounts[0]",
+		},
+		{
+			name: "async rollout shadow mode",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.Admission.Rollout = &AdmissionRollout{ShadowMode: Async}
+			},
+			field: "spec.admission.rollout.shadowMode",
+		},
+		{
+			name: "permissive rollout shadow mode",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.Admission.Rollout = &AdmissionRollout{ShadowMode: Permissive}
+			},
+		},
+		{
+			name: "enforcing admission with a single replica",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.Admission = Admission{Enable: true, Mode: Enforcing}
+			},
+			field: "spec.admission.replicas",
+		},
+		{
+			name: "enforcing admission with two replicas",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.Admission = Admission{Enable: true, Mode: Enforcing, Replicas: ptr.To(int32(2))}
+			},
+		},
+		{
+			name: "permissive admission with a single replica",
+			mutate: func(m *MondooAuditConfig) {
+				m.Spec.Admission = Admission{Enable: true, Mode: Permissive}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := testMondooAuditConfig()
+			test.mutate(m)
+
+			warnings, err := (&MondooAuditConfigValidator{}).ValidateCreate(context.Background(), m)
+			assert.Empty(t, warnings)
+			if test.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.True(t, apierrors.IsInvalid(err))
+			assert.Contains(t, err.Error(), test.field)
+		})
+	}
+}
+
+func TestMondooAuditConfigValidator_ValidateUpdate(t *testing.T) {
+	v := &MondooAuditConfigValidator{}
+
+	// errors the object already had are only warnings, so e.g. the finalizer can still be added
+	old := testMondooAuditConfig()
+	old.Spec.Nodes.Schedule = "every hour"
+	m := old.DeepCopy()
+	m.Finalizers = []string{"k8s.mondoo.com/delete"}
+	warnings, err := v.ValidateUpdate(context.Background(), old, m)
+	assert.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "spec.nodes.schedule")
+
+	// new errors are rejected
+	m.Spec.Containers.Schedule = "every day"
+	_, err = v.ValidateUpdate(context.Background(), old, m)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "spec.containers.schedule")
+	assert.NotContains(t, err.Error(), "spec.nodes.schedule")
+
+	// objects being deleted are not validated
+	m.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	warnings, err = v.ValidateUpdate(context.Background(), old, m)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
@@ -0,0 +1,78 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package v1alpha2
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// MondooOperatorConfigValidator rejects MondooOperatorConfigs which would be ignored or would break the
+// connection to Mondoo Platform
+// +kubebuilder:object:generate=false
+type MondooOperatorConfigValidator struct{}
+
+var _ admission.CustomValidator = &MondooOperatorConfigValidator{}
+
+func (v *MondooOperatorConfigValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	c, ok := obj.(*MondooOperatorConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a MondooOperatorConfig but got a %T", obj)
+	}
+	return nil, invalidError("MondooOperatorConfig", c.Name, c.Validate())
+}
+
+func (v *MondooOperatorConfigValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*MondooOperatorConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a MondooOperatorConfig but got a %T", oldObj)
+	}
+	c, ok := newObj.(*MondooOperatorConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a MondooOperatorConfig but got a %T", newObj)
+	}
+	if c.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	warnings, errs := ratchet(c.Validate(), old.Validate())
+	return warnings, invalidError("MondooOperatorConfig", c.Name, errs)
+}
+
+func (v *MondooOperatorConfigValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Validate checks the MondooOperatorConfig for settings which cannot be validated by the CRD schema
+func (c *MondooOperatorConfig) Validate() field.ErrorList {
+	var errs field.ErrorList
+	if c.Name != MondooOperatorConfigName {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), c.Name,
+			fmt.Sprintf("must be %s, other MondooOperatorConfigs are ignored", MondooOperatorConfigName)))
+	}
+
+	spec := field.NewPath("spec")
+	errs = append(errs, validateProxy(spec.Child("httpProxy"), c.Spec.HttpProxy)...)
+	errs = append(errs, validateProxy(spec.Child("containerProxy"), c.Spec.ContainerProxy)...)
+	return errs
+}
+
+// validateProxy checks whether the proxy is an absolute URL
+func validateProxy(path *field.Path, proxy *string) field.ErrorList {
+	if proxy == nil {
+		return nil
+	}
+	u, err := url.Parse(*proxy)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, *proxy, fmt.Sprintf("must be a valid URL: %s", err))}
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, *proxy, "must be an absolute URL, e.g. http://proxy.example.com:3128")}
+	}
+	return nil
+}
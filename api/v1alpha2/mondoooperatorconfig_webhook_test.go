@@ -0,0 +1,63 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package v1alpha2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestMondooOperatorConfigValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *MondooOperatorConfig
+		field  string
+	}{
+		{
+			name: "valid",
+			config: &MondooOperatorConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: MondooOperatorConfigName},
+				Spec:       MondooOperatorConfigSpec{HttpProxy: ptr.To("http://proxy.example.com:3128")},
+			},
+		},
+		{
+			name:   "ignored name",
+			config: &MondooOperatorConfig{ObjectMeta: metav1.ObjectMeta{Name: "my-config"}},
+			field:  "metadata.name",
+		},
+		{
+			name: "relative http proxy",
+			config: &MondooOperatorConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: MondooOperatorConfigName},
+				Spec:       MondooOperatorConfigSpec{HttpProxy: ptr.To("proxy.example.com:3128")},
+			},
+			field: "spec.httpProxy",
+		},
+		{
+			name: "invalid container proxy",
+			config: &MondooOperatorConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: MondooOperatorConfigName},
+				Spec:       MondooOperatorConfigSpec{ContainerProxy: ptr.To("http://proxy:port")},
+			},
+			field: "spec.containerProxy",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := (&MondooOperatorConfigValidator{}).ValidateCreate(context.Background(), test.config)
+			if test.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.field)
+		})
+	}
+}
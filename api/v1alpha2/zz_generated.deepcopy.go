@@ -9,6 +9,7 @@ package v1alpha2
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -22,6 +23,28 @@ func (in *Admission) DeepCopyInto(out *Admission) {
 		**out = **in
 	}
 	out.CertificateProvisioning = in.CertificateProvisioning
+	if in.ScoreThreshold != nil {
+		in, out := &in.ScoreThreshold, &out.ScoreThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	in.Exemptions.DeepCopyInto(&out.Exemptions)
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]AdmissionResource, len(*in))
+		copy(*out, *in)
+	}
+	out.Cache = in.Cache
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(AdmissionRollout)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Mutation.DeepCopyInto(&out.Mutation)
+	in.ImagePolicy.DeepCopyInto(&out.ImagePolicy)
+	out.ScanApiProtection = in.ScanApiProtection
+	out.Async = in.Async
+	in.ConnectAuditing.DeepCopyInto(&out.ConnectAuditing)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Admission.
@@ -34,6 +57,221 @@ func (in *Admission) DeepCopy() *Admission {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionAsync) DeepCopyInto(out *AdmissionAsync) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionAsync.
+func (in *AdmissionAsync) DeepCopy() *AdmissionAsync {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionAsync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionCache) DeepCopyInto(out *AdmissionCache) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionCache.
+func (in *AdmissionCache) DeepCopy() *AdmissionCache {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionConnectAuditing) DeepCopyInto(out *AdmissionConnectAuditing) {
+	*out = *in
+	if in.ProductionNamespaceSelector != nil {
+		in, out := &in.ProductionNamespaceSelector, &out.ProductionNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionConnectAuditing.
+func (in *AdmissionConnectAuditing) DeepCopy() *AdmissionConnectAuditing {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionConnectAuditing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionExemptions) DeepCopyInto(out *AdmissionExemptions) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceAccounts != nil {
+		in, out := &in.ServiceAccounts, &out.ServiceAccounts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionExemptions.
+func (in *AdmissionExemptions) DeepCopy() *AdmissionExemptions {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionExemptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionImageDigestPinning) DeepCopyInto(out *AdmissionImageDigestPinning) {
+	*out = *in
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionImageDigestPinning.
+func (in *AdmissionImageDigestPinning) DeepCopy() *AdmissionImageDigestPinning {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionImageDigestPinning)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionImagePolicy) DeepCopyInto(out *AdmissionImagePolicy) {
+	*out = *in
+	if in.AllowedRegistries != nil {
+		in, out := &in.AllowedRegistries, &out.AllowedRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DisallowedTags != nil {
+		in, out := &in.DisallowedTags, &out.DisallowedTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionImagePolicy.
+func (in *AdmissionImagePolicy) DeepCopy() *AdmissionImagePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionImagePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionMutation) DeepCopyInto(out *AdmissionMutation) {
+	*out = *in
+	in.ImageDigestPinning.DeepCopyInto(&out.ImageDigestPinning)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionMutation.
+func (in *AdmissionMutation) DeepCopy() *AdmissionMutation {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionMutation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionResource) DeepCopyInto(out *AdmissionResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionResource.
+func (in *AdmissionResource) DeepCopy() *AdmissionResource {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionRollout) DeepCopyInto(out *AdmissionRollout) {
+	*out = *in
+	if in.Percentage != nil {
+		in, out := &in.Percentage, &out.Percentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CanaryNamespaces != nil {
+		in, out := &in.CanaryNamespaces, &out.CanaryNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionRollout.
+func (in *AdmissionRollout) DeepCopy() *AdmissionRollout {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionScanApiProtection) DeepCopyInto(out *AdmissionScanApiProtection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionScanApiProtection.
+func (in *AdmissionScanApiProtection) DeepCopy() *AdmissionScanApiProtection {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionScanApiProtection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionStatus) DeepCopyInto(out *AdmissionStatus) {
+	*out = *in
+	if in.LastWouldBeDeniedTime != nil {
+		in, out := &in.LastWouldBeDeniedTime, &out.LastWouldBeDeniedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionStatus.
+func (in *AdmissionStatus) DeepCopy() *AdmissionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AdmissionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertificateProvisioning) DeepCopyInto(out *CertificateProvisioning) {
 	*out = *in
@@ -91,6 +329,7 @@ func (in *Containers) DeepCopy() *Containers {
 func (in *Filtering) DeepCopyInto(out *Filtering) {
 	*out = *in
 	in.Namespaces.DeepCopyInto(&out.Namespaces)
+	in.Kinds.DeepCopyInto(&out.Kinds)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Filtering.
@@ -116,6 +355,11 @@ func (in *FilteringSpec) DeepCopyInto(out *FilteringSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FilteringSpec.
@@ -128,6 +372,21 @@ func (in *FilteringSpec) DeepCopy() *FilteringSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupVersionKind) DeepCopyInto(out *GroupVersionKind) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupVersionKind.
+func (in *GroupVersionKind) DeepCopy() *GroupVersionKind {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupVersionKind)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Image) DeepCopyInto(out *Image) {
 	*out = *in
@@ -143,6 +402,31 @@ func (in *Image) DeepCopy() *Image {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KindFilteringSpec) DeepCopyInto(out *KindFilteringSpec) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = make([]GroupVersionKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = make([]GroupVersionKind, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KindFilteringSpec.
+func (in *KindFilteringSpec) DeepCopy() *KindFilteringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KindFilteringSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubernetesResources) DeepCopyInto(out *KubernetesResources) {
 	*out = *in
@@ -158,6 +442,21 @@ func (in *KubernetesResources) DeepCopy() *KubernetesResources {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubernetesResourcesStatus) DeepCopyInto(out *KubernetesResourcesStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubernetesResourcesStatus.
+func (in *KubernetesResourcesStatus) DeepCopy() *KubernetesResourcesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KubernetesResourcesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Metrics) DeepCopyInto(out *Metrics) {
 	*out = *in
@@ -300,6 +599,8 @@ func (in *MondooAuditConfigStatus) DeepCopyInto(out *MondooAuditConfigStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.Admission.DeepCopyInto(&out.Admission)
+	out.KubernetesResources = in.KubernetesResources
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MondooAuditConfigStatus.
@@ -0,0 +1,239 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package admission_replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils/logger"
+	webhookhandler "go.mondoo.com/mondoo-operator/pkg/webhooks/handler"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var Cmd = &cobra.Command{
+	Use:   "admission-test [files]",
+	Short: "Runs AdmissionReviews or manifests through the admission webhook logic and prints the decisions.",
+	Long: `Runs AdmissionReviews, AdmissionRequests or plain Kubernetes manifests through the same logic as the
+admission webhook and prints the decision for every resource. Manifests are tested as CREATE requests.
+The resources are scanned with the provided scan API, so the results are also reported to Mondoo.
+The command fails if at least one resource is denied.`,
+	Args: cobra.MinimumNArgs(1),
+}
+
+func init() {
+	scanApiUrl := Cmd.Flags().String("scan-api-url", "", "The URL of the service to send scan requests to.")
+	tokenInput := Cmd.Flags().String("token", "", "The token to use when making requests to the scan API. Cannot be specified in combination with --token-file-path.")
+	tokenFilePath := Cmd.Flags().String("token-file-path", "", "Path to a file containing token to use when making requests to the scan API. Cannot be specified in combination with --token.")
+	webhookMode := Cmd.Flags().String("enforcement-mode", string(v1alpha2.Enforcing), "The mode ('permissive', 'enforcing' or 'audit') to test the resources with.")
+	integrationMRN := Cmd.Flags().String("integration-mrn", "", "The Mondoo integration MRN to label scanned items with.")
+	clusterID := Cmd.Flags().String("cluster-id", "admission-test", "The cluster ID to label scanned items with.")
+	namespace := Cmd.Flags().String("namespace", "default", "The namespace for manifests without a namespace.")
+	includeNamespaces := Cmd.Flags().StringSlice("namespaces", nil, "Only process k8s resources matching the provided list of Namespaces.")
+	excludeNamespaces := Cmd.Flags().StringSlice("namespaces-exclude", nil, "Ignore k8s resources matching the provided list of Namespaces.")
+	scoreThreshold := Cmd.Flags().Int("score-threshold", -1, "The minimum score (0-100) a resource needs to pass the scan. A negative value disables the threshold.")
+	maxSeverity := Cmd.Flags().String("max-severity", "", "The highest severity (none, low, medium, high, critical) of a failing check which still passes the scan.")
+	allowedRegistries := Cmd.Flags().StringSlice("allowed-registries", nil, "Registries workload images may be pulled from. Wildcards are supported. If empty, all registries are allowed.")
+	disallowedImageTags := Cmd.Flags().StringSlice("disallowed-image-tags", nil, "Image tags which are not allowed in workloads, e.g. 'latest'. Wildcards are supported.")
+	requireImageDigest := Cmd.Flags().Bool("require-image-digest", false, "Only allow workload images which reference a digest.")
+	timeout := Cmd.Flags().Duration("timeout", 30*time.Second, "The time budget for the scan of a single resource.")
+
+	Cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		log.SetLogger(logger.NewLogger())
+
+		if *scanApiUrl == "" {
+			return fmt.Errorf("--scan-api-url must be provided")
+		}
+		if *tokenFilePath == "" && *tokenInput == "" {
+			return fmt.Errorf("either --token or --token-file-path must be provided")
+		}
+		if *tokenFilePath != "" && *tokenInput != "" {
+			return fmt.Errorf("only one of --token or --token-file-path must be provided")
+		}
+		if *webhookMode == string(v1alpha2.Async) {
+			return fmt.Errorf("async mode scans resources in the background and cannot be tested")
+		}
+
+		token := *tokenInput
+		if *tokenFilePath != "" {
+			tokenBytes, err := os.ReadFile(*tokenFilePath)
+			if err != nil {
+				return fmt.Errorf("failed to read in file with token content: %w", err)
+			}
+			token = strings.TrimSuffix(string(tokenBytes), "\n")
+		}
+
+		validator, err := webhookhandler.NewWebhookValidator(&webhookhandler.NewWebhookValidatorOpts{
+			Mode:                *webhookMode,
+			ScanUrl:             *scanApiUrl,
+			Token:               token,
+			IntegrationMrn:      *integrationMRN,
+			ClusterId:           *clusterID,
+			IncludeNamespaces:   *includeNamespaces,
+			ExcludeNamespaces:   *excludeNamespaces,
+			ScoreThreshold:      *scoreThreshold,
+			MaxSeverity:         *maxSeverity,
+			AllowedRegistries:   *allowedRegistries,
+			DisallowedImageTags: *disallowedImageTags,
+			RequireImageDigest:  *requireImageDigest,
+			ScanTimeout:         *timeout,
+		})
+		if err != nil {
+			return err
+		}
+
+		var requests []admission.Request
+		for _, path := range args {
+			fileRequests, err := RequestsFromFile(path, *namespace)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, fileRequests...)
+		}
+
+		cmd.SilenceUsage = true
+		denied := Replay(cmd.Context(), validator, requests, cmd.OutOrStdout())
+		if denied > 0 {
+			return fmt.Errorf("%d of %d resources denied", denied, len(requests))
+		}
+		return nil
+	}
+}
+
+// Replay runs the requests through the admission handler, prints the decisions and returns the
+// number of denied requests.
+func Replay(ctx context.Context, handler admission.Handler, requests []admission.Request, out io.Writer) int {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	denied := 0
+	for _, req := range requests {
+		response := handler.Handle(ctx, req)
+		decision := "ALLOWED"
+		if !response.Allowed {
+			decision = "DENIED"
+			denied++
+		}
+
+		message := ""
+		if response.Result != nil {
+			message = response.Result.Message
+		}
+		fmt.Fprintf(out, "%-8s %s %s: %s\n", decision, req.Kind.Kind, resourceName(req), message)
+		for _, w := range response.Warnings {
+			fmt.Fprintf(out, "         warning: %s\n", w)
+		}
+	}
+	return denied
+}
+
+func resourceName(req admission.Request) string {
+	if req.Namespace == "" {
+		return req.Name
+	}
+	return req.Namespace + "/" + req.Name
+}
+
+// RequestsFromFile reads the admission requests from a file. The file may contain AdmissionReviews,
+// AdmissionRequests or plain manifests as JSON or YAML, with multiple YAML documents per file.
+// Manifests without a namespace are placed in the provided namespace.
+func RequestsFromFile(path, namespace string) ([]admission.Request, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var requests []admission.Request
+	decoder := yaml.NewYAMLOrJSONDecoder(bufio.NewReader(f), 4096)
+	for {
+		doc := map[string]interface{}{}
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if len(doc) == 0 {
+			continue
+		}
+
+		req, err := requestFromDocument(doc, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read resource from %s: %w", path, err)
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
+
+func requestFromDocument(doc map[string]interface{}, namespace string) (admission.Request, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return admission.Request{}, err
+	}
+
+	// AdmissionReviews carry the request, AdmissionRequests have an object instead of a kind string
+	if doc["kind"] == "AdmissionReview" {
+		review := admissionv1.AdmissionReview{}
+		if err := json.Unmarshal(data, &review); err != nil {
+			return admission.Request{}, err
+		}
+		if review.Request == nil {
+			return admission.Request{}, fmt.Errorf("AdmissionReview without a request")
+		}
+		return admission.Request{AdmissionRequest: *review.Request}, nil
+	}
+	if _, ok := doc["kind"].(map[string]interface{}); ok {
+		req := admissionv1.AdmissionRequest{}
+		if err := json.Unmarshal(data, &req); err != nil {
+			return admission.Request{}, err
+		}
+		return admission.Request{AdmissionRequest: req}, nil
+	}
+
+	obj := &unstructured.Unstructured{Object: doc}
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" || gvk.Version == "" {
+		return admission.Request{}, fmt.Errorf("manifest without apiVersion and kind")
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(namespace)
+	}
+	raw, err := json.Marshal(obj.Object)
+	if err != nil {
+		return admission.Request{}, err
+	}
+
+	gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UID:       uuid.NewUUID(),
+			Kind:      metav1.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind},
+			Resource:  metav1.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource},
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}, nil
+}
@@ -0,0 +1,123 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package admission_replay
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const testManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:latest
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: tools
+`
+
+const testAdmissionReview = `{
+  "apiVersion": "admission.k8s.io/v1",
+  "kind": "AdmissionReview",
+  "request": {
+    "uid": "0df28fbd-5f5f-11e8-bc74-36e6bb280816",
+    "kind": {"group": "", "version": "v1", "kind": "Pod"},
+    "resource": {"group": "", "version": "v1", "resource": "pods"},
+    "name": "nginx",
+    "namespace": "default",
+    "operation": "UPDATE",
+    "object": {"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}}
+  }
+}`
+
+func writeTestFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "input.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestRequestsFromFileAdmissionRequest(t *testing.T) {
+	requests, err := RequestsFromFile("../../../tests/data/webhook-payload.json", "default")
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+
+	req := requests[0]
+	assert.Equal(t, "Pod", req.Kind.Kind)
+	assert.Equal(t, "memcached-sample-5c8cffd96c-42z72", req.Name)
+	assert.Equal(t, "system:serviceaccount:kube-system:replicaset-controller", req.UserInfo.Username)
+	assert.NotEmpty(t, req.Object.Raw)
+}
+
+func TestRequestsFromFileAdmissionReview(t *testing.T) {
+	requests, err := RequestsFromFile(writeTestFile(t, testAdmissionReview), "default")
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+
+	req := requests[0]
+	assert.Equal(t, admissionv1.Update, req.Operation)
+	assert.Equal(t, "pods", req.Resource.Resource)
+	assert.Equal(t, "nginx", req.Name)
+}
+
+func TestRequestsFromFileManifests(t *testing.T) {
+	requests, err := RequestsFromFile(writeTestFile(t, testManifests), "apps")
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	deployment := requests[0]
+	assert.Equal(t, admissionv1.Create, deployment.Operation)
+	assert.Equal(t, "apps", deployment.Kind.Group)
+	assert.Equal(t, "Deployment", deployment.Kind.Kind)
+	assert.Equal(t, "deployments", deployment.Resource.Resource)
+	assert.Equal(t, "nginx", deployment.Name)
+	assert.Equal(t, "apps", deployment.Namespace)
+	assert.NotEmpty(t, deployment.UID)
+	assert.Contains(t, string(deployment.Object.Raw), `"namespace":"apps"`)
+
+	cronJob := requests[1]
+	assert.Equal(t, "cronjobs", cronJob.Resource.Resource)
+	assert.Equal(t, "tools", cronJob.Namespace)
+}
+
+func TestRequestsFromFileInvalidManifest(t *testing.T) {
+	_, err := RequestsFromFile(writeTestFile(t, "metadata:\n  name: nginx\n"), "default")
+	assert.Error(t, err)
+}
+
+func TestReplay(t *testing.T) {
+	requests, err := RequestsFromFile(writeTestFile(t, testManifests), "default")
+	require.NoError(t, err)
+
+	handler := admission.HandlerFunc(func(_ context.Context, req admission.Request) admission.Response {
+		if req.Kind.Kind == "Deployment" {
+			return admission.Denied("FAILED MONDOO SCAN").WithWarnings("image uses the latest tag")
+		}
+		return admission.Allowed("PASSED MONDOO SCAN")
+	})
+
+	out := &bytes.Buffer{}
+	denied := Replay(context.Background(), handler, requests, out)
+	assert.Equal(t, 1, denied)
+	assert.Equal(t, `DENIED   Deployment default/nginx: FAILED MONDOO SCAN
+         warning: image uses the latest tag
+ALLOWED  CronJob tools/backup: PASSED MONDOO SCAN
+`, out.String())
+}
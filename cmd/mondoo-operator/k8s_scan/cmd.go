@@ -34,6 +34,7 @@ func init() {
 	cleanupOlderThan := Cmd.Flags().String("cleanup-assets-older-than", "", "Set the age for which assets which have not been updated in over the time provided should be garbage collected (eg 12m or 48h)")
 	includeNamespaces := Cmd.Flags().StringSlice("namespaces", nil, "Only resources residing in this list of Namespaces will be scanned")
 	excludeNamespaces := Cmd.Flags().StringSlice("namespaces-exclude", nil, "Ignore resources residing in any of the specified Namespaces")
+	discoveryTargets := Cmd.Flags().StringSlice("discovery-targets", nil, "Only discover these kinds of resources (eg clusters,deployments). If not set, all resources are discovered")
 
 	Cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		log.SetLogger(logger.NewLogger())
@@ -74,6 +75,7 @@ func init() {
 			ManagedBy:           *setManagedBy,
 			IncludeNamespaces:   *includeNamespaces,
 			ExcludeNamespaces:   *excludeNamespaces,
+			DiscoveryTargets:    *discoveryTargets,
 		}
 		res, err := client.ScanKubernetesResources(ctx, scanOpts)
 		if err != nil {
@@ -5,6 +5,7 @@ package main
 
 import (
 	"github.com/spf13/cobra"
+	"go.mondoo.com/mondoo-operator/cmd/mondoo-operator/admission_replay"
 	"go.mondoo.com/mondoo-operator/cmd/mondoo-operator/garbage_collect"
 	"go.mondoo.com/mondoo-operator/cmd/mondoo-operator/k8s_scan"
 	"go.mondoo.com/mondoo-operator/cmd/mondoo-operator/operator"
@@ -19,7 +20,7 @@ var rootCmd = &cobra.Command{
 }
 
 func main() {
-	rootCmd.AddCommand(operator.Cmd, webhook.Cmd, version.Cmd, k8s_scan.Cmd, garbage_collect.Cmd)
+	rootCmd.AddCommand(operator.Cmd, webhook.Cmd, version.Cmd, k8s_scan.Cmd, garbage_collect.Cmd, admission_replay.Cmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
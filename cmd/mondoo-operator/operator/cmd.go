@@ -4,6 +4,7 @@
 package operator
 
 import (
+	"crypto/tls"
 	"fmt"
 
 	"github.com/go-logr/logr"
@@ -38,6 +39,7 @@ import (
 	"go.mondoo.com/mondoo-operator/pkg/utils/logger"
 	"go.mondoo.com/mondoo-operator/pkg/utils/mondoo"
 	"go.mondoo.com/mondoo-operator/pkg/version"
+	crdwebhooks "go.mondoo.com/mondoo-operator/pkg/webhooks/crd"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	//+kubebuilder:scaffold:imports
 )
@@ -52,6 +54,8 @@ func init() {
 	probeAddr := Cmd.Flags().String("health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	enableLeaderElection := Cmd.Flags().Bool("leader-elect", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	enableCRDWebhooks := Cmd.Flags().Bool("enable-crd-webhooks", true,
+		"Serve the defaulting and validating webhooks for MondooAuditConfigs and MondooOperatorConfigs.")
 
 	Cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		// TODO: opts.BindFlags(flag.CommandLine) is not supported with cobra. If we want to support that we should manually
@@ -67,10 +71,16 @@ func init() {
 		utilruntime.Must(certmanagerv1.AddToScheme(scheme))
 		utilruntime.Must(monitoringv1.AddToScheme(scheme))
 
+		webhookProvisioner := &crdwebhooks.Provisioner{}
 		mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-			Scheme:                 scheme,
-			Metrics:                metricsserver.Options{BindAddress: *metricsAddr},
-			WebhookServer:          webhook.NewServer(webhook.Options{Port: 9443}),
+			Scheme:  scheme,
+			Metrics: metricsserver.Options{BindAddress: *metricsAddr},
+			WebhookServer: webhook.NewServer(webhook.Options{
+				Port: crdwebhooks.Port,
+				TLSOpts: []func(*tls.Config){func(c *tls.Config) {
+					c.GetCertificate = webhookProvisioner.GetCertificate
+				}},
+			}),
 			HealthProbeBindAddress: *probeAddr,
 			LeaderElection:         *enableLeaderElection,
 			LeaderElectionID:       "60679458.mondoo.com",
@@ -156,6 +166,35 @@ func init() {
 			setupLog.Error(err, "unable to check for terminated state of mondoo-operator-controller")
 		}
 
+		if *enableCRDWebhooks {
+			namespace, err := k8s.GetRunningNamespace()
+			if err != nil {
+				setupLog.Error(err, "unable to get the namespace of the operator")
+				return err
+			}
+			webhookProvisioner.KubeClient = client
+			webhookProvisioner.Namespace = namespace
+			if err := webhookProvisioner.Setup(ctx); err != nil {
+				setupLog.Error(err, "unable to set up the operator webhooks")
+				return err
+			}
+		}
+
+		// Without the webhook configurations, e.g. with OLM, no certificate is loaded, so the webhook server
+		// is not started and the operator does not wait for it to become ready
+		if *enableCRDWebhooks && webhookProvisioner.Installed() {
+			if err := mgr.Add(webhookProvisioner); err != nil {
+				setupLog.Error(err, "unable to add the operator webhook provisioner")
+				return err
+			}
+			crdwebhooks.Register(mgr)
+			// the webhook Service only routes to operator Pods serving the webhooks, e.g. during a rollout
+			if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+				setupLog.Error(err, "unable to set up the webhook ready check")
+				return err
+			}
+		}
+
 		if err = resource_monitor.RegisterResourceMonitors(mgr, scanApiStore); err != nil {
 			setupLog.Error(err, "unable to register resource monitors", "controller", "resource_monitor")
 			return err
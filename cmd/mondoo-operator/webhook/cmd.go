@@ -7,16 +7,24 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/constants"
+	"go.mondoo.com/mondoo-operator/pkg/imagecache"
 	"go.mondoo.com/mondoo-operator/pkg/utils/logger"
 	"go.mondoo.com/mondoo-operator/pkg/version"
 	webhookhandler "go.mondoo.com/mondoo-operator/pkg/webhooks/handler"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
@@ -28,11 +36,43 @@ var Cmd = &cobra.Command{
 func init() {
 	scanApiUrl := Cmd.Flags().String("scan-api-url", "", "The URL of the service to send scan requests to.")
 	tokenFilePath := Cmd.Flags().String("token-file-path", "", "Path to a file containing token to use when making scan requests.")
-	webhookMode := Cmd.Flags().String("enforcement-mode", string(v1alpha2.Permissive), "Mode 'permissive' allows resources that had a failing scan result pass, and mode 'enforcing' will deny resources with failed scanning result.")
+	webhookMode := Cmd.Flags().String("enforcement-mode", string(v1alpha2.Permissive), "Mode 'permissive' allows resources that had a failing scan result pass, mode 'enforcing' will deny resources with failed scanning result, mode 'audit' allows them but records that they would have been denied, and mode 'async' admits all resources and scans them in the background.")
 	integrationMRN := Cmd.Flags().String("integration-mrn", "", "The Mondoo integration MRN to label scanned items with if the MondooAuditConfig is configured with Mondoo integration.")
 	clusterID := Cmd.Flags().String("cluster-id", "", "A cluster-unique ID for associating the webhook payloads with the underlying cluster.")
 	includeNamespaces := Cmd.Flags().StringSlice("namespaces", nil, "Only process k8s resources matching the provided list of Namespaces.")
 	excludeNamespaces := Cmd.Flags().StringSlice("namespaces-exclude", nil, "Ignore k8s resources matching the provided list of Namespaces.")
+	namespaceSelector := Cmd.Flags().String("namespace-selector", "", "Only process k8s resources in Namespaces matching the provided label selector.")
+	scoreThreshold := Cmd.Flags().Int("score-threshold", -1, "The minimum score (0-100) a resource needs to pass the scan. A negative value disables the threshold.")
+	maxSeverity := Cmd.Flags().String("max-severity", "", "The highest severity (none, low, medium, high, critical) of a failing check which still passes the scan.")
+	exemptUsers := Cmd.Flags().StringSlice("exempt-users", nil, "Users which bypass enforcement. Their changes are still scanned.")
+	exemptGroups := Cmd.Flags().StringSlice("exempt-groups", nil, "Groups which bypass enforcement. Their changes are still scanned.")
+	failurePolicy := Cmd.Flags().String("failure-policy", "", "The response if a resource cannot be scanned: 'fail-open', 'fail-closed' or 'fail-open-with-event'. Defaults to 'fail-closed' in enforcing mode and 'fail-open' otherwise.")
+	scanTimeout := Cmd.Flags().Duration("scan-timeout", 0, "The time budget for a single scan. 0 means no timeout.")
+	metricsAddr := Cmd.Flags().String("metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	cacheSize := Cmd.Flags().Int("cache-size", 0, "The maximum number of cached scan results. 0 disables the cache.")
+	cacheTTL := Cmd.Flags().Duration("cache-ttl", 5*time.Minute, "The time after which a cached scan result expires.")
+	exemptServiceAccounts := Cmd.Flags().StringSlice("exempt-service-accounts", nil, "Service accounts (<namespace>/<name>) which bypass enforcement. Their changes are still scanned.")
+	rolloutPercentage := Cmd.Flags().Int("rollout-percentage", -1, "The percentage (0-100) of requests which are enforced in enforcing mode. A negative value disables the percentage rollout.")
+	canaryNamespaces := Cmd.Flags().StringSlice("canary-namespaces", nil, "Namespaces which are always enforced in enforcing mode. If set, requests in other namespaces are only enforced according to --rollout-percentage.")
+	rolloutShadowMode := Cmd.Flags().String("rollout-shadow-mode", "", "The mode ('audit' or 'permissive') for requests which are not enforced because of the rollout. Defaults to 'audit'.")
+	annotateScanResults := Cmd.Flags().Bool("annotate-scan-results", false, "Serve a mutating webhook which annotates resources with their scan result.")
+	pinImageDigests := Cmd.Flags().Bool("pin-image-digests", false, "Serve a mutating webhook which replaces image tags in workloads with digests.")
+	pinRegistries := Cmd.Flags().StringSlice("pin-registries", nil, "Only pin images from these registries. Wildcards are supported. If empty, images from all registries are pinned.")
+	imageDigestConfigMap := Cmd.Flags().String("image-digest-configmap", "", "The ConfigMap in the namespace of the MondooAuditConfig which stores resolved image digests across webhook replicas.")
+	allowedRegistries := Cmd.Flags().StringSlice("allowed-registries", nil, "Registries workload images may be pulled from. Wildcards are supported. If empty, all registries are allowed.")
+	disallowedImageTags := Cmd.Flags().StringSlice("disallowed-image-tags", nil, "Image tags which are not allowed in workloads, e.g. 'latest'. Wildcards are supported.")
+	requireImageDigest := Cmd.Flags().Bool("require-image-digest", false, "Only allow workload images which reference a digest.")
+	maxConcurrentScans := Cmd.Flags().Int("max-concurrent-scans", 0, "The maximum number of concurrent scans. Requests beyond the limit are handled according to the failure policy. 0 means no limit.")
+	circuitBreakerThreshold := Cmd.Flags().Int("circuit-breaker-threshold", 0, "The number of consecutive failed scans after which the scan API is not called for --circuit-breaker-open-duration. 0 disables the circuit breaker.")
+	circuitBreakerOpenDuration := Cmd.Flags().Duration("circuit-breaker-open-duration", 30*time.Second, "The time the circuit breaker stays open before a single scan probes the scan API.")
+	asyncQueueSize := Cmd.Flags().Int("async-queue-size", 0, "The number of resources waiting for a background scan in async mode. 0 uses the default of 1000.")
+	asyncWorkers := Cmd.Flags().Int("async-workers", 0, "The number of concurrent background scans in async mode. 0 uses the default of 4.")
+	asyncAnnotateScanResults := Cmd.Flags().Bool("async-annotate-scan-results", false, "Annotate resources with the result of their background scan in async mode.")
+	auditConnect := Cmd.Flags().Bool("audit-connect", false, "Serve a validating webhook which records exec, attach and port-forward requests to Pods.")
+	denyExecInProduction := Cmd.Flags().Bool("deny-exec-in-production", false, "Deny exec and attach requests to Pods in Namespaces matching --production-namespace-selector.")
+	productionNamespaceSelector := Cmd.Flags().String("production-namespace-selector", webhookhandler.DefaultProductionNamespaceSelector, "The label selector for production Namespaces.")
+	auditConfigName := Cmd.Flags().String("mondoo-audit-config-name", "", "The name of the MondooAuditConfig the webhook reports its status to.")
+	auditConfigNamespace := Cmd.Flags().String("mondoo-audit-config-namespace", "", "The namespace of the MondooAuditConfig the webhook reports its status to.")
 
 	Cmd.RunE = func(cmd *cobra.Command, args []string) error {
 		log.SetLogger(logger.NewLogger())
@@ -57,8 +97,13 @@ func init() {
 
 		// Setup a Manager
 		webhookLog.Info("setting up manager")
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(v1alpha2.AddToScheme(scheme))
 		mgr, err := manager.New(config.GetConfigOrDie(), manager.Options{
+			Scheme:                 scheme,
 			HealthProbeBindAddress: ":8081",
+			Metrics:                metricsserver.Options{BindAddress: *metricsAddr},
 		})
 		if err != nil {
 			webhookLog.Error(err, "unable to set up overall controller manager")
@@ -71,15 +116,59 @@ func init() {
 
 		webhookLog.Info("registering webhooks to the webhook server")
 
+		var auditReporter *webhookhandler.AuditReporter
+		if *auditConfigName != "" && *auditConfigNamespace != "" {
+			auditReporter = webhookhandler.NewAuditReporter(mgr.GetAPIReader(), mgr.GetClient(), *auditConfigName, *auditConfigNamespace)
+			if err := mgr.Add(auditReporter); err != nil {
+				webhookLog.Error(err, "unable to set up audit reporter")
+				return err
+			}
+		}
+
+		var namespaces labels.Selector
+		if *namespaceSelector != "" {
+			namespaces, err = labels.Parse(*namespaceSelector)
+			if err != nil {
+				webhookLog.Error(err, "invalid namespace selector")
+				return err
+			}
+		}
+
 		webhookOpts := &webhookhandler.NewWebhookValidatorOpts{
-			Client:            mgr.GetClient(),
-			Mode:              *webhookMode,
-			ScanUrl:           *scanApiUrl,
-			Token:             token,
-			IntegrationMrn:    *integrationMRN,
-			ClusterId:         *clusterID,
-			IncludeNamespaces: *includeNamespaces,
-			ExcludeNamespaces: *excludeNamespaces,
+			Client:                     mgr.GetClient(),
+			APIReader:                  mgr.GetAPIReader(),
+			Recorder:                   mgr.GetEventRecorderFor("mondoo-webhook"),
+			Mode:                       *webhookMode,
+			ScanUrl:                    *scanApiUrl,
+			Token:                      token,
+			IntegrationMrn:             *integrationMRN,
+			ClusterId:                  *clusterID,
+			IncludeNamespaces:          *includeNamespaces,
+			ExcludeNamespaces:          *excludeNamespaces,
+			NamespaceSelector:          namespaces,
+			ScoreThreshold:             *scoreThreshold,
+			MaxSeverity:                *maxSeverity,
+			ExemptUsers:                *exemptUsers,
+			ExemptGroups:               *exemptGroups,
+			ExemptServiceAccounts:      *exemptServiceAccounts,
+			CacheSize:                  *cacheSize,
+			CacheTTL:                   *cacheTTL,
+			FailurePolicy:              *failurePolicy,
+			ScanTimeout:                *scanTimeout,
+			AuditReporter:              auditReporter,
+			RolloutPercentage:          *rolloutPercentage,
+			CanaryNamespaces:           *canaryNamespaces,
+			RolloutShadowMode:          *rolloutShadowMode,
+			AnnotateScanResults:        *annotateScanResults,
+			AllowedRegistries:          *allowedRegistries,
+			DisallowedImageTags:        *disallowedImageTags,
+			RequireImageDigest:         *requireImageDigest,
+			MaxConcurrentScans:         *maxConcurrentScans,
+			CircuitBreakerThreshold:    *circuitBreakerThreshold,
+			CircuitBreakerOpenDuration: *circuitBreakerOpenDuration,
+			AsyncQueueSize:             *asyncQueueSize,
+			AsyncWorkers:               *asyncWorkers,
+			AsyncAnnotateScanResults:   *asyncAnnotateScanResults,
 		}
 		webhookValidator, err := webhookhandler.NewWebhookValidator(webhookOpts)
 		if err != nil {
@@ -87,6 +176,32 @@ func init() {
 			return err
 		}
 		hookServer.Register("/validate-k8s-mondoo-com", &webhook.Admission{Handler: webhookValidator})
+		if err := mgr.Add(webhookValidator.AsyncScanner()); err != nil {
+			webhookLog.Error(err, "unable to set up async scanner")
+			return err
+		}
+		if *annotateScanResults {
+			hookServer.Register("/mutate-k8s-mondoo-com", &webhook.Admission{Handler: webhookValidator.ScanResultAnnotator()})
+		}
+		if *pinImageDigests {
+			images := imagecache.NewImageCacher()
+			if *imageDigestConfigMap != "" && *auditConfigNamespace != "" {
+				images = imagecache.NewImageCacherWithConfigMap(mgr.GetAPIReader(), mgr.GetClient(), *imageDigestConfigMap, *auditConfigNamespace)
+			}
+			hookServer.Register("/pin-k8s-mondoo-com", &webhook.Admission{Handler: webhookValidator.ImageDigestPinner(images, *pinRegistries)})
+		}
+		if *auditConnect {
+			productionNamespaces, err := labels.Parse(*productionNamespaceSelector)
+			if err != nil {
+				webhookLog.Error(err, "invalid production namespace selector")
+				return err
+			}
+			hookServer.Register("/audit-connect-k8s-mondoo-com", &webhook.Admission{Handler: webhookValidator.ConnectAuditor(*denyExecInProduction, productionNamespaces)})
+			if err := mgr.Add(webhookValidator.ConnectReporter()); err != nil {
+				webhookLog.Error(err, "unable to set up connect reporter")
+				return err
+			}
+		}
 
 		if err := mgr.AddHealthzCheck("healthz", webhookValidator.HealthChecker()); err != nil {
 			webhookLog.Error(err, "unable to set up health check")
@@ -96,6 +211,11 @@ func init() {
 			webhookLog.Error(err, "unable to set up ready check")
 			return err
 		}
+		// the readiness probe excludes this check, it shows the circuit breaker state on /readyz/circuit-breaker
+		if err := mgr.AddReadyzCheck(constants.WebhookCircuitBreakerCheck, webhookValidator.CircuitBreakerChecker()); err != nil {
+			webhookLog.Error(err, "unable to set up circuit breaker check")
+			return err
+		}
 
 		webhookLog.Info("starting manager")
 		if err := mgr.Start(signals.SetupSignalHandler()); err != nil {
@@ -0,0 +1,125 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package admission
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	webhooksv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils/certificates"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
+)
+
+// defaultCertificateExpiryWindow is used if no ExpiryWarningDays are configured
+const defaultCertificateExpiryWindow = 30 * 24 * time.Hour
+
+// certificateCheck is the result of inspecting the webhook serving certificate
+type certificateCheck struct {
+	// notAfter is the expiry of the serving certificate. It is zero if there is no certificate yet.
+	notAfter time.Time
+	// err is set if the certificate cannot be used by the webhook
+	err error
+}
+
+// certificateExpiryWindow returns the remaining validity of the serving certificate below which it is reported
+// as expiring and rotated in self-signed mode
+func certificateExpiryWindow(m *mondoov1alpha2.MondooAuditConfig) time.Duration {
+	if days := m.Spec.Admission.CertificateProvisioning.ExpiryWarningDays; days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return defaultCertificateExpiryWindow
+}
+
+// checkCertificate inspects the serving certificate in the webhook TLS Secret and checks that it is trusted
+// by the CA bundle of the webhook configuration. The expiry is exported as metric.
+func (n *DeploymentHandler) checkCertificate(ctx context.Context, now time.Time) certificateCheck {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetTLSCertificatesSecretName(n.Mondoo.Name),
+			Namespace: n.TargetNamespace,
+		},
+	}
+	exists, err := k8s.CheckIfExists(ctx, n.KubeClient, secret, secret)
+	if err != nil {
+		webhookLog.Error(err, "Failed to check for existing webhook TLS Secret")
+		return certificateCheck{}
+	}
+	if !exists {
+		// The webhook Pods cannot start without the Secret, which is reported as unavailable webhook
+		metricsWebhookCertExpiry.DeleteLabelValues(n.Mondoo.Namespace, n.Mondoo.Name)
+		return certificateCheck{}
+	}
+
+	certs, err := certificates.Parse(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		metricsWebhookCertExpiry.DeleteLabelValues(n.Mondoo.Namespace, n.Mondoo.Name)
+		return certificateCheck{err: fmt.Errorf("failed to parse the certificate in Secret %s: %w", secret.Name, err)}
+	}
+	metricsWebhookCertExpiry.WithLabelValues(n.Mondoo.Namespace, n.Mondoo.Name).Set(float64(certs[0].NotAfter.Unix()))
+
+	check := certificateCheck{notAfter: certs[0].NotAfter}
+	if now.After(check.notAfter) {
+		return check
+	}
+
+	// In self-signed mode the CA bundle is injected in this reconcile, otherwise a certificate
+	// provisioner or the user injects it into the existing webhook configuration
+	caBundles := [][]byte{n.caBundle}
+	if len(n.caBundle) == 0 {
+		vwcName, err := validatingWebhookName(n.Mondoo)
+		if err != nil {
+			webhookLog.Error(err, "failed to generate Webhook name")
+			return check
+		}
+		vwc := &webhooksv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: vwcName}}
+		exists, err := k8s.CheckIfExists(ctx, n.KubeClient, vwc, vwc)
+		if err != nil {
+			webhookLog.Error(err, "Failed to check for existing ValidatingWebhookConfiguration")
+			return check
+		}
+		if !exists {
+			return check
+		}
+
+		caBundles = caBundles[:0]
+		for _, w := range vwc.Webhooks {
+			caBundles = append(caBundles, w.ClientConfig.CABundle)
+		}
+	}
+
+	for _, caBundle := range caBundles {
+		if err := verifyServingCertificate(certs, caBundle, now); err != nil {
+			check.err = fmt.Errorf("not trusted by the CA bundle of the ValidatingWebhookConfiguration: %w", err)
+			break
+		}
+	}
+	return check
+}
+
+// verifyServingCertificate checks that the serving certificate chain is trusted by the CA bundle
+func verifyServingCertificate(certs []*x509.Certificate, caBundle []byte, now time.Time) error {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBundle) {
+		return fmt.Errorf("the CA bundle contains no certificates")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	return err
+}
@@ -0,0 +1,204 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	webhooksv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils/certificates"
+	"go.mondoo.com/mondoo-operator/pkg/utils/mondoo"
+	fakeMondoo "go.mondoo.com/mondoo-operator/pkg/utils/mondoo/fake"
+)
+
+var testWebhookDNSNames = []string{"mondoo-client-webhook-service.mondoo-operator.svc"}
+
+// testCertificateObjects returns a webhook TLS Secret with a certificate issued at the provided time and
+// a ValidatingWebhookConfiguration with the provided CA bundle
+func testCertificateObjects(t *testing.T, issued time.Time, caBundle func(ca []byte) []byte) (*corev1.Secret, *webhooksv1.ValidatingWebhookConfiguration) {
+	ca, cert, key, _, err := certificates.GenerateSelfSigned(testWebhookDNSNames, issued)
+	require.NoError(t, err)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetTLSCertificatesSecretName(testMondooAuditConfigName),
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{corev1.TLSCertKey: cert, corev1.TLSPrivateKeyKey: key},
+	}
+
+	vwcName, err := validatingWebhookName(testAuditConfig(mondoov1alpha2.MondooAuditConfigSpec{}))
+	require.NoError(t, err)
+	vwc := &webhooksv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: vwcName},
+		Webhooks: []webhooksv1.ValidatingWebhook{
+			{Name: "policy.k8s.mondoo.com", ClientConfig: webhooksv1.WebhookClientConfig{CABundle: caBundle(ca)}},
+		},
+	}
+	return secret, vwc
+}
+
+func testAuditConfig(spec mondoov1alpha2.MondooAuditConfigSpec) *mondoov1alpha2.MondooAuditConfig {
+	return &mondoov1alpha2.MondooAuditConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: testMondooAuditConfigName, Namespace: testNamespace},
+		Spec:       spec,
+	}
+}
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Now()
+	otherCA, _, _, _, err := certificates.GenerateSelfSigned(testWebhookDNSNames, now)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		issued      time.Time
+		caBundle    func(ca []byte) []byte
+		noSecret    bool
+		noWebhook   bool
+		expectError string
+	}{
+		{
+			name:     "trusted certificate",
+			issued:   now,
+			caBundle: func(ca []byte) []byte { return ca },
+		},
+		{
+			name:     "rotated CA bundle",
+			issued:   now,
+			caBundle: func(ca []byte) []byte { return append(otherCA, ca...) },
+		},
+		{
+			name:        "CA bundle of another CA",
+			issued:      now,
+			caBundle:    func([]byte) []byte { return otherCA },
+			expectError: "not trusted by the CA bundle of the ValidatingWebhookConfiguration: x509: certificate signed by unknown authority",
+		},
+		{
+			name:        "no CA bundle",
+			issued:      now,
+			caBundle:    func([]byte) []byte { return nil },
+			expectError: "not trusted by the CA bundle of the ValidatingWebhookConfiguration: the CA bundle contains no certificates",
+		},
+		{
+			name:     "expired certificate",
+			issued:   now.Add(-2 * certificates.SelfSignedValidity),
+			caBundle: func([]byte) []byte { return otherCA },
+		},
+		{
+			name:      "no webhook configuration yet",
+			issued:    now,
+			caBundle:  func([]byte) []byte { return nil },
+			noWebhook: true,
+		},
+		{
+			name:     "no Secret yet",
+			noSecret: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var objects []client.Object
+			var expectNotAfter time.Time
+			if !test.noSecret {
+				secret, vwc := testCertificateObjects(t, test.issued, test.caBundle)
+				objects = append(objects, secret)
+				if !test.noWebhook {
+					objects = append(objects, vwc)
+				}
+				expectNotAfter = test.issued.Add(certificates.SelfSignedValidity)
+			}
+
+			n := &DeploymentHandler{
+				Mondoo:          testAuditConfig(mondoov1alpha2.MondooAuditConfigSpec{}),
+				KubeClient:      fake.NewClientBuilder().WithObjects(objects...).Build(),
+				TargetNamespace: testNamespace,
+			}
+			check := n.checkCertificate(context.TODO(), now)
+
+			assert.Equal(t, expectNotAfter.Unix(), check.notAfter.Unix())
+			if test.expectError != "" {
+				assert.ErrorContains(t, check.err, test.expectError)
+			} else {
+				assert.NoError(t, check.err)
+			}
+
+			if test.noSecret {
+				assert.Equal(t, 0, testutil.CollectAndCount(metricsWebhookCertExpiry))
+			} else {
+				expiry := metricsWebhookCertExpiry.WithLabelValues(testNamespace, testMondooAuditConfigName)
+				assert.Equal(t, float64(expectNotAfter.Unix()), testutil.ToFloat64(expiry))
+			}
+			metricsWebhookCertExpiry.Reset()
+		})
+	}
+}
+
+func TestCheckCertificateSelfSigned(t *testing.T) {
+	now := time.Now()
+	secret, vwc := testCertificateObjects(t, now, func([]byte) []byte { return nil })
+
+	// the CA bundle to be injected is checked instead of the one in the existing webhook configuration
+	ca, cert, _, _, err := certificates.GenerateSelfSigned(testWebhookDNSNames, now)
+	require.NoError(t, err)
+	secret.Data[corev1.TLSCertKey] = cert
+
+	n := &DeploymentHandler{
+		Mondoo:          testAuditConfig(mondoov1alpha2.MondooAuditConfigSpec{}),
+		KubeClient:      fake.NewClientBuilder().WithObjects(secret, vwc).Build(),
+		TargetNamespace: testNamespace,
+		caBundle:        ca,
+	}
+	assert.NoError(t, n.checkCertificate(context.TODO(), now).err)
+	metricsWebhookCertExpiry.Reset()
+}
+
+func TestReconcileReportsExpiringCertificate(t *testing.T) {
+	now := time.Now()
+	spec := testMondooAuditConfigSpec(true, false)
+	spec.Admission.CertificateProvisioning.ExpiryWarningDays = 20
+	auditConfig := testAuditConfig(spec)
+
+	// the certificate expires in 15 days
+	secret, vwc := testCertificateObjects(t, now.Add(-certificates.SelfSignedValidity+15*24*time.Hour), func(ca []byte) []byte { return ca })
+	kubeSystemNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: types.UID(testClusterID)},
+	}
+	deployment := WebhookDeployment(testNamespace, "ghcr.io/mondoohq/mondoo-operator:latest", *auditConfig, "", testClusterID)
+
+	n := &DeploymentHandler{
+		Mondoo:                 auditConfig,
+		KubeClient:             fake.NewClientBuilder().WithObjects(auditConfig, kubeSystemNamespace, deployment, secret, vwc).Build(),
+		TargetNamespace:        testNamespace,
+		MondooOperatorConfig:   &mondoov1alpha2.MondooOperatorConfig{},
+		ContainerImageResolver: fakeMondoo.NewNoOpContainerImageResolver(),
+	}
+	_, err := n.Reconcile(context.TODO())
+	require.NoError(t, err)
+
+	cond := mondoo.FindMondooAuditConditions(auditConfig.Status.Conditions, mondoov1alpha2.AdmissionDegraded)
+	require.NotNil(t, cond)
+	assert.Equal(t, corev1.ConditionTrue, cond.Status)
+	assert.Equal(t, "AdmissionCertificateExpiring", cond.Reason)
+	assert.Contains(t, cond.Message, "Admission webhook certificate expires at ")
+
+	// disabling admission removes the metric
+	auditConfig.Spec.Admission.Enable = false
+	_, err = n.Reconcile(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, 0, testutil.CollectAndCount(metricsWebhookCertExpiry))
+}
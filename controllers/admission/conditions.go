@@ -4,6 +4,9 @@
 package admission
 
 import (
+	"fmt"
+	"time"
+
 	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
 	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
 	"go.mondoo.com/mondoo-operator/pkg/utils/mondoo"
@@ -57,3 +60,30 @@ func updateAdmissionConditions(config *mondoov1alpha2.MondooAuditConfig, degrade
 
 	config.Status.Conditions = mondoo.SetMondooAuditCondition(config.Status.Conditions, mondoov1alpha2.AdmissionDegraded, status, reason, msg, updateCheck, affectedPods, memoryLimit)
 }
+
+// updateCertificateConditions sets the AdmissionDegraded condition with the expiry date if the webhook serving
+// certificate cannot be used or expires within the window. It returns true if the condition was set.
+func updateCertificateConditions(config *mondoov1alpha2.MondooAuditConfig, check certificateCheck, window time.Duration, now time.Time) bool {
+	expiry := check.notAfter.UTC().Format(time.RFC3339)
+	var reason, msg string
+	switch {
+	case check.err != nil && check.notAfter.IsZero():
+		reason = "AdmissionCertificateInvalid"
+		msg = fmt.Sprintf("Admission webhook certificate: %s", check.err)
+	case check.err != nil:
+		reason = "AdmissionCertificateInvalid"
+		msg = fmt.Sprintf("Admission webhook certificate expiring at %s: %s", expiry, check.err)
+	case check.notAfter.IsZero() || check.notAfter.Sub(now) > window:
+		return false
+	case now.After(check.notAfter):
+		reason = "AdmissionCertificateExpired"
+		msg = fmt.Sprintf("Admission webhook certificate expired at %s", expiry)
+	default:
+		reason = "AdmissionCertificateExpiring"
+		msg = fmt.Sprintf("Admission webhook certificate expires at %s", expiry)
+	}
+
+	config.Status.Conditions = mondoo.SetMondooAuditCondition(config.Status.Conditions, mondoov1alpha2.AdmissionDegraded,
+		corev1.ConditionTrue, reason, msg, mondoo.UpdateConditionIfReasonOrMessageChange, []string{}, "")
+	return true
+}
@@ -4,11 +4,13 @@
 package admission
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils/certificates"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -130,6 +132,61 @@ func TestConditions_OOM_Available(t *testing.T) {
 	assert.Empty(t, cond.AffectedPods)
 }
 
+func TestConditions_CertificateValid(t *testing.T) {
+	config := &v1alpha2.MondooAuditConfig{}
+	now := time.Now()
+
+	assert.False(t, updateCertificateConditions(config, certificateCheck{notAfter: now.Add(certificates.SelfSignedValidity)}, defaultCertificateExpiryWindow, now))
+	assert.False(t, updateCertificateConditions(config, certificateCheck{}, defaultCertificateExpiryWindow, now))
+	assert.Empty(t, config.Status.Conditions)
+}
+
+func TestConditions_CertificateExpiring(t *testing.T) {
+	config := &v1alpha2.MondooAuditConfig{}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, updateCertificateConditions(config, certificateCheck{notAfter: now.Add(10 * 24 * time.Hour)}, 14*24*time.Hour, now))
+
+	cond := config.Status.Conditions[0]
+	assert.Equal(t, "Admission webhook certificate expires at 2024-05-11T00:00:00Z", cond.Message)
+	assert.Equal(t, "AdmissionCertificateExpiring", cond.Reason)
+	assert.Equal(t, corev1.ConditionTrue, cond.Status)
+	assert.Equal(t, v1alpha2.AdmissionDegraded, cond.Type)
+
+	// outside of a shorter window
+	config = &v1alpha2.MondooAuditConfig{}
+	assert.False(t, updateCertificateConditions(config, certificateCheck{notAfter: now.Add(10 * 24 * time.Hour)}, 7*24*time.Hour, now))
+}
+
+func TestConditions_CertificateExpired(t *testing.T) {
+	config := &v1alpha2.MondooAuditConfig{}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.True(t, updateCertificateConditions(config, certificateCheck{notAfter: now.Add(-time.Hour)}, defaultCertificateExpiryWindow, now))
+
+	cond := config.Status.Conditions[0]
+	assert.Equal(t, "Admission webhook certificate expired at 2024-04-30T23:00:00Z", cond.Message)
+	assert.Equal(t, "AdmissionCertificateExpired", cond.Reason)
+	assert.Equal(t, corev1.ConditionTrue, cond.Status)
+}
+
+func TestConditions_CertificateInvalid(t *testing.T) {
+	config := &v1alpha2.MondooAuditConfig{}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	check := certificateCheck{notAfter: now.Add(certificates.SelfSignedValidity), err: fmt.Errorf("failed to rotate the self-signed certificate: forbidden")}
+	assert.True(t, updateCertificateConditions(config, check, defaultCertificateExpiryWindow, now))
+
+	cond := config.Status.Conditions[0]
+	assert.Equal(t, "Admission webhook certificate expiring at 2025-05-01T00:00:00Z: failed to rotate the self-signed certificate: forbidden", cond.Message)
+	assert.Equal(t, "AdmissionCertificateInvalid", cond.Reason)
+	assert.Equal(t, corev1.ConditionTrue, cond.Status)
+
+	check = certificateCheck{err: fmt.Errorf("no PEM encoded certificate found")}
+	assert.True(t, updateCertificateConditions(config, check, defaultCertificateExpiryWindow, now))
+	assert.Equal(t, "Admission webhook certificate: no PEM encoded certificate found", config.Status.Conditions[0].Message)
+}
+
 func oomPodList() *corev1.PodList {
 	return &corev1.PodList{
 		Items: []corev1.Pod{
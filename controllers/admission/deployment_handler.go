@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"time"
 
 	webhooksv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
@@ -40,6 +41,9 @@ type DeploymentHandler struct {
 	TargetNamespace        string
 	ContainerImageResolver mondoo.ContainerImageResolver
 	MondooOperatorConfig   *mondoov1alpha2.MondooOperatorConfig
+
+	// caBundle is the CA bundle injected into the webhook configurations in self-signed mode
+	caBundle []byte
 }
 
 // syncValidatingWebhookConfiguration will create/update the ValidatingWebhookConfiguration
@@ -65,6 +69,9 @@ func (n *DeploymentHandler) syncValidatingWebhookConfiguration(ctx context.Conte
 		if vwc.Webhooks[i].ClientConfig.Service.Port == nil {
 			vwc.Webhooks[i].ClientConfig.Service.Port = ptr.To(int32(443))
 		}
+		if len(n.caBundle) > 0 {
+			vwc.Webhooks[i].ClientConfig.CABundle = n.caBundle
+		}
 	}
 
 	metav1.SetMetaDataAnnotation(&vwc.ObjectMeta, annotationKey, annotationValue)
@@ -130,26 +137,106 @@ func deepEqualsValidatingWebhookConfiguration(existing, desired *webhooksv1.Vali
 			return false
 		}
 
+		// The CA bundle is only compared if we inject it ourselves instead of a certificate provisioner
+		if len(desired.Webhooks[i].ClientConfig.CABundle) > 0 &&
+			!bytes.Equal(existing.Webhooks[i].ClientConfig.CABundle, desired.Webhooks[i].ClientConfig.CABundle) {
+			return false
+		}
+
 		if existing.Webhooks[i].Name != desired.Webhooks[i].Name {
 			return false
 		}
 
-		if len(existing.Webhooks[i].Rules) != len(desired.Webhooks[i].Rules) {
+		if !reflect.DeepEqual(existing.Webhooks[i].FailurePolicy, desired.Webhooks[i].FailurePolicy) ||
+			!reflect.DeepEqual(existing.Webhooks[i].TimeoutSeconds, desired.Webhooks[i].TimeoutSeconds) {
 			return false
 		}
 
-		for j := range existing.Webhooks[i].Rules {
-			existingRule := existing.Webhooks[i].Rules[j]
-			desiredRule := desired.Webhooks[i].Rules[j]
-			if !reflect.DeepEqual(existingRule.APIGroups, desiredRule.APIGroups) ||
-				!reflect.DeepEqual(existingRule.APIVersions, desiredRule.APIVersions) ||
-				!reflect.DeepEqual(existingRule.Operations, desiredRule.Operations) ||
-				!reflect.DeepEqual(existingRule.Resources, desiredRule.Resources) {
-				return false
-			}
+		if !labelSelectorsEqual(existing.Webhooks[i].NamespaceSelector, desired.Webhooks[i].NamespaceSelector) ||
+			!labelSelectorsEqual(existing.Webhooks[i].ObjectSelector, desired.Webhooks[i].ObjectSelector) {
+			return false
 		}
+
+		if !rulesEqual(existing.Webhooks[i].Rules, desired.Webhooks[i].Rules) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deepEqualsMutatingWebhookConfiguration compares the fields of the MutatingWebhookConfiguration we care about,
+// like deepEqualsValidatingWebhookConfiguration does for the ValidatingWebhookConfiguration.
+func deepEqualsMutatingWebhookConfiguration(existing, desired *webhooksv1.MutatingWebhookConfiguration, annotationKey string) bool {
+	if existing.Annotations == nil || existing.Annotations[annotationKey] != desired.Annotations[annotationKey] {
+		return false
+	}
+
+	if len(existing.Webhooks) != len(desired.Webhooks) || !reflect.DeepEqual(existing.Labels, desired.Labels) {
+		return false
+	}
+
+	for i := range existing.Webhooks {
+		if !reflect.DeepEqual(existing.Webhooks[i].ClientConfig.Service, desired.Webhooks[i].ClientConfig.Service) ||
+			(len(desired.Webhooks[i].ClientConfig.CABundle) > 0 &&
+				!bytes.Equal(existing.Webhooks[i].ClientConfig.CABundle, desired.Webhooks[i].ClientConfig.CABundle)) ||
+			existing.Webhooks[i].Name != desired.Webhooks[i].Name ||
+			!reflect.DeepEqual(existing.Webhooks[i].FailurePolicy, desired.Webhooks[i].FailurePolicy) ||
+			!reflect.DeepEqual(existing.Webhooks[i].TimeoutSeconds, desired.Webhooks[i].TimeoutSeconds) {
+			return false
+		}
+
+		if !labelSelectorsEqual(existing.Webhooks[i].NamespaceSelector, desired.Webhooks[i].NamespaceSelector) ||
+			!labelSelectorsEqual(existing.Webhooks[i].ObjectSelector, desired.Webhooks[i].ObjectSelector) {
+			return false
+		}
+
+		if !rulesEqual(existing.Webhooks[i].Rules, desired.Webhooks[i].Rules) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rulesEqual compares the webhook rules
+func rulesEqual(existing, desired []webhooksv1.RuleWithOperations) bool {
+	if len(existing) != len(desired) {
+		return false
+	}
+
+	for j := range existing {
+		if !reflect.DeepEqual(existing[j].APIGroups, desired[j].APIGroups) ||
+			!reflect.DeepEqual(existing[j].APIVersions, desired[j].APIVersions) ||
+			!reflect.DeepEqual(existing[j].Operations, desired[j].Operations) ||
+			!reflect.DeepEqual(existing[j].Resources, desired[j].Resources) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelSelectorsEqual compares two label selectors. The API server defaults a nil selector
+// to an empty one, so both are treated the same.
+func labelSelectorsEqual(a, b *metav1.LabelSelector) bool {
+	if a == nil {
+		a = &metav1.LabelSelector{}
 	}
+	if b == nil {
+		b = &metav1.LabelSelector{}
+	}
+	return len(a.MatchLabels) == len(b.MatchLabels) && len(a.MatchExpressions) == len(b.MatchExpressions) &&
+		(len(a.MatchLabels) == 0 || reflect.DeepEqual(a.MatchLabels, b.MatchLabels)) &&
+		(len(a.MatchExpressions) == 0 || reflect.DeepEqual(a.MatchExpressions, b.MatchExpressions))
+}
 
+// hasLabels checks whether all of the desired labels are set
+func hasLabels(labels, desired map[string]string) bool {
+	for k, v := range desired {
+		if labels[k] != v {
+			return false
+		}
+	}
 	return true
 }
 
@@ -179,7 +266,7 @@ func (n *DeploymentHandler) syncWebhookService(ctx context.Context) error {
 	}
 
 	tlsSecretName := GetTLSCertificatesSecretName(n.Mondoo.Name)
-	if !k8s.AreServicesEqual(*desiredService, *service) ||
+	if !k8s.AreServicesEqual(*desiredService, *service) || !hasLabels(service.Labels, desiredService.Labels) ||
 		(n.Mondoo.Spec.Admission.CertificateProvisioning.Mode == mondoov1alpha2.OpenShiftProvisioning &&
 			(!metav1.HasAnnotation(service.ObjectMeta, openShiftServiceAnnotationKey) ||
 				service.Annotations[openShiftServiceAnnotationKey] != tlsSecretName)) {
@@ -187,6 +274,9 @@ func (n *DeploymentHandler) syncWebhookService(ctx context.Context) error {
 			metav1.SetMetaDataAnnotation(&service.ObjectMeta, openShiftServiceAnnotationKey, tlsSecretName)
 		}
 		k8s.UpdateService(service, *desiredService)
+		for k, v := range desiredService.Labels {
+			metav1.SetMetaDataLabel(&service.ObjectMeta, k, v)
+		}
 		if err := n.KubeClient.Update(ctx, service); err != nil {
 			webhookLog.Error(err, "failed to update existing webhook Service")
 			return err
@@ -211,6 +301,25 @@ func (n *DeploymentHandler) syncWebhookDeployment(ctx context.Context) error {
 		}
 	}
 
+	// The serving certificate is generated before the Deployment, so the webhook Pods can mount it right away
+	if n.Mondoo.Spec.Admission.CertificateProvisioning.Mode == mondoov1alpha2.SelfSignedProvisioning {
+		ss := &SelfSignedHandler{
+			KubeClient:      n.KubeClient,
+			TargetNamespace: n.TargetNamespace,
+			Mondoo:          n.Mondoo,
+			Scheme:          n.KubeClient.Scheme(),
+			RotationWindow:  certificateExpiryWindow(n.Mondoo),
+		}
+
+		caBundle, notAfter, err := ss.Setup(ctx)
+		if err != nil {
+			check := certificateCheck{notAfter: notAfter, err: fmt.Errorf("failed to rotate the self-signed certificate: %w", err)}
+			updateCertificateConditions(n.Mondoo, check, certificateExpiryWindow(n.Mondoo), time.Now())
+			return err
+		}
+		n.caBundle = caBundle
+	}
+
 	clusterID, err := k8s.GetClusterUID(ctx, n.KubeClient, webhookLog)
 	if err != nil {
 		return err
@@ -263,7 +372,11 @@ func (n *DeploymentHandler) syncWebhookDeployment(ctx context.Context) error {
 		return err
 	}
 
-	updateAdmissionConditions(n.Mondoo, n.isWebhookDegraded(existingDeployment), pods)
+	// An expiring or untrusted serving certificate takes precedence, as it breaks the webhook even if its Pods are ready
+	now := time.Now()
+	if !updateCertificateConditions(n.Mondoo, n.checkCertificate(ctx, now), certificateExpiryWindow(n.Mondoo), now) {
+		updateAdmissionConditions(n.Mondoo, n.isWebhookDegraded(existingDeployment), pods)
+	}
 
 	// Not a full check for whether someone has modified our Deployment, but checking for some important bits so we know
 	// if an Update() is needed.
@@ -292,7 +405,9 @@ func (n *DeploymentHandler) isWebhookDegraded(deployment *appsv1.Deployment) boo
 	return deployment.Status.ReadyReplicas < deployment.Status.Replicas
 }
 
-func (n *DeploymentHandler) prepareValidatingWebhook(ctx context.Context, vwc *webhooksv1.ValidatingWebhookConfiguration) error {
+// certificateAnnotation returns the annotation which makes the configured certificate provisioner
+// inject the CA data into the webhook configurations
+func (n *DeploymentHandler) certificateAnnotation() (string, string) {
 	var annotationKey, annotationValue string
 
 	switch n.Mondoo.Spec.Admission.CertificateProvisioning.Mode {
@@ -304,11 +419,7 @@ func (n *DeploymentHandler) prepareValidatingWebhook(ctx context.Context, vwc *w
 			Scheme:          n.KubeClient.Scheme(),
 		}
 
-		var err error
 		annotationKey, annotationValue = cm.GetAnnotations()
-		if err != nil {
-			return err
-		}
 
 	case mondoov1alpha2.OpenShiftProvisioning:
 		// For OpenShift we just annotate the webhook so that the necessary CA data is injected
@@ -316,22 +427,68 @@ func (n *DeploymentHandler) prepareValidatingWebhook(ctx context.Context, vwc *w
 		annotationKey = openShiftWebhookAnnotationKey
 		annotationValue = "true"
 
+	case mondoov1alpha2.SelfSignedProvisioning:
+		// The operator generates the certificates and injects the CA data into the webhook itself
+		annotationKey = manualTLSAnnotationKey
+		annotationValue = string(mondoov1alpha2.SelfSignedProvisioning)
+
 	default:
 		// Consider this "manual" mode where the user is responsible for populating the Secret with
 		// appropriate TLS certificates. Populating the Secret will unblock the Pod and allow it to run.
 		// User also needs to populate the CA data on the webhook.
-		// So just apply the ValidatingWebhookConfiguration as-is
+		// So just apply the webhook configurations as-is
 		annotationKey = manualTLSAnnotationKey
 		annotationValue = "manual"
 	}
+	return annotationKey, annotationValue
+}
+
+func (n *DeploymentHandler) prepareValidatingWebhook(ctx context.Context, vwc *webhooksv1.ValidatingWebhookConfiguration) error {
+	annotationKey, annotationValue := n.certificateAnnotation()
+
+	// The webhook for exec, attach and port-forward requests is only registered if enabled
+	connect := n.Mondoo.Spec.Admission.ConnectAuditing
+	webhooks := []webhooksv1.ValidatingWebhook{}
+	for _, w := range vwc.Webhooks {
+		if w.Name != connectAuditingWebhookName || connect.Enable {
+			webhooks = append(webhooks, w)
+		}
+	}
+	vwc.Webhooks = webhooks
 
 	for i := range vwc.Webhooks {
-		if n.Mondoo.Spec.Admission.Mode == mondoov1alpha2.Enforcing {
+		if vwc.Webhooks[i].Name == connectAuditingWebhookName {
+			// Interactive access is only blocked during a webhook outage if it is denied in production
+			vwc.Webhooks[i].FailurePolicy = ptr.To(webhooksv1.Ignore)
+			if connect.DenyExecInProduction && effectiveFailurePolicy(n.Mondoo.Spec.Admission) == mondoov1alpha2.FailClosed {
+				vwc.Webhooks[i].FailurePolicy = ptr.To(webhooksv1.Fail)
+			}
+			if n.Mondoo.Spec.Admission.TimeoutSeconds > 0 {
+				vwc.Webhooks[i].TimeoutSeconds = ptr.To(n.Mondoo.Spec.Admission.TimeoutSeconds)
+			}
+			vwc.Webhooks[i].NamespaceSelector = webhookNamespaceSelector(n.Mondoo.Spec.Filtering.Namespaces)
+			continue
+		}
+
+		if effectiveFailurePolicy(n.Mondoo.Spec.Admission) == mondoov1alpha2.FailClosed {
 			*vwc.Webhooks[i].FailurePolicy = webhooksv1.Fail
 		} else {
 			*vwc.Webhooks[i].FailurePolicy = webhooksv1.Ignore
 		}
+
+		if n.Mondoo.Spec.Admission.TimeoutSeconds > 0 {
+			vwc.Webhooks[i].TimeoutSeconds = ptr.To(n.Mondoo.Spec.Admission.TimeoutSeconds)
+		}
+
+		if resources := admissionResources(n.Mondoo.Spec); resources != nil {
+			vwc.Webhooks[i].Rules = webhookRules(resources)
+		}
+
+		// Let the API server skip excluded Namespaces and objects instead of filtering them in the webhook
+		vwc.Webhooks[i].NamespaceSelector = webhookNamespaceSelector(n.Mondoo.Spec.Filtering.Namespaces)
+		vwc.Webhooks[i].ObjectSelector = webhookObjectSelector()
 	}
+	vwc.Webhooks = withEnforcingNamespacesWebhook(vwc.Webhooks, n.Mondoo.Spec.Admission)
 
 	// For AKS the ValidatingWebhookConfiguration would normally not be notified about resources
 	// in Namespaces with the label key 'control-plane'. Adding the label "admissions.enforcer/disabled": "true"
@@ -344,11 +501,135 @@ func (n *DeploymentHandler) prepareValidatingWebhook(ctx context.Context, vwc *w
 	return n.syncValidatingWebhookConfiguration(ctx, vwc, annotationKey, annotationValue)
 }
 
+// prepareMutatingWebhook creates/updates the MutatingWebhookConfiguration with the enabled mutating
+// webhooks and removes it if none is enabled
+func (n *DeploymentHandler) prepareMutatingWebhook(ctx context.Context, mwc *webhooksv1.MutatingWebhookConfiguration) error {
+	mwcName, err := mutatingWebhookName(n.Mondoo)
+	if err != nil {
+		webhookLog.Error(err, "failed to generate Webhook name")
+		return err
+	}
+	mwc.SetName(mwcName)
+
+	mutation := n.Mondoo.Spec.Admission.Mutation
+	enabled := map[string]bool{
+		imageDigestPinningWebhookName: mutation.ImageDigestPinning.Enable,
+		scanResultWebhookName:         mutation.AnnotateScanResults,
+	}
+	webhooks := []webhooksv1.MutatingWebhook{}
+	for _, w := range mwc.Webhooks {
+		if enabled[w.Name] {
+			webhooks = append(webhooks, w)
+		}
+	}
+	mwc.Webhooks = webhooks
+
+	if len(mwc.Webhooks) == 0 {
+		return k8s.DeleteIfExists(ctx, n.KubeClient, mwc)
+	}
+
+	annotationKey, annotationValue := n.certificateAnnotation()
+	metav1.SetMetaDataAnnotation(&mwc.ObjectMeta, annotationKey, annotationValue)
+
+	for i := range mwc.Webhooks {
+		mwc.Webhooks[i].ClientConfig.Service.Name = webhookServiceName(n.Mondoo.Name)
+		mwc.Webhooks[i].ClientConfig.Service.Namespace = n.Mondoo.Namespace
+		if mwc.Webhooks[i].ClientConfig.Service.Port == nil {
+			mwc.Webhooks[i].ClientConfig.Service.Port = ptr.To(int32(443))
+		}
+		if len(n.caBundle) > 0 {
+			mwc.Webhooks[i].ClientConfig.CABundle = n.caBundle
+		}
+
+		// The annotations are informational, so objects are never rejected because of the mutating webhook
+		mwc.Webhooks[i].FailurePolicy = ptr.To(webhooksv1.Ignore)
+
+		if n.Mondoo.Spec.Admission.TimeoutSeconds > 0 {
+			mwc.Webhooks[i].TimeoutSeconds = ptr.To(n.Mondoo.Spec.Admission.TimeoutSeconds)
+		}
+
+		if resources := admissionResources(n.Mondoo.Spec); resources != nil {
+			mwc.Webhooks[i].Rules = webhookRules(resources)
+		}
+		if mwc.Webhooks[i].Name == imageDigestPinningWebhookName {
+			mwc.Webhooks[i].Rules = imageDigestPinningRules(mwc.Webhooks[i].Rules)
+		}
+
+		mwc.Webhooks[i].NamespaceSelector = webhookNamespaceSelector(n.Mondoo.Spec.Filtering.Namespaces)
+		mwc.Webhooks[i].ObjectSelector = webhookObjectSelector()
+	}
+
+	if mwc.Labels == nil {
+		mwc.Labels = map[string]string{}
+	}
+	mwc.Labels["admissions.enforcer/disabled"] = "true"
+
+	existingMWC := &webhooksv1.MutatingWebhookConfiguration{}
+	created, err := k8s.CreateIfNotExist(ctx, n.KubeClient, existingMWC, mwc)
+	if err != nil {
+		webhookLog.Error(err, "Failed to create MutatingWebhookConfiguration resource")
+		return err
+	}
+
+	if created {
+		webhookLog.Info("MutatingWebhookConfiguration created")
+		return nil
+	}
+
+	if !deepEqualsMutatingWebhookConfiguration(existingMWC, mwc, annotationKey) {
+		existingMWC.Webhooks = mwc.Webhooks
+		existingMWC.Labels = mwc.Labels
+
+		if existingMWC.Annotations == nil {
+			existingMWC.Annotations = map[string]string{}
+		}
+		for _, key := range webhookAnnotationList {
+			delete(existingMWC.Annotations, key)
+		}
+		existingMWC.Annotations[annotationKey] = annotationValue
+
+		if err := n.KubeClient.Update(ctx, existingMWC); err != nil {
+			webhookLog.Error(err, "Failed to update existing MutatingWebhookConfiguration resource")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncImageDigestConfigMap creates the ConfigMap which stores the resolved image digests if image digest
+// pinning is enabled and removes it otherwise
+func (n *DeploymentHandler) syncImageDigestConfigMap(ctx context.Context) error {
+	desired := WebhookImageDigestConfigMap(n.TargetNamespace, *n.Mondoo)
+	if !n.Mondoo.Spec.Admission.Mutation.ImageDigestPinning.Enable {
+		return k8s.DeleteIfExists(ctx, n.KubeClient, desired)
+	}
+
+	if err := n.setControllerRef(desired); err != nil {
+		return err
+	}
+
+	// the webhook owns the data, so an existing ConfigMap is never updated
+	created, err := k8s.CreateIfNotExist(ctx, n.KubeClient, &corev1.ConfigMap{}, desired)
+	if err != nil {
+		webhookLog.Error(err, "failed to create ConfigMap for image digests")
+		return err
+	}
+	if created {
+		webhookLog.Info("Created image digest ConfigMap")
+	}
+	return nil
+}
+
 func (n *DeploymentHandler) applyWebhooks(ctx context.Context) (ctrl.Result, error) {
 	if err := n.syncWebhookService(ctx); err != nil {
 		return ctrl.Result{}, err
 	}
 
+	if err := n.syncImageDigestConfigMap(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	if err := n.syncWebhookDeployment(ctx); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -406,6 +687,12 @@ func (n *DeploymentHandler) applyWebhooks(ctx context.Context) (ctrl.Result, err
 				return ctrl.Result{}, fmt.Errorf("failed to convert to ValidatingWebhookConfiguration")
 			}
 			syncErr = n.prepareValidatingWebhook(ctx, vwc)
+		case "MutatingWebhookConfiguration":
+			mwc, ok := obj.(*webhooksv1.MutatingWebhookConfiguration)
+			if !ok {
+				return ctrl.Result{}, fmt.Errorf("failed to convert to MutatingWebhookConfiguration")
+			}
+			syncErr = n.prepareMutatingWebhook(ctx, mwc)
 		default:
 			err := fmt.Errorf("unexpected type %s to decode", gvk.Kind)
 			webhookLog.Error(err, "Failed to convert type")
@@ -413,7 +700,7 @@ func (n *DeploymentHandler) applyWebhooks(ctx context.Context) (ctrl.Result, err
 		}
 
 		if syncErr != nil {
-			return ctrl.Result{}, err
+			return ctrl.Result{}, syncErr
 		}
 
 	}
@@ -437,6 +724,16 @@ func (n *DeploymentHandler) Reconcile(ctx context.Context) (ctrl.Result, error)
 			return ctrl.Result{}, err
 		}
 
+		ss := SelfSignedHandler{
+			TargetNamespace: n.TargetNamespace,
+			KubeClient:      n.KubeClient,
+			Mondoo:          n.Mondoo,
+			Scheme:          n.KubeClient.Scheme(),
+		}
+		if err := ss.Cleanup(ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+
 		result, err := n.down(ctx)
 		if err != nil || result.Requeue {
 			return result, err
@@ -474,7 +771,12 @@ func (n *DeploymentHandler) down(ctx context.Context) (ctrl.Result, error) {
 		return ctrl.Result{}, err
 	}
 
-	// Cleanup ValidatingWebhooks
+	if err := k8s.DeleteIfExists(ctx, n.KubeClient, WebhookImageDigestConfigMap(n.TargetNamespace, *n.Mondoo)); err != nil {
+		webhookLog.Error(err, "failed to clean up image digest ConfigMap resource")
+		return ctrl.Result{}, err
+	}
+
+	// Cleanup ValidatingWebhooks and MutatingWebhooks
 	r := bytes.NewReader(webhookManifestsyaml)
 	yamlDecoder := yamlutil.NewYAMLOrJSONDecoder(r, 4096)
 	objectDecoder := scheme.Codecs.UniversalDeserializer()
@@ -484,6 +786,11 @@ func (n *DeploymentHandler) down(ctx context.Context) (ctrl.Result, error) {
 		webhookLog.Error(err, "failed to generate Webhook name")
 		return ctrl.Result{}, err
 	}
+	mwcName, err := mutatingWebhookName(n.Mondoo)
+	if err != nil {
+		webhookLog.Error(err, "failed to generate Webhook name")
+		return ctrl.Result{}, err
+	}
 
 	// Go through each YAML object, convert as needed to Delete()
 	for {
@@ -516,6 +823,11 @@ func (n *DeploymentHandler) down(ctx context.Context) (ctrl.Result, error) {
 			if conversionOK {
 				genericObject.SetName(vwcName)
 			}
+		case "MutatingWebhookConfiguration":
+			genericObject, conversionOK = obj.(*webhooksv1.MutatingWebhookConfiguration)
+			if conversionOK {
+				genericObject.SetName(mwcName)
+			}
 		default:
 			err := fmt.Errorf("unexpected type %s to decode", gvk.Kind)
 			webhookLog.Error(err, "Failed to convert type")
@@ -535,6 +847,7 @@ func (n *DeploymentHandler) down(ctx context.Context) (ctrl.Result, error) {
 
 	// Make sure to clear any degraded status
 	updateAdmissionConditions(n.Mondoo, false, &corev1.PodList{})
+	metricsWebhookCertExpiry.DeleteLabelValues(n.Mondoo.Namespace, n.Mondoo.Name)
 
 	return ctrl.Result{}, nil
 }
@@ -4,6 +4,7 @@
 package admission
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -17,12 +18,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
 	scheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 
 	certmanagerv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 
@@ -218,6 +222,93 @@ func TestReconcile(t *testing.T) {
 				assert.NoError(t, err, "error retrieving cert-manager Certificate that should exist")
 			},
 		},
+		{
+			name: "admission enabled with self-signed certificates",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.FailurePolicy = mondoov1alpha2.FailClosed
+				mac.Admission.Mutation.AnnotateScanResults = true
+				mac.Admission.CertificateProvisioning = mondoov1alpha2.CertificateProvisioning{
+					Mode: mondoov1alpha2.SelfSignedProvisioning,
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				secret := &corev1.Secret{}
+				secretKey := types.NamespacedName{Name: GetTLSCertificatesSecretName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), secretKey, secret), "expected webhook TLS Secret to exist")
+				require.NotEmpty(t, secret.Data[caCertKey])
+
+				vwc := getValidatingWebhook(t, kubeClient)
+				assert.Equal(t, "self-signed", vwc.Annotations[manualTLSAnnotationKey])
+				for _, w := range vwc.Webhooks {
+					assert.Equal(t, secret.Data[caCertKey], w.ClientConfig.CABundle)
+				}
+
+				mwcName, err := mutatingWebhookName(&mondoov1alpha2.MondooAuditConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: testMondooAuditConfigName, Namespace: testNamespace},
+				})
+				require.NoError(t, err)
+				mwc := &webhooksv1.MutatingWebhookConfiguration{}
+				require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKey{Name: mwcName}, mwc), "expected MutatingWebhookConfiguration to exist")
+				for _, w := range mwc.Webhooks {
+					assert.Equal(t, secret.Data[caCertKey], w.ClientConfig.CABundle)
+				}
+			},
+		},
+		{
+			name: "inject rotated CA bundle into existing webhook",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.FailurePolicy = mondoov1alpha2.FailClosed
+				mac.Admission.CertificateProvisioning = mondoov1alpha2.CertificateProvisioning{
+					Mode: mondoov1alpha2.SelfSignedProvisioning,
+				}
+				return mac
+			}(),
+			existingObjects: func(m mondoov1alpha2.MondooAuditConfig) []client.Object {
+				n := &DeploymentHandler{Mondoo: &m, KubeClient: fake.NewClientBuilder().Build(), TargetNamespace: testNamespace}
+				vwc := getValidatingWebhookFromManifests(t)
+				if err := n.prepareValidatingWebhook(context.TODO(), vwc); err != nil {
+					panic(err)
+				}
+				vwc.ResourceVersion = ""
+				for i := range vwc.Webhooks {
+					vwc.Webhooks[i].ClientConfig.CABundle = []byte("outdated")
+				}
+				return []client.Object{vwc}
+			},
+			validate: func(t *testing.T, kubeClient client.Client) {
+				secret := &corev1.Secret{}
+				secretKey := types.NamespacedName{Name: GetTLSCertificatesSecretName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), secretKey, secret), "expected webhook TLS Secret to exist")
+
+				vwc := getValidatingWebhook(t, kubeClient)
+				for _, w := range vwc.Webhooks {
+					assert.Equal(t, secret.Data[caCertKey], w.ClientConfig.CABundle)
+				}
+			},
+		},
+		{
+			name:                  "cleanup self-signed certificates when admission change to disabled",
+			mondooAuditConfigSpec: testMondooAuditConfigSpec(false, false),
+			existingObjects: func(m mondoov1alpha2.MondooAuditConfig) []client.Object {
+				secret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        GetTLSCertificatesSecretName(testMondooAuditConfigName),
+						Namespace:   testNamespace,
+						Annotations: map[string]string{manualTLSAnnotationKey: "self-signed"},
+					},
+				}
+				return []client.Object{secret}
+			},
+			validate: func(t *testing.T, kubeClient client.Client) {
+				secret := &corev1.Secret{}
+				secretKey := types.NamespacedName{Name: GetTLSCertificatesSecretName(testMondooAuditConfigName), Namespace: testNamespace}
+				err := kubeClient.Get(context.TODO(), secretKey, secret)
+				assert.True(t, errors.IsNotFound(err), "expected self-signed Secret to not exist when webhooks disabled")
+			},
+		},
 		{
 			name:                  "cleanup when admission change to disabled",
 			mondooAuditConfigSpec: testMondooAuditConfigSpec(false, false),
@@ -430,6 +521,468 @@ func TestReconcile(t *testing.T) {
 				assert.Truef(t, k8s.AreDeploymentsEqual(*deployment, *expectedDeployment), "deployment has not been updated")
 			},
 		},
+		{
+			name: "namespace selector from include list",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Filtering.Namespaces.Include = []string{"app1", "app2"}
+				mac.Filtering.Namespaces.Exclude = []string{"kube-system"}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				assert.Equal(t, &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: namespaceNameLabelKey, Operator: metav1.LabelSelectorOpIn, Values: []string{"app1", "app2"}},
+						{Key: admissionModeLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{string(mondoov1alpha2.Enforcing)}},
+					},
+				}, vwc.Webhooks[0].NamespaceSelector)
+				assert.Equal(t, webhookObjectSelector(), vwc.Webhooks[0].ObjectSelector)
+			},
+		},
+		{
+			name: "namespace selector from exclude list skips globs",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Filtering.Namespaces.Exclude = []string{"kube-system", "test-*"}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				assert.Equal(t, &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: namespaceNameLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"}},
+						{Key: admissionModeLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{string(mondoov1alpha2.Enforcing)}},
+					},
+				}, vwc.Webhooks[0].NamespaceSelector)
+			},
+		},
+		{
+			name: "namespace selector from label selector",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Filtering.Namespaces.Exclude = []string{"kube-system"}
+				mac.Filtering.Namespaces.Selector = &metav1.LabelSelector{
+					MatchLabels: map[string]string{"mondoo": "enabled"},
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				assert.Equal(t, &metav1.LabelSelector{
+					MatchLabels: map[string]string{"mondoo": "enabled"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: namespaceNameLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kube-system"}},
+						{Key: admissionModeLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{string(mondoov1alpha2.Enforcing)}},
+					},
+				}, vwc.Webhooks[0].NamespaceSelector)
+
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				assert.Subset(t, deployment.Spec.Template.Spec.Containers[0].Args, []string{"--namespace-selector", "mondoo=enabled"})
+			},
+		},
+		{
+			name: "no namespace selector for include globs",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Filtering.Namespaces.Include = []string{"app1", "team-*"}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				// only the split for Namespaces labeled to enforce remains
+				assert.Equal(t, []metav1.LabelSelectorRequirement{
+					{Key: admissionModeLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{string(mondoov1alpha2.Enforcing)}},
+				}, vwc.Webhooks[0].NamespaceSelector.MatchExpressions)
+			},
+		},
+		{
+			name: "update webhook selectors when filtering changes",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Filtering.Namespaces.Exclude = []string{"kube-system"}
+				return mac
+			}(),
+			existingObjects: func(m mondoov1alpha2.MondooAuditConfig) []client.Object {
+				vwc := getValidatingWebhookFromManifests(t)
+				vwcName, err := validatingWebhookName(&m)
+				require.NoError(t, err)
+				vwc.Name = vwcName
+				vwc.Annotations = map[string]string{manualTLSAnnotationKey: "manual"}
+				vwc.Labels = map[string]string{"admissions.enforcer/disabled": "true"}
+				for i := range vwc.Webhooks {
+					vwc.Webhooks[i].ClientConfig.Service.Name = webhookServiceName(m.Name)
+					vwc.Webhooks[i].ClientConfig.Service.Namespace = m.Namespace
+					vwc.Webhooks[i].ClientConfig.Service.Port = ptr.To(int32(443))
+				}
+				return []client.Object{vwc}
+			},
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				assert.Equal(t, []string{"kube-system"}, vwc.Webhooks[0].NamespaceSelector.MatchExpressions[0].Values)
+				assert.Equal(t, webhookObjectSelector(), vwc.Webhooks[0].ObjectSelector)
+			},
+		},
+		{
+			name: "render configured admission resources into webhook rules",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.Resources = []mondoov1alpha2.AdmissionResource{
+					{Group: "", Version: "v1", Resource: "pods"},
+					{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+					{Group: "", Version: "v1", Resource: "services"},
+					{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				rules := vwc.Webhooks[0].Rules
+				require.Len(t, rules, 3)
+				assert.Equal(t, []string{""}, rules[0].APIGroups)
+				assert.Equal(t, []string{"pods", "services"}, rules[0].Resources)
+				assert.Equal(t, []string{"networking.k8s.io"}, rules[1].APIGroups)
+				assert.Equal(t, []string{"ingresses"}, rules[1].Resources)
+				assert.Equal(t, []string{"rbac.authorization.k8s.io"}, rules[2].APIGroups)
+				assert.Equal(t, []string{"clusterroles"}, rules[2].Resources)
+				assert.Equal(t, []webhooksv1.OperationType{webhooksv1.Create, webhooksv1.Update}, rules[2].Operations)
+			},
+		},
+		{
+			name: "filter webhook rules by kinds",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Filtering.Kinds.Exclude = []mondoov1alpha2.GroupVersionKind{
+					{Kind: "Pod"},
+					{Group: "batch", Kind: "Job"},
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				rules := vwc.Webhooks[0].Rules
+				require.Len(t, rules, 2)
+				assert.Equal(t, []string{"apps"}, rules[0].APIGroups)
+				assert.Equal(t, []string{"deployments", "daemonsets", "statefulsets"}, rules[0].Resources)
+				assert.Equal(t, []string{"batch"}, rules[1].APIGroups)
+				assert.Equal(t, []string{"cronjobs"}, rules[1].Resources)
+			},
+		},
+		{
+			name: "filter configured admission resources by kinds",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.Resources = []mondoov1alpha2.AdmissionResource{
+					{Group: "", Version: "v1", Resource: "pods"},
+					{Group: "apps", Version: "v1", Resource: "deployments"},
+					{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+				}
+				mac.Filtering.Kinds.Include = []mondoov1alpha2.GroupVersionKind{{Group: "apps", Kind: "Deployment"}}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				rules := vwc.Webhooks[0].Rules
+				require.Len(t, rules, 2)
+				assert.Equal(t, []string{"deployments"}, rules[0].Resources)
+				// custom resources are not filtered
+				assert.Equal(t, []string{"ingresses"}, rules[1].Resources)
+			},
+		},
+		{
+			name:                  "default webhook rules",
+			mondooAuditConfigSpec: testMondooAuditConfigSpec(true, false),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				// the connect auditing webhook is not registered by default, but the policy webhook is split
+				// for Namespaces labeled to enforce
+				require.Len(t, vwc.Webhooks, 2)
+				assert.Equal(t, getValidatingWebhookFromManifests(t).Webhooks[0].Rules, vwc.Webhooks[0].Rules)
+				assert.Equal(t, vwc.Webhooks[0].Rules, vwc.Webhooks[1].Rules)
+			},
+		},
+		{
+			name:                  "fail closed for namespaces labeled to enforce",
+			mondooAuditConfigSpec: testMondooAuditConfigSpec(true, false),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				require.Len(t, vwc.Webhooks, 2)
+				assert.Equal(t, webhooksv1.Ignore, *vwc.Webhooks[0].FailurePolicy)
+				assert.Contains(t, vwc.Webhooks[0].NamespaceSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+					Key: admissionModeLabelKey, Operator: metav1.LabelSelectorOpNotIn, Values: []string{string(mondoov1alpha2.Enforcing)},
+				})
+				assert.Equal(t, enforcingNamespacesWebhookName, vwc.Webhooks[1].Name)
+				assert.Equal(t, webhooksv1.Fail, *vwc.Webhooks[1].FailurePolicy)
+				assert.Contains(t, vwc.Webhooks[1].NamespaceSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+					Key: admissionModeLabelKey, Operator: metav1.LabelSelectorOpIn, Values: []string{string(mondoov1alpha2.Enforcing)},
+				})
+
+				// the webhook decides per request without an explicit failure policy
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				assert.NotContains(t, deployment.Spec.Template.Spec.Containers[0].Args, "--failure-policy")
+			},
+		},
+		{
+			name: "no split with an explicit failure policy",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.FailurePolicy = mondoov1alpha2.FailOpen
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				require.Len(t, vwc.Webhooks, 1)
+				assert.Equal(t, webhooksv1.Ignore, *vwc.Webhooks[0].FailurePolicy)
+			},
+		},
+		{
+			name: "failure policy and timeout",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.FailurePolicy = mondoov1alpha2.FailClosed
+				mac.Admission.TimeoutSeconds = 10
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				assert.Equal(t, webhooksv1.Fail, *vwc.Webhooks[0].FailurePolicy)
+				assert.Equal(t, ptr.To(int32(10)), vwc.Webhooks[0].TimeoutSeconds)
+
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				args := deployment.Spec.Template.Spec.Containers[0].Args
+				assert.Contains(t, args, string(mondoov1alpha2.FailClosed))
+				assert.Contains(t, args, "8s")
+			},
+		},
+		{
+			name: "enforcement rollout",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.Mode = mondoov1alpha2.Enforcing
+				mac.Admission.Rollout = &mondoov1alpha2.AdmissionRollout{
+					Percentage:       ptr.To(int32(25)),
+					CanaryNamespaces: []string{"canary-a", "canary-b"},
+					ShadowMode:       mondoov1alpha2.Permissive,
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				args := deployment.Spec.Template.Spec.Containers[0].Args
+				assert.Subset(t, args, []string{
+					"--rollout-percentage", "25",
+					"--canary-namespaces", "canary-a,canary-b",
+					"--rollout-shadow-mode", string(mondoov1alpha2.Permissive),
+				})
+			},
+		},
+		{
+			name: "image policy",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.ImagePolicy = mondoov1alpha2.AdmissionImagePolicy{
+					AllowedRegistries: []string{"registry.example.com", "*.azurecr.io"},
+					DisallowedTags:    []string{"latest"},
+					RequireDigest:     true,
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				args := deployment.Spec.Template.Spec.Containers[0].Args
+				assert.Subset(t, args, []string{
+					"--allowed-registries", "registry.example.com,*.azurecr.io",
+					"--disallowed-image-tags", "latest",
+					"--require-image-digest",
+				})
+			},
+		},
+		{
+			name: "scan API protection",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.ScanApiProtection = mondoov1alpha2.AdmissionScanApiProtection{
+					MaxConcurrentScans: 20,
+					FailureThreshold:   5,
+					OpenSeconds:        60,
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				args := deployment.Spec.Template.Spec.Containers[0].Args
+				assert.Subset(t, args, []string{
+					"--max-concurrent-scans", "20",
+					"--circuit-breaker-threshold", "5",
+					"--circuit-breaker-open-duration", "60s",
+				})
+			},
+		},
+		{
+			name: "async mode",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.Mode = mondoov1alpha2.Async
+				mac.Admission.Async = mondoov1alpha2.AdmissionAsync{
+					AnnotateScanResults: true,
+					QueueSize:           500,
+					Workers:             8,
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				args := deployment.Spec.Template.Spec.Containers[0].Args
+				assert.Subset(t, args, []string{
+					"--enforcement-mode", string(mondoov1alpha2.Async),
+					"--async-annotate-scan-results",
+					"--async-queue-size", "500",
+					"--async-workers", "8",
+				})
+			},
+		},
+		{
+			name: "connect auditing",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.FailurePolicy = mondoov1alpha2.FailClosed
+				mac.Admission.ConnectAuditing = mondoov1alpha2.AdmissionConnectAuditing{
+					Enable:               true,
+					DenyExecInProduction: true,
+					ProductionNamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"env": "prod"},
+					},
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				vwc := getValidatingWebhook(t, kubeClient)
+				require.Len(t, vwc.Webhooks, 2)
+				connect := vwc.Webhooks[1]
+				assert.Equal(t, connectAuditingWebhookName, connect.Name)
+				assert.Equal(t, webhooksv1.Fail, *connect.FailurePolicy)
+				assert.Equal(t, []webhooksv1.OperationType{webhooksv1.Connect}, connect.Rules[0].Operations)
+				assert.Equal(t, []string{"pods/exec", "pods/attach", "pods/portforward"}, connect.Rules[0].Resources)
+				assert.Nil(t, connect.ObjectSelector)
+
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				assert.Subset(t, deployment.Spec.Template.Spec.Containers[0].Args, []string{
+					"--audit-connect",
+					"--deny-exec-in-production",
+					"--production-namespace-selector", "env=prod",
+				})
+			},
+		},
+		{
+			name: "annotate scan results",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.Mutation.AnnotateScanResults = true
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				mwcName, err := mutatingWebhookName(&mondoov1alpha2.MondooAuditConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: testMondooAuditConfigName, Namespace: testNamespace},
+				})
+				require.NoError(t, err)
+
+				mwc := &webhooksv1.MutatingWebhookConfiguration{}
+				require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKey{Name: mwcName}, mwc), "expected MutatingWebhookConfiguration to exist")
+				require.Len(t, mwc.Webhooks, 1)
+				assert.Equal(t, webhooksv1.Ignore, *mwc.Webhooks[0].FailurePolicy)
+				assert.Equal(t, webhookServiceName(testMondooAuditConfigName), mwc.Webhooks[0].ClientConfig.Service.Name)
+				assert.Equal(t, "/mutate-k8s-mondoo-com", *mwc.Webhooks[0].ClientConfig.Service.Path)
+
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				assert.Contains(t, deployment.Spec.Template.Spec.Containers[0].Args, "--annotate-scan-results")
+			},
+		},
+		{
+			name: "pin image digests",
+			mondooAuditConfigSpec: func() mondoov1alpha2.MondooAuditConfigSpec {
+				mac := testMondooAuditConfigSpec(true, false)
+				mac.Admission.Mutation.ImageDigestPinning = mondoov1alpha2.AdmissionImageDigestPinning{
+					Enable:     true,
+					Registries: []string{"ghcr.io", "*.azurecr.io"},
+				}
+				return mac
+			}(),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				mwcName, err := mutatingWebhookName(&mondoov1alpha2.MondooAuditConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: testMondooAuditConfigName, Namespace: testNamespace},
+				})
+				require.NoError(t, err)
+
+				mwc := &webhooksv1.MutatingWebhookConfiguration{}
+				require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKey{Name: mwcName}, mwc), "expected MutatingWebhookConfiguration to exist")
+				require.Len(t, mwc.Webhooks, 1)
+				assert.Equal(t, imageDigestPinningWebhookName, mwc.Webhooks[0].Name)
+				// bare Pods and Jobs are only pinned on creation, workload controllers also on updates
+				rules := mwc.Webhooks[0].Rules
+				require.Len(t, rules, 2)
+				assert.Equal(t, []webhooksv1.OperationType{webhooksv1.Create}, rules[0].Operations)
+				assert.ElementsMatch(t, []string{"pods", "jobs"}, rules[0].Resources)
+				assert.Equal(t, []webhooksv1.OperationType{webhooksv1.Create, webhooksv1.Update}, rules[1].Operations)
+				assert.ElementsMatch(t, []string{"deployments", "daemonsets", "statefulsets", "cronjobs"}, rules[1].Resources)
+
+				cm := &corev1.ConfigMap{}
+				cmKey := types.NamespacedName{Name: imageDigestConfigMapName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), cmKey, cm), "expected image digest ConfigMap to exist")
+
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				assert.Subset(t, deployment.Spec.Template.Spec.Containers[0].Args, []string{
+					"--pin-image-digests",
+					"--image-digest-configmap", cm.Name,
+					"--pin-registries", "ghcr.io,*.azurecr.io",
+				})
+			},
+		},
+		{
+			name:                  "no mutating webhook by default",
+			mondooAuditConfigSpec: testMondooAuditConfigSpec(true, false),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				mwcs := &webhooksv1.MutatingWebhookConfigurationList{}
+				require.NoError(t, kubeClient.List(context.TODO(), mwcs))
+				assert.Empty(t, mwcs.Items)
+			},
+		},
+		{
+			name:                  "expose webhook metrics",
+			mondooAuditConfigSpec: testMondooAuditConfigSpec(true, false),
+			validate: func(t *testing.T, kubeClient client.Client) {
+				service := &corev1.Service{}
+				serviceKey := types.NamespacedName{Name: webhookServiceName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), serviceKey, service), "expected Webhook Service to exist")
+				assert.Equal(t, WebhookDeploymentLabels(), service.Labels)
+				require.Len(t, service.Spec.Ports, 2)
+				assert.Equal(t, webhookMetricsPortName, service.Spec.Ports[1].Name)
+
+				deployment := &appsv1.Deployment{}
+				deploymentKey := types.NamespacedName{Name: webhookDeploymentName(testMondooAuditConfigName), Namespace: testNamespace}
+				require.NoError(t, kubeClient.Get(context.TODO(), deploymentKey, deployment), "expected Webhook Deployment to exist")
+				assert.Equal(t, webhookMetricsPortName, deployment.Spec.Template.Spec.Containers[0].Ports[0].Name)
+			},
+		},
 		{
 			name:                  "update webhook Service when changed externally",
 			mondooAuditConfigSpec: testMondooAuditConfigSpec(true, false),
@@ -503,6 +1056,74 @@ func TestReconcile(t *testing.T) {
 	}
 }
 
+func TestReconcile_WebhookConfigurationError(t *testing.T) {
+	auditConfig := &mondoov1alpha2.MondooAuditConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testMondooAuditConfigName,
+			Namespace: testNamespace,
+		},
+		Spec: testMondooAuditConfigSpec(true, false),
+	}
+	kubeSystemNamespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kube-system",
+			UID:  types.UID(testClusterID),
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithStatusSubresource(auditConfig).
+		WithObjects(auditConfig, kubeSystemNamespace).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if _, ok := obj.(*webhooksv1.ValidatingWebhookConfiguration); ok {
+					return fmt.Errorf("admission webhook configurations are read-only")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	webhooks := &DeploymentHandler{
+		Mondoo:                 auditConfig,
+		KubeClient:             fakeClient,
+		TargetNamespace:        testNamespace,
+		MondooOperatorConfig:   &mondoov1alpha2.MondooOperatorConfig{},
+		ContainerImageResolver: fakeMondoo.NewNoOpContainerImageResolver(),
+	}
+
+	_, err := webhooks.Reconcile(context.TODO())
+	assert.ErrorContains(t, err, "admission webhook configurations are read-only")
+}
+
+func getValidatingWebhook(t *testing.T, kubeClient client.Client) *webhooksv1.ValidatingWebhookConfiguration {
+	vwcName, err := validatingWebhookName(&mondoov1alpha2.MondooAuditConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testMondooAuditConfigName,
+			Namespace: testNamespace,
+		},
+	})
+	require.NoError(t, err, "unexpected failure while generating Webhook name")
+
+	vwc := &webhooksv1.ValidatingWebhookConfiguration{}
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKey{Name: vwcName}, vwc), "expected ValidatingWebhookConfiguration to exist")
+	return vwc
+}
+
+func getValidatingWebhookFromManifests(t *testing.T) *webhooksv1.ValidatingWebhookConfiguration {
+	yamlDecoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(webhookManifestsyaml), 4096)
+	for {
+		rawObject := runtime.RawExtension{}
+		require.NoError(t, yamlDecoder.Decode(&rawObject), "expected a ValidatingWebhookConfiguration")
+
+		obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(rawObject.Raw, nil, nil)
+		require.NoError(t, err, "failed to decode webhook manifests")
+		if vwc, ok := obj.(*webhooksv1.ValidatingWebhookConfiguration); ok {
+			return vwc
+		}
+	}
+}
+
 func defaultResourcesWhenEnabled() []client.Object {
 	objects := []client.Object{}
 
@@ -0,0 +1,23 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package admission
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// metricsWebhookCertExpiry is the expiry as a Unix timestamp, so it stays accurate between reconciles
+var metricsWebhookCertExpiry = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "mondoo_webhook_cert_expiry_seconds",
+		Help: "Expiry of the admission webhook serving certificate in seconds since the Unix epoch by MondooAuditConfig namespace and name",
+	},
+	[]string{"namespace", "name"},
+)
+
+func init() {
+	// Register custom metrics with the global prometheus registry
+	metrics.Registry.MustRegister(metricsWebhookCertExpiry)
+}
@@ -6,24 +6,45 @@ package admission
 import (
 	"fmt"
 	"strings"
+	"time"
 
+	webhooksv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
 	"go.mondoo.com/mondoo-operator/controllers/scanapi"
+	"go.mondoo.com/mondoo-operator/pkg/constants"
 	"go.mondoo.com/mondoo-operator/pkg/feature_flags"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
 )
 
 const (
 	webhookDeploymentLabelKey   = "app.kubernetes.io/name"
 	webhookDeploymentLabelValue = "mondoo-operator-webhook"
 
+	// webhookMetricsPort is the port the webhook exposes its Prometheus metrics on
+	webhookMetricsPort     = 8080
+	webhookMetricsPortName = "metrics"
+
+	// The names of the mutating webhooks in the MutatingWebhookConfiguration
+	imageDigestPinningWebhookName = "pin.k8s.mondoo.com"
+	scanResultWebhookName         = "scan-result.k8s.mondoo.com"
+
+	// connectAuditingWebhookName is the name of the validating webhook for exec, attach and port-forward requests
+	connectAuditingWebhookName = "connect.k8s.mondoo.com"
+	// enforcingNamespacesWebhookName is the name of the copy of the policy webhook which fails closed for
+	// Namespaces labeled to enforce while the global mode does not
+	enforcingNamespacesWebhookName = "policy-enforcing.k8s.mondoo.com"
+	// admissionModeLabelKey is the Namespace label which overrides the admission mode
+	admissionModeLabelKey = "k8s.mondoo.com/admission-mode"
+
 	// openShiftServiceAnnotationKey is how we annotate a Service so that OpenShift
 	// will create TLS certificates for the webhook Service.
 	openShiftServiceAnnotationKey = "service.beta.openshift.io/serving-cert-secret-name"
@@ -35,6 +56,18 @@ const (
 	// manualTLSAnnotationKey is for when there is no explicit 'injection-style' defined in
 	// the MondooAuditConfig. We treat this to mean that the user will provide their own certs.
 	manualTLSAnnotationKey = "mondoo.com/tls-mode"
+
+	// namespaceNameLabelKey is the label the API server sets on every Namespace with the name of the Namespace.
+	namespaceNameLabelKey = "kubernetes.io/metadata.name"
+
+	// webhookOptOutLabelKey is the label which excludes an object from admission when set to "false".
+	webhookOptOutLabelKey = constants.MondooScanOptOutKey
+
+	// scanTimeoutMargin is the time the webhook reserves to answer the API server after the scan timed out.
+	scanTimeoutMargin = 2 * time.Second
+
+	// globChars are the characters with a special meaning in namespace filtering globs.
+	globChars = "*?[]{}!\\"
 )
 
 // webhookAnnotationList is a list of all the possible annotations we could set on a Webhook.
@@ -69,12 +102,124 @@ func WebhookDeployment(ns, image string, m mondoov1alpha2.MondooAuditConfig, int
 		strings.Join(m.Spec.Filtering.Namespaces.Include, ","),
 		"--namespaces-exclude",
 		strings.Join(m.Spec.Filtering.Namespaces.Exclude, ","),
+		"--mondoo-audit-config-name",
+		m.Name,
+		"--mondoo-audit-config-namespace",
+		m.Namespace,
+	}
+
+	if selector := m.Spec.Filtering.Namespaces.Selector; selector != nil && (len(selector.MatchLabels) > 0 || len(selector.MatchExpressions) > 0) {
+		containerArgs = append(containerArgs, []string{"--namespace-selector", metav1.FormatLabelSelector(selector)}...)
 	}
 
 	if integrationMRN != "" {
 		containerArgs = append(containerArgs, []string{"--integration-mrn", integrationMRN}...)
 	}
 
+	if m.Spec.Admission.ScoreThreshold != nil {
+		containerArgs = append(containerArgs, []string{"--score-threshold", fmt.Sprintf("%d", *m.Spec.Admission.ScoreThreshold)}...)
+	}
+
+	if m.Spec.Admission.MaxSeverity != "" {
+		containerArgs = append(containerArgs, []string{"--max-severity", string(m.Spec.Admission.MaxSeverity)}...)
+	}
+
+	// Without an explicit policy the webhook fails closed for every enforced request, including
+	// Namespaces which override the mode to enforcing
+	if m.Spec.Admission.FailurePolicy != "" {
+		containerArgs = append(containerArgs, []string{"--failure-policy", string(m.Spec.Admission.FailurePolicy)}...)
+	}
+	if m.Spec.Admission.TimeoutSeconds > 0 {
+		containerArgs = append(containerArgs, []string{"--scan-timeout", scanTimeout(m.Spec.Admission.TimeoutSeconds).String()}...)
+	}
+
+	if m.Spec.Admission.Cache.Enable {
+		containerArgs = append(containerArgs, []string{
+			"--cache-size", fmt.Sprintf("%d", m.Spec.Admission.Cache.MaxEntries),
+			"--cache-ttl", fmt.Sprintf("%ds", m.Spec.Admission.Cache.TTLSeconds),
+		}...)
+	}
+
+	if rollout := m.Spec.Admission.Rollout; rollout != nil && m.Spec.Admission.Mode == mondoov1alpha2.Enforcing {
+		if rollout.Percentage != nil {
+			containerArgs = append(containerArgs, []string{"--rollout-percentage", fmt.Sprintf("%d", *rollout.Percentage)}...)
+		} else if len(rollout.CanaryNamespaces) == 0 {
+			// an empty rollout enforces nothing
+			containerArgs = append(containerArgs, []string{"--rollout-percentage", "0"}...)
+		}
+		if len(rollout.CanaryNamespaces) > 0 {
+			containerArgs = append(containerArgs, []string{"--canary-namespaces", strings.Join(rollout.CanaryNamespaces, ",")}...)
+		}
+		if rollout.ShadowMode != "" {
+			containerArgs = append(containerArgs, []string{"--rollout-shadow-mode", string(rollout.ShadowMode)}...)
+		}
+	}
+
+	if m.Spec.Admission.Mutation.AnnotateScanResults {
+		containerArgs = append(containerArgs, "--annotate-scan-results")
+	}
+
+	if pinning := m.Spec.Admission.Mutation.ImageDigestPinning; pinning.Enable {
+		containerArgs = append(containerArgs, []string{"--pin-image-digests", "--image-digest-configmap", imageDigestConfigMapName(m.Name)}...)
+		if len(pinning.Registries) > 0 {
+			containerArgs = append(containerArgs, []string{"--pin-registries", strings.Join(pinning.Registries, ",")}...)
+		}
+	}
+
+	imagePolicy := m.Spec.Admission.ImagePolicy
+	if len(imagePolicy.AllowedRegistries) > 0 {
+		containerArgs = append(containerArgs, []string{"--allowed-registries", strings.Join(imagePolicy.AllowedRegistries, ",")}...)
+	}
+	if len(imagePolicy.DisallowedTags) > 0 {
+		containerArgs = append(containerArgs, []string{"--disallowed-image-tags", strings.Join(imagePolicy.DisallowedTags, ",")}...)
+	}
+	if imagePolicy.RequireDigest {
+		containerArgs = append(containerArgs, "--require-image-digest")
+	}
+
+	async := m.Spec.Admission.Async
+	if async.AnnotateScanResults {
+		containerArgs = append(containerArgs, "--async-annotate-scan-results")
+	}
+	if async.QueueSize > 0 {
+		containerArgs = append(containerArgs, []string{"--async-queue-size", fmt.Sprintf("%d", async.QueueSize)}...)
+	}
+	if async.Workers > 0 {
+		containerArgs = append(containerArgs, []string{"--async-workers", fmt.Sprintf("%d", async.Workers)}...)
+	}
+
+	if connect := m.Spec.Admission.ConnectAuditing; connect.Enable {
+		containerArgs = append(containerArgs, "--audit-connect")
+		if connect.DenyExecInProduction {
+			containerArgs = append(containerArgs, "--deny-exec-in-production")
+		}
+		if connect.ProductionNamespaceSelector != nil {
+			containerArgs = append(containerArgs, []string{"--production-namespace-selector", metav1.FormatLabelSelector(connect.ProductionNamespaceSelector)}...)
+		}
+	}
+
+	protection := m.Spec.Admission.ScanApiProtection
+	if protection.MaxConcurrentScans > 0 {
+		containerArgs = append(containerArgs, []string{"--max-concurrent-scans", fmt.Sprintf("%d", protection.MaxConcurrentScans)}...)
+	}
+	if protection.FailureThreshold > 0 {
+		containerArgs = append(containerArgs, []string{"--circuit-breaker-threshold", fmt.Sprintf("%d", protection.FailureThreshold)}...)
+		if protection.OpenSeconds > 0 {
+			containerArgs = append(containerArgs, []string{"--circuit-breaker-open-duration", fmt.Sprintf("%ds", protection.OpenSeconds)}...)
+		}
+	}
+
+	exemptions := m.Spec.Admission.Exemptions
+	if len(exemptions.Users) > 0 {
+		containerArgs = append(containerArgs, []string{"--exempt-users", strings.Join(exemptions.Users, ",")}...)
+	}
+	if len(exemptions.Groups) > 0 {
+		containerArgs = append(containerArgs, []string{"--exempt-groups", strings.Join(exemptions.Groups, ",")}...)
+	}
+	if len(exemptions.ServiceAccounts) > 0 {
+		containerArgs = append(containerArgs, []string{"--exempt-service-accounts", strings.Join(exemptions.ServiceAccounts, ",")}...)
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      webhookDeploymentName(m.Name),
@@ -99,6 +244,13 @@ func WebhookDeployment(ns, image string, m mondoov1alpha2.MondooAuditConfig, int
 							Args:            containerArgs,
 							Image:           image,
 							ImagePullPolicy: corev1.PullIfNotPresent,
+							Ports: []corev1.ContainerPort{
+								{
+									Name:          webhookMetricsPortName,
+									ContainerPort: webhookMetricsPort,
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
 							LivenessProbe: &corev1.Probe{
 								ProbeHandler: corev1.ProbeHandler{
 									HTTPGet: &corev1.HTTPGetAction{
@@ -114,7 +266,8 @@ func WebhookDeployment(ns, image string, m mondoov1alpha2.MondooAuditConfig, int
 							ReadinessProbe: &corev1.Probe{
 								ProbeHandler: corev1.ProbeHandler{
 									HTTPGet: &corev1.HTTPGetAction{
-										Path: "/readyz",
+										// an open circuit breaker must not remove the webhook from its Service
+										Path: "/readyz?exclude=" + constants.WebhookCircuitBreakerCheck,
 										Port: intstr.FromInt(8081),
 									},
 								},
@@ -219,14 +372,22 @@ func WebhookService(ns string, m mondoov1alpha2.MondooAuditConfig) *corev1.Servi
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      webhookServiceName(m.Name),
 			Namespace: ns,
+			Labels:    WebhookDeploymentLabels(),
 		},
 		Spec: corev1.ServiceSpec{
 			Ports: []corev1.ServicePort{
 				{
+					Name:       "webhook",
 					Port:       int32(443),
 					Protocol:   corev1.ProtocolTCP,
 					TargetPort: intstr.FromInt(webhook.DefaultPort),
 				},
+				{
+					Name:       webhookMetricsPortName,
+					Port:       int32(webhookMetricsPort),
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromString(webhookMetricsPortName),
+				},
 			},
 			Type:     corev1.ServiceTypeClusterIP,
 			Selector: WebhookDeploymentLabels(),
@@ -234,6 +395,21 @@ func WebhookService(ns string, m mondoov1alpha2.MondooAuditConfig) *corev1.Servi
 	}
 }
 
+// WebhookImageDigestConfigMap returns the ConfigMap in which the webhook replicas share the resolved image digests
+func WebhookImageDigestConfigMap(ns string, m mondoov1alpha2.MondooAuditConfig) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      imageDigestConfigMapName(m.Name),
+			Namespace: ns,
+			Labels:    WebhookDeploymentLabels(),
+		},
+	}
+}
+
+func imageDigestConfigMapName(prefix string) string {
+	return prefix + "-webhook-image-digests"
+}
+
 func webhookServiceName(prefix string) string {
 	return prefix + "-webhook-service"
 }
@@ -248,3 +424,239 @@ func validatingWebhookName(mondooAuditConfig *mondoov1alpha2.MondooAuditConfig)
 	}
 	return fmt.Sprintf("%s-%s-mondoo", mondooAuditConfig.Namespace, mondooAuditConfig.Name), nil
 }
+
+func mutatingWebhookName(mondooAuditConfig *mondoov1alpha2.MondooAuditConfig) (string, error) {
+	if mondooAuditConfig == nil {
+		return "", fmt.Errorf("cannot generate webhook name from nil MondooAuditConfig")
+	}
+	return fmt.Sprintf("%s-%s-mondoo-mutating", mondooAuditConfig.Namespace, mondooAuditConfig.Name), nil
+}
+
+// webhookNamespaceSelector translates the namespace filtering into a namespaceSelector for the webhook,
+// so the API server doesn't call the webhook for excluded Namespaces. The label selector of the filtering
+// is combined with the requirement for the Namespace names.
+func webhookNamespaceSelector(namespaces mondoov1alpha2.FilteringSpec) *metav1.LabelSelector {
+	selector := &metav1.LabelSelector{}
+	if namespaces.Selector != nil {
+		selector = namespaces.Selector.DeepCopy()
+	}
+	selector.MatchExpressions = append(selector.MatchExpressions, namespaceNameRequirements(namespaces)...)
+	return selector
+}
+
+// namespaceNameRequirements translates the include and exclude lists into label selector requirements.
+// Globs cannot be expressed with a label selector, so they are left to the webhook itself. An include list
+// with globs results in no requirement, while glob entries in the exclude list are skipped.
+func namespaceNameRequirements(namespaces mondoov1alpha2.FilteringSpec) []metav1.LabelSelectorRequirement {
+	// The include list takes precedence over the exclude list
+	if len(namespaces.Include) > 0 {
+		for _, ns := range namespaces.Include {
+			if strings.ContainsAny(ns, globChars) {
+				return nil
+			}
+		}
+		return []metav1.LabelSelectorRequirement{
+			{
+				Key:      namespaceNameLabelKey,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   namespaces.Include,
+			},
+		}
+	}
+
+	var exclude []string
+	for _, ns := range namespaces.Exclude {
+		if !strings.ContainsAny(ns, globChars) {
+			exclude = append(exclude, ns)
+		}
+	}
+	if len(exclude) == 0 {
+		return nil
+	}
+	return []metav1.LabelSelectorRequirement{
+		{
+			Key:      namespaceNameLabelKey,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   exclude,
+		},
+	}
+}
+
+// webhookObjectSelector returns the objectSelector for the webhook, which allows opting out single objects
+// by labeling them with "k8s.mondoo.com/scan: false".
+func webhookObjectSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      webhookOptOutLabelKey,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   []string{constants.MondooScanOptOutValue},
+			},
+		},
+	}
+}
+
+// withEnforcingNamespacesWebhook splits the policy webhook if no failure policy is configured and the global mode
+// does not enforce. Namespaces labeled to enforce are sent to a copy of the webhook which fails closed, while
+// all other Namespaces keep the failure policy of the global mode.
+func withEnforcingNamespacesWebhook(webhooks []webhooksv1.ValidatingWebhook, admission mondoov1alpha2.Admission) []webhooksv1.ValidatingWebhook {
+	if admission.FailurePolicy != "" || admission.Mode == mondoov1alpha2.Enforcing {
+		return webhooks
+	}
+
+	result := []webhooksv1.ValidatingWebhook{}
+	for _, w := range webhooks {
+		if w.Name == connectAuditingWebhookName {
+			result = append(result, w)
+			continue
+		}
+
+		enforcing := *w.DeepCopy()
+		enforcing.Name = enforcingNamespacesWebhookName
+		enforcing.FailurePolicy = ptr.To(webhooksv1.Fail)
+		enforcing.NamespaceSelector.MatchExpressions = append(enforcing.NamespaceSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      admissionModeLabelKey,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   []string{string(mondoov1alpha2.Enforcing)},
+		})
+
+		w.NamespaceSelector.MatchExpressions = append(w.NamespaceSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      admissionModeLabelKey,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{string(mondoov1alpha2.Enforcing)},
+		})
+		result = append(result, w, enforcing)
+	}
+	return result
+}
+
+// defaultAdmissionResources are the resources checked at admission if none are configured. They match
+// the rules of the webhook manifests.
+var defaultAdmissionResources = []mondoov1alpha2.AdmissionResource{
+	{Group: "", Version: "v1", Resource: "pods"},
+	{Group: "apps", Version: "v1", Resource: "deployments"},
+	{Group: "apps", Version: "v1", Resource: "daemonsets"},
+	{Group: "apps", Version: "v1", Resource: "statefulsets"},
+	{Group: "batch", Version: "v1", Resource: "jobs"},
+	{Group: "batch", Version: "v1", Resource: "cronjobs"},
+}
+
+// admissionResources returns the resources checked at admission with the kinds filtering applied. Resources
+// which are not scanned by the operator, e.g. custom resources, are not filtered. Returns nil if neither
+// resources nor kinds are configured, so the rules of the webhook manifests are kept.
+func admissionResources(spec mondoov1alpha2.MondooAuditConfigSpec) []mondoov1alpha2.AdmissionResource {
+	resources := spec.Admission.Resources
+	if !k8s.IsKindFilteringEnabled(spec.Filtering.Kinds) {
+		if len(resources) == 0 {
+			return nil
+		}
+		return resources
+	}
+
+	if len(resources) == 0 {
+		resources = defaultAdmissionResources
+	}
+	filtered := []mondoov1alpha2.AdmissionResource{}
+	for _, r := range resources {
+		if gvk, ok := scannedKindForResource(r); ok && !k8s.AllowKind(gvk, spec.Filtering.Kinds) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// scannedKindForResource looks up the kind of an admission resource among the kinds scanned by the operator
+func scannedKindForResource(r mondoov1alpha2.AdmissionResource) (schema.GroupVersionKind, bool) {
+	for _, k := range k8s.ScannedKinds {
+		if k.Group == r.Group && k.Resource == r.Resource {
+			return schema.GroupVersionKind{Group: r.Group, Version: r.Version, Kind: k.Kind}, true
+		}
+	}
+	return schema.GroupVersionKind{}, false
+}
+
+// webhookRules renders the configured admission resources into webhook rules with one rule per
+// API group and version. Returns nil if no resources are configured.
+func webhookRules(resources []mondoov1alpha2.AdmissionResource) []webhooksv1.RuleWithOperations {
+	var rules []webhooksv1.RuleWithOperations
+	ruleIndex := map[string]int{}
+	for _, r := range resources {
+		key := r.Group + "/" + r.Version
+		i, ok := ruleIndex[key]
+		if !ok {
+			rules = append(rules, webhooksv1.RuleWithOperations{
+				Operations: []webhooksv1.OperationType{webhooksv1.Create, webhooksv1.Update},
+				Rule: webhooksv1.Rule{
+					APIGroups:   []string{r.Group},
+					APIVersions: []string{r.Version},
+				},
+			})
+			i = len(rules) - 1
+			ruleIndex[key] = i
+		}
+		rules[i].Resources = append(rules[i].Resources, r.Resource)
+	}
+	return rules
+}
+
+// pinOnUpdateResources are the workload controllers whose images are also pinned on updates
+var pinOnUpdateResources = map[string]bool{
+	"deployments":  true,
+	"daemonsets":   true,
+	"statefulsets": true,
+	"replicasets":  true,
+	"cronjobs":     true,
+}
+
+// imageDigestPinningRules splits the rules of the image digest pinning webhook. Workload controllers are
+// pinned on creation and updates, while bare Pods, the immutable Pod template of Jobs and all other
+// resources are only pinned on creation.
+func imageDigestPinningRules(rules []webhooksv1.RuleWithOperations) []webhooksv1.RuleWithOperations {
+	var result []webhooksv1.RuleWithOperations
+	for _, r := range rules {
+		var createOnly, update []string
+		for _, resource := range r.Resources {
+			if pinOnUpdateResources[resource] {
+				update = append(update, resource)
+			} else {
+				createOnly = append(createOnly, resource)
+			}
+		}
+
+		if len(createOnly) > 0 {
+			rule := *r.DeepCopy()
+			rule.Operations = []webhooksv1.OperationType{webhooksv1.Create}
+			rule.Resources = createOnly
+			result = append(result, rule)
+		}
+		if len(update) > 0 {
+			rule := *r.DeepCopy()
+			rule.Operations = []webhooksv1.OperationType{webhooksv1.Create, webhooksv1.Update}
+			rule.Resources = update
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+// effectiveFailurePolicy returns the configured failure policy. If none is configured, the webhook fails
+// closed in enforcing mode and fails open otherwise.
+func effectiveFailurePolicy(admission mondoov1alpha2.Admission) mondoov1alpha2.AdmissionFailurePolicy {
+	if admission.FailurePolicy != "" {
+		return admission.FailurePolicy
+	}
+	if admission.Mode == mondoov1alpha2.Enforcing {
+		return mondoov1alpha2.FailClosed
+	}
+	return mondoov1alpha2.FailOpen
+}
+
+// scanTimeout returns the time budget for a scan, which is a bit shorter than the webhook timeout.
+func scanTimeout(timeoutSeconds int32) time.Duration {
+	timeout := time.Duration(timeoutSeconds)*time.Second - scanTimeoutMargin
+	if timeout < time.Second {
+		return time.Second
+	}
+	return timeout
+}
@@ -0,0 +1,153 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package admission
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils/certificates"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var selfSignedLog = ctrl.Log.WithName("self-signed")
+
+// caCertKey is the key of the CA bundle in the TLS Secret
+const caCertKey = "ca.crt"
+
+// SelfSignedHandler generates a CA and a serving certificate for the webhook into the TLS Secret and
+// rotates them before they expire. The CA private key is not stored, so every rotation creates a new CA.
+type SelfSignedHandler struct {
+	Mondoo          *v1alpha2.MondooAuditConfig
+	KubeClient      client.Client
+	TargetNamespace string
+	Scheme          *runtime.Scheme
+	// RotationWindow is the remaining validity below which the certificates are rotated. It has to be longer
+	// than the periodic reconcile interval of the MondooAuditConfig, so they are rotated before they expire.
+	// Defaults to 30 days.
+	RotationWindow time.Duration
+	// now returns the current time. Defaults to time.Now.
+	now func() time.Time
+}
+
+// Setup makes sure the TLS Secret holds a valid serving certificate. It returns the CA bundle to inject
+// into the webhook configurations and the expiry of the serving certificate. If the certificates cannot
+// be rotated, the expiry of the existing serving certificate is returned with the error.
+func (s *SelfSignedHandler) Setup(ctx context.Context) ([]byte, time.Time, error) {
+	now := s.clock()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetTLSCertificatesSecretName(s.Mondoo.Name),
+			Namespace: s.TargetNamespace,
+		},
+	}
+
+	exists, err := k8s.CheckIfExists(ctx, s.KubeClient, secret, secret)
+	if err != nil {
+		selfSignedLog.Error(err, "Failed to check for existing webhook TLS Secret")
+		return nil, time.Time{}, err
+	}
+
+	var notAfter time.Time
+	var previousCAs []byte
+	if exists {
+		certs, err := certificates.Parse(secret.Data[corev1.TLSCertKey])
+		if err == nil {
+			notAfter = certs[0].NotAfter
+			if len(secret.Data[caCertKey]) > 0 && slices.Equal(certs[0].DNSNames, s.dnsNames()) &&
+				notAfter.Sub(now) > s.rotationWindow() {
+				return secret.Data[caCertKey], notAfter, nil
+			}
+		}
+		// the previous CA stays trusted until the webhook serves the new certificate
+		previousCAs = certificates.Valid(secret.Data[caCertKey], now)
+		selfSignedLog.Info("Rotating self-signed webhook certificates", "notAfter", notAfter)
+	}
+
+	caBundle, certPEM, keyPEM, newNotAfter, err := certificates.GenerateSelfSigned(s.dnsNames(), now)
+	if err != nil {
+		selfSignedLog.Error(err, "Failed to generate self-signed webhook certificates")
+		return nil, notAfter, err
+	}
+	if len(previousCAs) > 0 {
+		caBundle = append(caBundle, previousCAs...)
+	}
+
+	if err := ctrl.SetControllerReference(s.Mondoo, secret, s.Scheme); err != nil {
+		selfSignedLog.Error(err, "failed to set owner reference")
+		return nil, notAfter, err
+	}
+	metav1.SetMetaDataAnnotation(&secret.ObjectMeta, manualTLSAnnotationKey, string(v1alpha2.SelfSignedProvisioning))
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+		caCertKey:               caBundle,
+	}
+
+	if exists {
+		err = s.KubeClient.Update(ctx, secret)
+	} else {
+		err = s.KubeClient.Create(ctx, secret)
+	}
+	if err != nil {
+		selfSignedLog.Error(err, "Failed to store self-signed webhook certificates")
+		return nil, notAfter, err
+	}
+	return caBundle, newNotAfter, nil
+}
+
+// Cleanup removes the TLS Secret if it holds self-signed certificates generated by the operator
+func (s *SelfSignedHandler) Cleanup(ctx context.Context) error {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Name: GetTLSCertificatesSecretName(s.Mondoo.Name), Namespace: s.TargetNamespace}
+	if err := s.KubeClient.Get(ctx, key, secret); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		selfSignedLog.Error(err, "Failed to check for existing webhook TLS Secret")
+		return err
+	}
+
+	if secret.Annotations[manualTLSAnnotationKey] != string(v1alpha2.SelfSignedProvisioning) {
+		return nil
+	}
+	if err := k8s.DeleteIfExists(ctx, s.KubeClient, secret); err != nil {
+		selfSignedLog.Error(err, "Failed to clean up self-signed webhook TLS Secret")
+		return err
+	}
+	return nil
+}
+
+func (s *SelfSignedHandler) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+func (s *SelfSignedHandler) rotationWindow() time.Duration {
+	window := s.RotationWindow
+	if window == 0 {
+		window = defaultCertificateExpiryWindow
+	}
+	// the certificates would be rotated on every reconcile if the window exceeded their validity
+	return min(window, certificates.SelfSignedValidity/2)
+}
+
+func (s *SelfSignedHandler) dnsNames() []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", webhookServiceName(s.Mondoo.Name), s.TargetNamespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", webhookServiceName(s.Mondoo.Name), s.TargetNamespace),
+	}
+}
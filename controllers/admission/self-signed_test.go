@@ -0,0 +1,174 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package admission
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils/certificates"
+)
+
+func testSelfSignedHandler(kubeClient client.Client, now time.Time) *SelfSignedHandler {
+	return &SelfSignedHandler{
+		Mondoo: &mondoov1alpha2.MondooAuditConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: testMondooAuditConfigName, Namespace: testNamespace},
+		},
+		KubeClient:      kubeClient,
+		TargetNamespace: testNamespace,
+		Scheme:          scheme.Scheme,
+		now:             func() time.Time { return now },
+	}
+}
+
+func getTLSSecret(t *testing.T, kubeClient client.Client) *corev1.Secret {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Name: GetTLSCertificatesSecretName(testMondooAuditConfigName), Namespace: testNamespace}
+	require.NoError(t, kubeClient.Get(context.TODO(), key, secret), "expected webhook TLS Secret to exist")
+	return secret
+}
+
+// verifyServingCert checks that the serving certificate in the Secret is trusted by the CA bundle
+func verifyServingCert(t *testing.T, secret *corev1.Secret, caBundle []byte, now time.Time) {
+	_, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	require.NoError(t, err, "expected matching serving certificate and key")
+
+	certs, err := certificates.Parse(secret.Data[corev1.TLSCertKey])
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caBundle))
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		DNSName:     "mondoo-client-webhook-service.mondoo-operator.svc",
+		Roots:       roots,
+		CurrentTime: now,
+	})
+	assert.NoError(t, err, "expected serving certificate to be signed by the CA bundle")
+}
+
+func countCertificates(data []byte) int {
+	count := 0
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		count++
+	}
+	return count
+}
+
+func TestSelfSignedHandler_Generate(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	now := time.Now()
+
+	caBundle, notAfter, err := testSelfSignedHandler(kubeClient, now).Setup(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(certificates.SelfSignedValidity).Unix(), notAfter.Unix())
+
+	secret := getTLSSecret(t, kubeClient)
+	assert.Equal(t, corev1.SecretTypeTLS, secret.Type)
+	assert.Equal(t, "self-signed", secret.Annotations[manualTLSAnnotationKey])
+	assert.Equal(t, caBundle, secret.Data[caCertKey])
+	assert.Equal(t, 1, countCertificates(caBundle))
+	verifyServingCert(t, secret, caBundle, now)
+}
+
+func TestSelfSignedHandler_Reuse(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	now := time.Now()
+
+	caBundle, notAfter, err := testSelfSignedHandler(kubeClient, now).Setup(context.TODO())
+	require.NoError(t, err)
+	secret := getTLSSecret(t, kubeClient)
+
+	// still outside of the rotation window
+	later := notAfter.Add(-defaultCertificateExpiryWindow - time.Hour)
+	reusedBundle, reusedNotAfter, err := testSelfSignedHandler(kubeClient, later).Setup(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, caBundle, reusedBundle)
+	assert.Equal(t, notAfter.Unix(), reusedNotAfter.Unix())
+	assert.Equal(t, secret.Data, getTLSSecret(t, kubeClient).Data)
+}
+
+func TestSelfSignedHandler_Rotate(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	now := time.Now()
+
+	oldBundle, notAfter, err := testSelfSignedHandler(kubeClient, now).Setup(context.TODO())
+	require.NoError(t, err)
+	oldSecret := getTLSSecret(t, kubeClient)
+
+	later := notAfter.Add(-defaultCertificateExpiryWindow + time.Hour)
+	caBundle, newNotAfter, err := testSelfSignedHandler(kubeClient, later).Setup(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, later.Add(certificates.SelfSignedValidity).Unix(), newNotAfter.Unix())
+
+	// the new CA comes first and the old one stays trusted until the webhook serves the new certificate
+	assert.Equal(t, 2, countCertificates(caBundle))
+	assert.Contains(t, string(caBundle), string(oldBundle))
+	verifyServingCert(t, oldSecret, caBundle, later)
+
+	secret := getTLSSecret(t, kubeClient)
+	assert.NotEqual(t, oldSecret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSCertKey])
+	verifyServingCert(t, secret, caBundle, later)
+
+	// expired CAs are dropped from the bundle on the next rotation
+	expired := newNotAfter.Add(-defaultCertificateExpiryWindow + time.Hour)
+	caBundle, _, err = testSelfSignedHandler(kubeClient, expired).Setup(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, 2, countCertificates(caBundle))
+	assert.NotContains(t, string(caBundle), string(oldBundle))
+}
+
+func TestSelfSignedHandler_RotationWindow(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	now := time.Now()
+
+	caBundle, notAfter, err := testSelfSignedHandler(kubeClient, now).Setup(context.TODO())
+	require.NoError(t, err)
+
+	// a longer window rotates the certificates earlier
+	handler := testSelfSignedHandler(kubeClient, notAfter.Add(-60*24*time.Hour))
+	handler.RotationWindow = 90 * 24 * time.Hour
+	rotatedBundle, _, err := handler.Setup(context.TODO())
+	require.NoError(t, err)
+	assert.NotEqual(t, caBundle, rotatedBundle)
+
+	// the window is capped, so the certificates are not rotated on every reconcile
+	handler = testSelfSignedHandler(kubeClient, time.Now())
+	handler.RotationWindow = 2 * certificates.SelfSignedValidity
+	reusedBundle, _, err := handler.Setup(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, rotatedBundle, reusedBundle)
+}
+
+func TestSelfSignedHandler_Cleanup(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+	handler := testSelfSignedHandler(kubeClient, time.Now())
+
+	_, _, err := handler.Setup(context.TODO())
+	require.NoError(t, err)
+	require.NoError(t, handler.Cleanup(context.TODO()))
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Name: GetTLSCertificatesSecretName(testMondooAuditConfigName), Namespace: testNamespace}
+	assert.True(t, errors.IsNotFound(kubeClient.Get(context.TODO(), key, secret)), "expected self-signed Secret to be removed")
+
+	// Secrets provided by the user are left behind
+	userSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	require.NoError(t, kubeClient.Create(context.TODO(), userSecret))
+	require.NoError(t, handler.Cleanup(context.TODO()))
+	assert.NoError(t, kubeClient.Get(context.TODO(), key, secret), "expected user-provided Secret to still exist")
+}
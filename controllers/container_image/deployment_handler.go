@@ -14,6 +14,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -71,7 +72,14 @@ func (n *DeploymentHandler) syncCronJob(ctx context.Context) error {
 		return err
 	}
 
-	updated, err := n.syncConfigMap(ctx, clusterUid)
+	// the scan Pods cannot access the Namespaces, so the label selector is resolved here
+	auditConfig, anyNamespace, err := k8s.ResolveNamespaceSelector(ctx, n.KubeClient, *n.Mondoo)
+	if err != nil {
+		logger.Error(err, "Failed to resolve the Namespace selector")
+		return err
+	}
+
+	updated, err := n.syncConfigMap(ctx, clusterUid, auditConfig)
 	if err != nil {
 		return err
 	}
@@ -110,6 +118,9 @@ func (n *DeploymentHandler) syncCronJob(ctx context.Context) error {
 
 	existing := &batchv1.CronJob{}
 	desired := CronJob(mondooClientImage, integrationMrn, clusterUid, privateRegistriesSecretName, n.Mondoo, *n.MondooOperatorConfig)
+	if !anyNamespace {
+		desired.Spec.Suspend = ptr.To(true)
+	}
 	if err := ctrl.SetControllerReference(n.Mondoo, desired, n.KubeClient.Scheme()); err != nil {
 		logger.Error(err, "Failed to set ControllerReference", "namespace", desired.Namespace, "name", desired.Name)
 		return err
@@ -127,6 +138,7 @@ func (n *DeploymentHandler) syncCronJob(ctx context.Context) error {
 		existing.Spec.JobTemplate = desired.Spec.JobTemplate
 		existing.Spec.Schedule = desired.Spec.Schedule
 		existing.Spec.ConcurrencyPolicy = desired.Spec.ConcurrencyPolicy
+		existing.Spec.Suspend = desired.Spec.Suspend
 		existing.SetOwnerReferences(desired.GetOwnerReferences())
 
 		// Remove any old jobs because they won't be updated when the cronjob changes
@@ -170,8 +182,8 @@ func (n *DeploymentHandler) syncCronJob(ctx context.Context) error {
 
 // syncConfigMap syncs the inventory ConfigMap. Returns a boolean indicating whether the ConfigMap has been updated. It
 // can only be "true", if the ConfigMap existed before this reconcile cycle and the inventory was different from the
-// desired state.
-func (n *DeploymentHandler) syncConfigMap(ctx context.Context, clusterUid string) (bool, error) {
+// desired state. The Namespace selector of the provided MondooAuditConfig has to be resolved already.
+func (n *DeploymentHandler) syncConfigMap(ctx context.Context, clusterUid string, auditConfig v1alpha2.MondooAuditConfig) (bool, error) {
 	existing := &corev1.ConfigMap{}
 
 	integrationMrn, err := k8s.TryGetIntegrationMrnForAuditConfig(ctx, n.KubeClient, *n.Mondoo)
@@ -180,7 +192,7 @@ func (n *DeploymentHandler) syncConfigMap(ctx context.Context, clusterUid string
 		return false, err
 	}
 
-	desired, err := ConfigMap(integrationMrn, clusterUid, *n.Mondoo, *n.MondooOperatorConfig)
+	desired, err := ConfigMap(integrationMrn, clusterUid, auditConfig, *n.MondooOperatorConfig)
 	if err != nil {
 		logger.Error(err, "failed to generate desired ConfigMap with inventory")
 		return false, err
@@ -16,6 +16,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -360,6 +361,44 @@ func (s *DeploymentHandlerSuite) TestReconcile_DisableContainerImageScanning() {
 	s.Equal(0, len(cronJobs.Items))
 }
 
+func (s *DeploymentHandlerSuite) TestReconcile_NamespaceSelector() {
+	s.auditConfig.Spec.Filtering.Namespaces.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "enabled"}}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"mondoo": "enabled"}},
+	}
+	s.fakeClientBuilder = s.fakeClientBuilder.WithObjects(namespace)
+	d := s.createDeploymentHandler()
+	s.NoError(d.KubeClient.Create(s.ctx, &s.auditConfig))
+
+	_, err := d.Reconcile(s.ctx)
+	s.NoError(err)
+
+	// the inventory contains the Namespaces matching the selector
+	resolved := s.auditConfig.DeepCopy()
+	resolved.Spec.Filtering.Namespaces = mondoov1alpha2.FilteringSpec{Include: []string{"team-a"}}
+	expected, err := ConfigMap("", test.KubeSystemNamespaceUid, *resolved, mondoov1alpha2.MondooOperatorConfig{})
+	s.NoError(err)
+
+	configMap := &corev1.ConfigMap{}
+	s.NoError(d.KubeClient.Get(s.ctx, client.ObjectKeyFromObject(expected), configMap))
+	s.Equal(expected.Data, configMap.Data)
+
+	cronJob := &batchv1.CronJob{}
+	cronJobKey := client.ObjectKey{Name: CronJobName(s.auditConfig.Name), Namespace: s.auditConfig.Namespace}
+	s.NoError(d.KubeClient.Get(s.ctx, cronJobKey, cronJob))
+	s.Nil(cronJob.Spec.Suspend)
+
+	// the CronJob is suspended while no Namespace matches
+	namespace.Labels = nil
+	s.NoError(d.KubeClient.Update(s.ctx, namespace))
+
+	_, err = d.Reconcile(s.ctx)
+	s.NoError(err)
+
+	s.NoError(d.KubeClient.Get(s.ctx, cronJobKey, cronJob))
+	s.Equal(ptr.To(true), cronJob.Spec.Suspend)
+}
+
 func (s *DeploymentHandlerSuite) createDeploymentHandler() DeploymentHandler {
 	return DeploymentHandler{
 		KubeClient:             s.fakeClientBuilder.Build(),
@@ -12,6 +12,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -74,8 +75,18 @@ func (n *DeploymentHandler) syncCronJob(ctx context.Context) error {
 		return err
 	}
 
+	// the scan Pods cannot access the Namespaces, so the label selector is resolved here
+	auditConfig, anyNamespace, err := k8s.ResolveNamespaceSelector(ctx, n.KubeClient, *n.Mondoo)
+	if err != nil {
+		logger.Error(err, "Failed to resolve the Namespace selector")
+		return err
+	}
+
 	existing := &batchv1.CronJob{}
-	desired := CronJob(mondooOperatorImage, integrationMrn, clusterUid, n.Mondoo)
+	desired := CronJob(mondooOperatorImage, integrationMrn, clusterUid, &auditConfig)
+	if !anyNamespace {
+		desired.Spec.Suspend = ptr.To(true)
+	}
 	if err := ctrl.SetControllerReference(n.Mondoo, desired, n.KubeClient.Scheme()); err != nil {
 		logger.Error(err, "Failed to set ControllerReference", "namespace", desired.Namespace, "name", desired.Name)
 		return err
@@ -93,6 +104,7 @@ func (n *DeploymentHandler) syncCronJob(ctx context.Context) error {
 		existing.Spec.JobTemplate = desired.Spec.JobTemplate
 		existing.Spec.Schedule = desired.Spec.Schedule
 		existing.Spec.ConcurrencyPolicy = desired.Spec.ConcurrencyPolicy
+		existing.Spec.Suspend = desired.Spec.Suspend
 		existing.SetOwnerReferences(desired.GetOwnerReferences())
 
 		// Remove any old jobs because they won't be updated when the cronjob changes
@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -372,6 +374,72 @@ func (s *DeploymentHandlerSuite) TestReconcile_CreateWithCustomSchedule() {
 	s.Equal(created.Spec.Schedule, customSchedule)
 }
 
+func (s *DeploymentHandlerSuite) TestReconcile_CreateWithNamespaceSelector() {
+	s.auditConfig.Spec.Filtering.Namespaces.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "enabled"}}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"mondoo": "enabled"}},
+	}
+	s.fakeClientBuilder = s.fakeClientBuilder.WithObjects(namespace)
+	d := s.createDeploymentHandler()
+	s.NoError(d.KubeClient.Create(s.ctx, &s.auditConfig))
+	s.scanApiStoreMock.EXPECT().Add(gomock.Any()).Times(2)
+
+	_, err := d.Reconcile(s.ctx)
+	s.NoError(err)
+
+	image, err := s.containerImageResolver.MondooOperatorImage(s.ctx, "", "", false)
+	s.NoError(err)
+
+	// the selector is resolved to the matching Namespaces
+	resolved := s.auditConfig.DeepCopy()
+	resolved.Spec.Filtering.Namespaces = mondoov1alpha2.FilteringSpec{Include: []string{"team-a"}}
+	expected := CronJob(image, "", test.KubeSystemNamespaceUid, resolved)
+
+	created := &batchv1.CronJob{}
+	s.NoError(d.KubeClient.Get(s.ctx, client.ObjectKeyFromObject(expected), created))
+	s.Equal(expected.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args, created.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args)
+	s.Nil(created.Spec.Suspend)
+
+	// the CronJob is suspended while no Namespace matches
+	namespace.Labels = nil
+	s.NoError(d.KubeClient.Update(s.ctx, namespace))
+
+	_, err = d.Reconcile(s.ctx)
+	s.NoError(err)
+
+	s.NoError(d.KubeClient.Get(s.ctx, client.ObjectKeyFromObject(expected), created))
+	s.Equal(ptr.To(true), created.Spec.Suspend)
+}
+
+func (s *DeploymentHandlerSuite) TestReconcile_CreateWithKinds() {
+	s.auditConfig.Spec.Filtering.Kinds.Include = []mondoov1alpha2.GroupVersionKind{
+		{Group: "apps", Kind: "Deployment"},
+		{Group: "batch", Kind: "CronJob"},
+	}
+	d := s.createDeploymentHandler()
+	s.NoError(d.KubeClient.Create(s.ctx, &s.auditConfig))
+
+	scanApiUrl := scanapi.ScanApiServiceUrl(*d.Mondoo)
+	s.scanApiStoreMock.EXPECT().Add(&scan_api_store.ScanApiStoreAddOpts{
+		Url:           scanApiUrl,
+		Token:         "token",
+		ResourceTypes: []string{"deployment", "cronjob"},
+	}).Times(1)
+
+	_, err := d.Reconcile(s.ctx)
+	s.NoError(err)
+
+	image, err := s.containerImageResolver.MondooOperatorImage(s.ctx, "", "", false)
+	s.NoError(err)
+	expected := CronJob(image, "", test.KubeSystemNamespaceUid, &s.auditConfig)
+
+	created := &batchv1.CronJob{}
+	s.NoError(d.KubeClient.Get(s.ctx, client.ObjectKeyFromObject(expected), created))
+	s.Contains(
+		strings.Join(created.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Args, " "),
+		"--discovery-targets clusters,deployments,cronjobs")
+}
+
 func (s *DeploymentHandlerSuite) createDeploymentHandler() DeploymentHandler {
 	return DeploymentHandler{
 		KubeClient:             s.fakeClientBuilder.Build(),
@@ -12,6 +12,7 @@ import (
 	"go.mondoo.com/mondoo-operator/api/v1alpha2"
 	"go.mondoo.com/mondoo-operator/controllers/scanapi"
 	"go.mondoo.com/mondoo-operator/pkg/feature_flags"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -38,6 +39,10 @@ func CronJob(image, integrationMrn, clusterUid string, m *v1alpha2.MondooAuditCo
 		"--namespaces-exclude", strings.Join(m.Spec.Filtering.Namespaces.Exclude, ","),
 	}
 
+	if targets := k8s.DiscoveryTargets(m.Spec.Filtering.Kinds); targets != nil {
+		containerArgs = append(containerArgs, []string{"--discovery-targets", strings.Join(targets, ",")}...)
+	}
+
 	if integrationMrn != "" {
 		containerArgs = append(containerArgs, []string{"--integration-mrn", integrationMrn}...)
 	}
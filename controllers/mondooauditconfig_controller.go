@@ -5,7 +5,6 @@ package controllers
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -69,13 +68,14 @@ var MondooClientBuilder = mondooclient.NewClient
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=pods;namespaces;nodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
-// Just neeed to be able to create a Secret to hold the generated ScanAPI token
-//+kubebuilder:rbac:groups=core,resources=secrets,verbs=create;delete
+// Just neeed to be able to create a Secret to hold the generated ScanAPI token and to rotate self-signed webhook certificates
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=create;delete;update
 // Need to be able to check for the existence of Secrets with tokens, Mondoo service accounts, and private image pull secrets without asking for permission to read all Secrets
 //+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
 //+kubebuilder:rbac:groups=cert-manager.io,resources=certificates;issuers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=mutatingwebhookconfigurations,verbs=get;list;watch;create;update;patch;delete
 //The last line is required as we cant assign higher permissions that exist for operator serviceaccount
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -167,30 +167,12 @@ func (r *MondooAuditConfigReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		}
 	}
 
-	// Set the default cron tab if none is set
-	shouldUpdate := false
-	if mondooAuditConfig.Spec.KubernetesResources.ContainerImageScanning && !mondooAuditConfig.Spec.Containers.Enable {
-		mondooAuditConfig.Spec.Containers.Enable = true
-		shouldUpdate = true
-	}
-	if mondooAuditConfig.Spec.Nodes.Enable && mondooAuditConfig.Spec.Nodes.Schedule == "" {
-		mondooAuditConfig.Spec.Nodes.Schedule = fmt.Sprintf("%d * * * *", time.Now().Add(1*time.Minute).Minute())
-		shouldUpdate = true
-	}
-	if mondooAuditConfig.Spec.KubernetesResources.Enable && mondooAuditConfig.Spec.KubernetesResources.Schedule == "" {
-		mondooAuditConfig.Spec.KubernetesResources.Schedule = fmt.Sprintf("%d * * * *", time.Now().Add(1*time.Minute).Minute())
-		shouldUpdate = true
-	}
-	if mondooAuditConfig.Spec.Containers.Enable && mondooAuditConfig.Spec.Containers.Schedule == "" {
-		cronStart := time.Now().Add(1 * time.Minute)
-		mondooAuditConfig.Spec.Containers.Schedule = fmt.Sprintf("%d %d * * *", cronStart.Minute(), cronStart.Hour())
-		shouldUpdate = true
-	}
-	if shouldUpdate {
-		err := r.Update(ctx, mondooAuditConfig)
-		if err != nil {
-			log.Error(reconcileError, "failed to update MondooAuditConfig with default schedule")
-			return ctrl.Result{}, reconcileError
+	// The defaults are set by the MondooAuditConfig webhook. MondooAuditConfigs which were admitted before
+	// the webhook was registered are defaulted here.
+	if mondooAuditConfig.SetDefaults(time.Now()) {
+		if err := r.Update(ctx, mondooAuditConfig); err != nil {
+			log.Error(err, "failed to update MondooAuditConfig with default schedule")
+			return ctrl.Result{}, err
 		}
 		return ctrl.Result{Requeue: true}, nil
 	}
@@ -344,6 +326,25 @@ func (r *MondooAuditConfigReconciler) nodeEventsRequestMapper(ctx context.Contex
 	return requests
 }
 
+// namespaceEventsRequestMapper enqueues the MondooAuditConfigs which filter Namespaces by labels or run scheduled
+// scans, so the scans are updated when Namespaces are created, deleted, labeled or opted out of scanning
+func (r *MondooAuditConfigReconciler) namespaceEventsRequestMapper(ctx context.Context, o client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	auditConfigs := &v1alpha2.MondooAuditConfigList{}
+	if err := r.Client.List(ctx, auditConfigs); err != nil {
+		logger := ctrllog.Log.WithName("namespace-watcher")
+		logger.Error(err, "Failed to list MondooAuditConfigs")
+		return requests
+	}
+
+	for _, a := range auditConfigs.Items {
+		if a.Spec.Filtering.Namespaces.Selector != nil || a.Spec.KubernetesResources.Enable || a.Spec.Containers.Enable {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&a)})
+		}
+	}
+	return requests
+}
+
 // cronJobPodsRequestMapper watches Pods created by our CronJobs
 // Otherwise we wouldn't be able to report OOM status on the spawned Pods
 func (r *MondooAuditConfigReconciler) cronJobPodsRequestMapper(ctx context.Context, o client.Object) []reconcile.Request {
@@ -504,6 +505,10 @@ func (r *MondooAuditConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			&corev1.Node{},
 			handler.EnqueueRequestsFromMapFunc(r.nodeEventsRequestMapper),
 			builder.WithPredicates(k8s.IgnoreGenericEventsPredicate{})).
+		Watches(
+			&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.namespaceEventsRequestMapper),
+			builder.WithPredicates(k8s.IgnoreGenericEventsPredicate{})).
 		Complete(r)
 }
 
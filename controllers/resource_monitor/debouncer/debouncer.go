@@ -12,6 +12,9 @@ import (
 	"go.mondoo.com/mondoo-operator/controllers/resource_monitor/scan_api_store"
 	"go.mondoo.com/mondoo-operator/pkg/feature_flags"
 	"go.mondoo.com/mondoo-operator/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -32,15 +35,17 @@ type debouncer struct {
 	resChan      chan string
 	resources    map[string]struct{}
 	scanApiStore scan_api_store.ScanApiStore
+	kubeClient   client.Reader
 }
 
-func NewDebouncer(scanApiStore scan_api_store.ScanApiStore) Debouncer {
+func NewDebouncer(scanApiStore scan_api_store.ScanApiStore, kubeClient client.Reader) Debouncer {
 	return &debouncer{
 		isFirstFlush: true,
 		flushTimeout: defaultFlushTimeout * time.Second,
 		resChan:      make(chan string),
 		resources:    make(map[string]struct{}),
 		scanApiStore: scanApiStore,
+		kubeClient:   kubeClient,
 	}
 }
 
@@ -62,6 +67,7 @@ func (d *debouncer) Start(ctx context.Context, managedBy string) {
 			}
 
 			clients := d.scanApiStore.GetAll()
+			namespaceLabels := make(map[string]labels.Set)
 
 			for res := range d.resources {
 				for _, c := range clients {
@@ -71,8 +77,11 @@ func (d *debouncer) Start(ctx context.Context, managedBy string) {
 						logger.Error(err, "skipping resource", "request", res)
 						continue
 					}
+					if !c.AllowResourceType(fields[0]) {
+						continue
+					}
 					namespace := fields[1]
-					allow, err := utils.AllowNamespace(namespace, c.IncludeNamespaces, c.ExcludeNamespaces)
+					allow, err := d.allowNamespace(ctx, c, namespace, namespaceLabels)
 					if err != nil {
 						logger.Error(err, "skipping resource", "request", res)
 						continue
@@ -90,6 +99,32 @@ func (d *debouncer) Start(ctx context.Context, managedBy string) {
 	}
 }
 
+// allowNamespace checks whether the Namespace passes the filtering of the scan API client. The labels of
+// the Namespaces are only fetched if needed and are cached in namespaceLabels.
+func (d *debouncer) allowNamespace(
+	ctx context.Context, c scan_api_store.ClientConfiguration, namespace string, namespaceLabels map[string]labels.Set,
+) (bool, error) {
+	allow, err := utils.AllowNamespace(namespace, c.IncludeNamespaces, c.ExcludeNamespaces)
+	if err != nil || !allow || c.NamespaceSelector == nil {
+		return allow, err
+	}
+
+	set, ok := namespaceLabels[namespace]
+	if !ok {
+		// cluster-scoped resources have no labels to match
+		set = labels.Set{}
+		if namespace != "" {
+			ns := &corev1.Namespace{}
+			if err := d.kubeClient.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+				return false, err
+			}
+			set = labels.Set(ns.Labels)
+		}
+		namespaceLabels[namespace] = set
+	}
+	return c.NamespaceSelector.Matches(set), nil
+}
+
 func (d *debouncer) Add(res string) {
 	// If the resource monitor is disabled ignore the update
 	if feature_flags.GetDisableResourceMonitor() {
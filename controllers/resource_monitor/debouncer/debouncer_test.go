@@ -13,6 +13,10 @@ import (
 	"go.mondoo.com/mondoo-operator/controllers/resource_monitor/scan_api_store"
 	scanapistoremock "go.mondoo.com/mondoo-operator/controllers/resource_monitor/scan_api_store/mock"
 	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 type DebouncerSuite struct {
@@ -30,7 +34,11 @@ func (s *DebouncerSuite) BeforeTest(suiteName, testName string) {
 	s.mockCtrl = gomock.NewController(s.T())
 	s.mockMondooClient = mock.NewMockScanApiClient(s.mockCtrl)
 	s.scanApiStore = scanapistoremock.NewMockScanApiStore(s.mockCtrl)
-	s.debouncer = NewDebouncer(s.scanApiStore).(*debouncer)
+	kubeClient := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"mondoo": "enabled"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-ns"}},
+	).Build()
+	s.debouncer = NewDebouncer(s.scanApiStore, kubeClient).(*debouncer)
 	s.debouncer.flushTimeout = 1 * time.Second
 }
 
@@ -168,6 +176,60 @@ func (s *DebouncerSuite) TestStart_MultipleScanApiClients() {
 	s.Empty(s.debouncer.resources)
 }
 
+func (s *DebouncerSuite) TestStart_NamespaceSelector() {
+	s.debouncer.isFirstFlush = false
+	go s.debouncer.Start(s.ctx, "")
+
+	keys := []string{"pod:default:test", "deployment:test-ns:dep"}
+	for _, k := range keys {
+		for i := 0; i < 100; i++ {
+			s.debouncer.Add(k)
+		}
+	}
+
+	integrationMrn := "integration-mrn"
+	s.scanApiStore.EXPECT().GetAll().Times(1).Return([]scan_api_store.ClientConfiguration{
+		{Client: s.mockMondooClient, IntegrationMrn: integrationMrn, NamespaceSelector: labels.SelectorFromSet(labels.Set{"mondoo": "enabled"})},
+	})
+
+	// Verify we only schedule a scan for the resource in the labeled Namespace.
+	s.mockMondooClient.EXPECT().
+		ScheduleKubernetesResourceScan(gomock.Any(), integrationMrn, "pod:default:test", "").
+		Times(1).
+		Return(nil, nil)
+
+	time.Sleep(s.debouncer.flushTimeout + 100*time.Millisecond)
+
+	s.Empty(s.debouncer.resources)
+}
+
+func (s *DebouncerSuite) TestStart_ResourceTypes() {
+	s.debouncer.isFirstFlush = false
+	go s.debouncer.Start(s.ctx, "")
+
+	keys := []string{"pod:default:test", "deployment:test-ns:dep"}
+	for _, k := range keys {
+		for i := 0; i < 100; i++ {
+			s.debouncer.Add(k)
+		}
+	}
+
+	integrationMrn := "integration-mrn"
+	s.scanApiStore.EXPECT().GetAll().Times(1).Return([]scan_api_store.ClientConfiguration{
+		{Client: s.mockMondooClient, IntegrationMrn: integrationMrn, ResourceTypes: []string{"deployment"}},
+	})
+
+	// Verify we only schedule a scan for the resource of the included kind.
+	s.mockMondooClient.EXPECT().
+		ScheduleKubernetesResourceScan(gomock.Any(), integrationMrn, "deployment:test-ns:dep", "").
+		Times(1).
+		Return(nil, nil)
+
+	time.Sleep(s.debouncer.flushTimeout + 100*time.Millisecond)
+
+	s.Empty(s.debouncer.resources)
+}
+
 func TestDebouncerSuite(t *testing.T) {
 	suite.Run(t, new(DebouncerSuite))
 }
@@ -0,0 +1,103 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package resource_monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+const defaultOptOutFlushInterval = 30 * time.Second
+
+// optOutReporter aggregates the changed objects which are opted out of scanning and periodically adds
+// them to the status of the MondooAuditConfigs with Kubernetes resources scanning enabled. Aggregating
+// the count keeps the resource monitors from updating the status for every change.
+type optOutReporter struct {
+	reader   client.Reader
+	writer   client.StatusClient
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending int64
+}
+
+// newOptOutReporter creates an optOutReporter. The reader should not be cached, because the status is
+// also updated by the MondooAuditConfig controller.
+func newOptOutReporter(reader client.Reader, writer client.StatusClient) *optOutReporter {
+	return &optOutReporter{
+		reader:   reader,
+		writer:   writer,
+		interval: defaultOptOutFlushInterval,
+	}
+}
+
+// record registers a changed object which is opted out of scanning
+func (r *optOutReporter) record() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending++
+}
+
+// Start flushes the pending count until the context is cancelled. It implements manager.Runnable.
+func (r *optOutReporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.flush(ctx); err != nil {
+				logger.Error(err, "failed to report objects opted out of scanning")
+			}
+		}
+	}
+}
+
+// flush adds the pending count to the status of the MondooAuditConfigs with Kubernetes resources scanning
+// enabled. If the MondooAuditConfigs cannot be listed, the count is kept for the next flush.
+func (r *optOutReporter) flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = 0
+	r.mu.Unlock()
+
+	if pending == 0 {
+		return nil
+	}
+
+	auditConfigs := &v1alpha2.MondooAuditConfigList{}
+	if err := r.reader.List(ctx, auditConfigs); err != nil {
+		r.mu.Lock()
+		r.pending += pending
+		r.mu.Unlock()
+		return err
+	}
+
+	for _, a := range auditConfigs.Items {
+		if !a.Spec.KubernetesResources.Enable {
+			continue
+		}
+		key := client.ObjectKeyFromObject(&a)
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			mac := &v1alpha2.MondooAuditConfig{}
+			if err := r.reader.Get(ctx, key, mac); err != nil {
+				return err
+			}
+			mac.Status.KubernetesResources.OptedOutCount += pending
+			return r.writer.Status().Update(ctx, mac)
+		})
+		if err != nil {
+			logger.Error(err, "failed to report objects opted out of scanning", "mondooauditconfig", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package resource_monitor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+func TestOptOutReporterFlush(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(v1alpha2.AddToScheme(scheme))
+
+	enabled := &v1alpha2.MondooAuditConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "enabled", Namespace: "mondoo-operator"},
+		Spec:       v1alpha2.MondooAuditConfigSpec{KubernetesResources: v1alpha2.KubernetesResources{Enable: true}},
+		Status: v1alpha2.MondooAuditConfigStatus{
+			KubernetesResources: v1alpha2.KubernetesResourcesStatus{OptedOutCount: 3},
+		},
+	}
+	disabled := &v1alpha2.MondooAuditConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "disabled", Namespace: "mondoo-operator"},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(enabled, disabled).WithStatusSubresource(enabled, disabled).Build()
+
+	reporter := newOptOutReporter(kubeClient, kubeClient)
+
+	// nothing to report
+	require.NoError(t, reporter.flush(context.TODO()))
+
+	reporter.record()
+	reporter.record()
+	require.NoError(t, reporter.flush(context.TODO()))
+	assert.Zero(t, reporter.pending)
+
+	updated := &v1alpha2.MondooAuditConfig{}
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(enabled), updated))
+	assert.Equal(t, int64(5), updated.Status.KubernetesResources.OptedOutCount)
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(disabled), updated))
+	assert.Zero(t, updated.Status.KubernetesResources.OptedOutCount)
+}
+
+func TestOptOutReporterFlushKeepsCountOnError(t *testing.T) {
+	// the scheme doesn't know MondooAuditConfigs, so listing them fails
+	kubeClient := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+
+	reporter := newOptOutReporter(kubeClient, kubeClient)
+	reporter.record()
+	assert.Error(t, reporter.flush(context.TODO()))
+	assert.Equal(t, int64(1), reporter.pending)
+}
@@ -5,29 +5,25 @@ package resource_monitor
 
 import (
 	"go.mondoo.com/mondoo-operator/controllers/resource_monitor/scan_api_store"
-	appsv1 "k8s.io/api/apps/v1"
-	batchv1 "k8s.io/api/batch/v1"
-	corev1 "k8s.io/api/core/v1"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
-var resourceTypes = []func() client.Object{
-	func() client.Object { return &corev1.Pod{} },
-	func() client.Object { return &appsv1.Deployment{} },
-	func() client.Object { return &appsv1.ReplicaSet{} },
-	func() client.Object { return &appsv1.StatefulSet{} },
-	func() client.Object { return &appsv1.DaemonSet{} },
-	func() client.Object { return &batchv1.Job{} },
-	func() client.Object { return &batchv1.CronJob{} },
-}
-
+// RegisterResourceMonitors registers a resource monitor for every scanned kind. The kinds filtering of
+// the MondooAuditConfigs can change while the operator is running, so the monitors are always registered
+// and only trigger scans for the MondooAuditConfigs which include their kind.
 func RegisterResourceMonitors(mgr manager.Manager, scanApiStore scan_api_store.ScanApiStore) error {
-	for _, r := range resourceTypes {
-		resMon, err := NewResourceMonitorController(mgr.GetClient(), r, scanApiStore)
+	optOut := newOptOutReporter(mgr.GetAPIReader(), mgr.GetClient())
+	if err := mgr.Add(optOut); err != nil {
+		return err
+	}
+
+	for _, k := range k8s.ScannedKinds {
+		resMon, err := NewResourceMonitorController(mgr.GetClient(), k.NewObject, scanApiStore)
 		if err != nil {
 			return err
 		}
+		resMon.optOutReporter = optOut
 		if err := resMon.SetupWithManager(mgr); err != nil {
 			return err
 		}
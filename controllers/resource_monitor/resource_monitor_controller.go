@@ -6,6 +6,7 @@ package resource_monitor
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 
 	"go.mondoo.com/mondoo-operator/controllers/resource_monitor/debouncer"
@@ -27,6 +28,8 @@ type ResourceMonitorController struct {
 	debouncer    debouncer.Debouncer
 	resourceType string
 	scanApiStore scan_api_store.ScanApiStore
+	// optOutReporter counts the objects opted out of scanning. It is nil if the count isn't reported.
+	optOutReporter *optOutReporter
 }
 
 func NewResourceMonitorController(
@@ -43,7 +46,7 @@ func NewResourceMonitorController(
 	return &ResourceMonitorController{
 		Client:       kubeClient,
 		createRes:    createRes,
-		debouncer:    debouncer.NewDebouncer(scanApiStore),
+		debouncer:    debouncer.NewDebouncer(scanApiStore, kubeClient),
 		resourceType: strings.ToLower(gvk.Kind),
 		scanApiStore: scanApiStore,
 	}, nil
@@ -98,9 +101,24 @@ func (r *ResourceMonitorController) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, nil
 	}
 
-	if len(r.scanApiStore.GetAll()) > 0 {
+	if k8s.IsScanOptedOut(obj) {
+		logger.V(5).Info("skipping object opted out of scanning", "type", r.resourceType, "namespace", req.Namespace, "name", req.Name)
+		if r.optOutReporter != nil {
+			r.optOutReporter.record()
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if r.isScanned() {
 		r.debouncer.Add(fmt.Sprintf("%s:%s:%s", r.resourceType, req.Namespace, req.Name))
 	}
 
 	return ctrl.Result{}, nil
 }
+
+// isScanned checks whether any scan API client scans the resource type of the controller
+func (r *ResourceMonitorController) isScanned() bool {
+	return slices.ContainsFunc(r.scanApiStore.GetAll(), func(c scan_api_store.ClientConfiguration) bool {
+		return c.AllowResourceType(r.resourceType)
+	})
+}
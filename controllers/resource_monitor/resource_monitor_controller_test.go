@@ -110,6 +110,77 @@ func (s *ResourceMonitorControllerSuite) TestReconcile_Pod_NoScanApi() {
 	s.NoError(err)
 }
 
+func (s *ResourceMonitorControllerSuite) TestReconcile_Pod_KindNotScanned() {
+	ctx := context.Background()
+	scanApiStore := scanapistoremock.NewMockScanApiStore(s.mockCtrl)
+
+	ns := utils.RandString(10)
+	name := utils.RandString(10)
+	createRes := func() client.Object {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+			},
+		}
+	}
+
+	r, err := NewResourceMonitorController(
+		s.fakeClientBuilder.WithObjects(createRes()).Build(),
+		createRes,
+		scanApiStore)
+	s.Require().NoError(err)
+	r.debouncer = s.debouncerMock
+
+	// the debouncer is not called if no scan API client scans Pods
+	scanApiStore.EXPECT().GetAll().Return([]scan_api_store.ClientConfiguration{{ResourceTypes: []string{"deployment"}}}).Times(1)
+
+	res, err := r.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: ns,
+			Name:      name,
+		},
+	})
+	s.True(res.IsZero())
+	s.NoError(err)
+}
+
+func (s *ResourceMonitorControllerSuite) TestReconcile_Pod_OptedOut() {
+	ctx := context.Background()
+	scanApiStore := scanapistoremock.NewMockScanApiStore(s.mockCtrl)
+
+	ns := utils.RandString(10)
+	name := utils.RandString(10)
+	createRes := func() client.Object {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   ns,
+				Annotations: map[string]string{"k8s.mondoo.com/scan": "false"},
+			},
+		}
+	}
+
+	r, err := NewResourceMonitorController(
+		s.fakeClientBuilder.WithObjects(createRes()).Build(),
+		createRes,
+		scanApiStore)
+	s.Require().NoError(err)
+	r.debouncer = s.debouncerMock
+	r.optOutReporter = newOptOutReporter(nil, nil)
+
+	// the debouncer is not called for opted out objects
+	res, err := r.Reconcile(ctx, controllerruntime.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: ns,
+			Name:      name,
+		},
+	})
+	s.True(res.IsZero())
+	s.NoError(err)
+	s.Equal(int64(1), r.optOutReporter.pending)
+}
+
 func (s *ResourceMonitorControllerSuite) TestReconcile_Child_Pod() {
 	ctx := context.Background()
 	scanApiStore := scanapistoremock.NewMockScanApiStore(s.mockCtrl)
@@ -5,8 +5,10 @@ package scan_api_store
 
 import (
 	"context"
+	"slices"
 
 	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -29,6 +31,15 @@ type ClientConfiguration struct {
 	IntegrationMrn    string
 	IncludeNamespaces []string
 	ExcludeNamespaces []string
+	// NamespaceSelector is nil if the Namespaces are not filtered by labels
+	NamespaceSelector labels.Selector
+	// ResourceTypes lists the lower case kinds which are scanned. It is nil if the kinds are not filtered.
+	ResourceTypes []string
+}
+
+// AllowResourceType checks whether resources of the type, i.e. the lower case kind, are scanned
+func (c ClientConfiguration) AllowResourceType(resourceType string) bool {
+	return c.ResourceTypes == nil || slices.Contains(c.ResourceTypes, resourceType)
 }
 
 type requestType string
@@ -45,6 +56,8 @@ type urlRequest struct {
 	integrationMrn    string
 	includeNamespaces []string
 	excludeNamespaces []string
+	namespaceSelector labels.Selector
+	resourceTypes     []string
 }
 
 type scanApiStore struct {
@@ -92,6 +105,8 @@ func (s *scanApiStore) Start() {
 					IntegrationMrn:    req.integrationMrn,
 					IncludeNamespaces: req.includeNamespaces,
 					ExcludeNamespaces: req.excludeNamespaces,
+					NamespaceSelector: req.namespaceSelector,
+					ResourceTypes:     req.resourceTypes,
 				}
 			case DeleteRequest:
 				delete(s.scanClients, req.url)
@@ -114,6 +129,8 @@ type ScanApiStoreAddOpts struct {
 	IntegrationMrn    string
 	IncludeNamespaces []string
 	ExcludeNamespaces []string
+	NamespaceSelector labels.Selector
+	ResourceTypes     []string
 }
 
 // Add adds a scan api url to the store. The operatorion is idempotent.
@@ -125,6 +142,8 @@ func (s *scanApiStore) Add(opts *ScanApiStoreAddOpts) {
 		integrationMrn:    opts.IntegrationMrn,
 		includeNamespaces: opts.IncludeNamespaces,
 		excludeNamespaces: opts.ExcludeNamespaces,
+		namespaceSelector: opts.NamespaceSelector,
+		resourceTypes:     opts.ResourceTypes,
 	}
 }
 
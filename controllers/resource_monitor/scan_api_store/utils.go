@@ -5,9 +5,11 @@ package scan_api_store
 
 import (
 	"context"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"go.mondoo.com/mondoo-operator/api/v1alpha2"
 	"go.mondoo.com/mondoo-operator/controllers/scanapi"
@@ -29,12 +31,30 @@ func HandleAuditConfig(ctx context.Context, kubeClient client.Client, scanApiSto
 			return err
 		}
 
+		var namespaceSelector labels.Selector
+		if auditConfig.Spec.Filtering.Namespaces.Selector != nil {
+			namespaceSelector, err = metav1.LabelSelectorAsSelector(auditConfig.Spec.Filtering.Namespaces.Selector)
+			if err != nil {
+				return err
+			}
+		}
+
+		var resourceTypes []string
+		if k8s.IsKindFilteringEnabled(auditConfig.Spec.Filtering.Kinds) {
+			resourceTypes = []string{}
+			for _, k := range k8s.FilterScannedKinds(auditConfig.Spec.Filtering.Kinds) {
+				resourceTypes = append(resourceTypes, strings.ToLower(k.Kind))
+			}
+		}
+
 		opts := &ScanApiStoreAddOpts{
 			Url:               scanapi.ScanApiServiceUrl(auditConfig),
 			Token:             string(secret.Data[constants.MondooTokenSecretKey]),
 			IntegrationMrn:    integrationMrn,
 			IncludeNamespaces: auditConfig.Spec.Filtering.Namespaces.Include,
 			ExcludeNamespaces: auditConfig.Spec.Filtering.Namespaces.Exclude,
+			NamespaceSelector: namespaceSelector,
+			ResourceTypes:     resourceTypes,
 		}
 		scanApiStore.Add(opts)
 	}
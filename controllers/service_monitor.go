@@ -95,9 +95,13 @@ func (s *ServiceMonitor) serviceMonitorForMondoo(m *mondoov1alpha2.MondooOperato
 				},
 			},
 			Selector: metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					// The key/value set for the mondoo-operator Deployment
-					"app.kubernetes.io/name": "mondoo-operator",
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "app.kubernetes.io/name",
+						Operator: metav1.LabelSelectorOpIn,
+						// The values set for the mondoo-operator and the admission webhook Deployments
+						Values: []string{"mondoo-operator", "mondoo-operator-webhook"},
+					},
 				},
 			},
 			NamespaceSelector: monitoringv1.NamespaceSelector{
@@ -121,6 +121,8 @@ func (s *scanApiClient) ScanKubernetesResources(ctx context.Context, scanOpts *S
 
 	if scanOpts.ScanContainerImages {
 		scanJob.Inventory.Spec.Assets[0].Connections[0].Discover.Targets = []string{"container-images"}
+	} else if len(scanOpts.DiscoveryTargets) > 0 {
+		scanJob.Inventory.Spec.Assets[0].Connections[0].Discover.Targets = scanOpts.DiscoveryTargets
 	}
 
 	reqBodyBytes, err := json.Marshal(scanJob)
@@ -13,6 +13,17 @@ import (
 )
 
 func FakeServer() *httptest.Server {
+	return FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok: true,
+		WorstScore: &scanapiclient.Score{
+			Type:  scanapiclient.ValidScanResult,
+			Value: 100,
+		},
+	})
+}
+
+// FakeServerWithResult starts a fake scan API which returns the provided result for admission reviews.
+func FakeServerWithResult(admissionResult *scanapiclient.ScanResult) *httptest.Server {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/Scan/HealthCheck", func(w http.ResponseWriter, r *http.Request) {
 		result := &common.HealthCheckResponse{
@@ -30,14 +41,7 @@ func FakeServer() *httptest.Server {
 	})
 
 	mux.HandleFunc(scanapiclient.RunAdmissionReviewEndpoint, func(w http.ResponseWriter, r *http.Request) {
-		result := &scanapiclient.ScanResult{
-			Ok: true,
-			WorstScore: &scanapiclient.Score{
-				Type:  scanapiclient.ValidScanResult,
-				Value: 100,
-			},
-		}
-		data, err := json.Marshal(result)
+		data, err := json.Marshal(admissionResult)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -5,6 +5,7 @@ package scanapiclient
 
 import (
 	"context"
+	"sort"
 
 	"go.mondoo.com/cnquery/v11/providers-sdk/v1/inventory"
 	"go.mondoo.com/cnspec/v11/policy/scan"
@@ -43,6 +44,84 @@ const ValidScanResult = uint32(2)
 type ScanResult struct {
 	WorstScore *Score `json:"worstScore,omitempty"`
 	Ok         bool   `json:"ok,omitempty"`
+	// Full holds the full report. It is only set for jobs with ReportType_FULL.
+	Full *ReportCollection `json:"full,omitempty"`
+}
+
+// ReportCollection is the part of the cnspec ReportCollection the operator needs to list the
+// results of the executed checks
+type ReportCollection struct {
+	Bundle *Bundle `json:"bundle,omitempty"`
+	// Reports holds the report of every scanned asset by asset MRN
+	Reports map[string]*Report `json:"reports,omitempty"`
+}
+
+type Bundle struct {
+	Policies []*Policy `json:"policies,omitempty"`
+	Queries  []*Query  `json:"queries,omitempty"`
+}
+
+type Policy struct {
+	Mrn  string `json:"mrn,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type Query struct {
+	Mrn   string `json:"mrn,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+type Report struct {
+	ScoringMrn string `json:"scoring_mrn,omitempty"`
+	EntityMrn  string `json:"entity_mrn,omitempty"`
+	// Scores holds the scores of the executed checks and policies by their MRN
+	Scores map[string]*Score `json:"scores,omitempty"`
+}
+
+// CheckResult is the result of a single check executed during a scan
+type CheckResult struct {
+	Mrn   string
+	Title string
+	Score *Score
+}
+
+// Checks returns the results of the checks in the full report. The scores of the policies and the
+// assets themselves are skipped. Returns nil if the result has no full report.
+func (r *ScanResult) Checks() []*CheckResult {
+	if r == nil || r.Full == nil {
+		return nil
+	}
+
+	titles := map[string]string{}
+	policies := map[string]bool{}
+	if r.Full.Bundle != nil {
+		for _, q := range r.Full.Bundle.Queries {
+			if q != nil {
+				titles[q.Mrn] = q.Title
+			}
+		}
+		for _, p := range r.Full.Bundle.Policies {
+			if p != nil {
+				policies[p.Mrn] = true
+			}
+		}
+	}
+
+	var checks []*CheckResult
+	for _, report := range r.Full.Reports {
+		if report == nil {
+			continue
+		}
+		for mrn, score := range report.Scores {
+			if score == nil || policies[mrn] || mrn == report.EntityMrn || mrn == report.ScoringMrn {
+				continue
+			}
+			checks = append(checks, &CheckResult{Mrn: mrn, Title: titles[mrn], Score: score})
+		}
+	}
+	// the reports and scores are maps, sort them for stable messages
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Mrn < checks[j].Mrn })
+	return checks
 }
 
 type Score struct {
@@ -76,6 +155,8 @@ type ScanKubernetesResourcesOpts struct {
 	ManagedBy           string
 	IncludeNamespaces   []string
 	ExcludeNamespaces   []string
+	// DiscoveryTargets limits the discovered Kubernetes resources. If empty, all resources are discovered.
+	DiscoveryTargets []string
 }
 
 type Empty struct{}
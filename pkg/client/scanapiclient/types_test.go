@@ -0,0 +1,40 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package scanapiclient_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+)
+
+var fullScanResult = mustRead("../../../tests/data/admission-review-full-result.json")
+
+func TestScanResultChecks(t *testing.T) {
+	result := &scanapiclient.ScanResult{}
+	require.NoError(t, json.Unmarshal(fullScanResult, result))
+
+	// the scores of the asset and the policy are not checks
+	checks := result.Checks()
+	require.Len(t, checks, 3)
+
+	titles := map[string]uint32{}
+	for _, c := range checks {
+		titles[c.Title] = c.Score.Value
+	}
+	assert.Equal(t, map[string]uint32{
+		"Container should not run as root":                   20,
+		"Container should have a memory limit":               60,
+		"Container should not run as a privileged container": 100,
+	}, titles)
+}
+
+func TestScanResultChecks_NoFullReport(t *testing.T) {
+	assert.Empty(t, (&scanapiclient.ScanResult{Ok: true}).Checks())
+	assert.Empty(t, (*scanapiclient.ScanResult)(nil).Checks())
+}
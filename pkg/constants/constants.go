@@ -16,4 +16,12 @@ const (
 	// MondooAssetsIntegrationLabel is the label we set for any assets whenever the consoleIntegration is enabled
 	// (for consistency with other integrations, the integration tag will not use the 'k8s' prefix)
 	MondooAssetsIntegrationLabel = "mondoo.com/" + "integration-mrn"
+	// MondooScanOptOutKey is the annotation (or label) which excludes an object from scanning when set to
+	// MondooScanOptOutValue
+	MondooScanOptOutKey   = "k8s.mondoo.com/scan"
+	MondooScanOptOutValue = "false"
+	// WebhookCircuitBreakerCheck is the name of the ready check which reports the circuit breaker of the
+	// admission webhook. The readiness probe excludes it, so an open circuit breaker does not remove the
+	// webhook from its Service.
+	WebhookCircuitBreakerCheck = "circuit-breaker"
 )
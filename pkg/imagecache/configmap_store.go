@@ -0,0 +1,119 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package imagecache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const storeTimeout = 5 * time.Second
+
+// resolutionStore persists resolved images
+type resolutionStore interface {
+	get(image string) (imageData, bool, error)
+	set(image string, data imageData) error
+}
+
+// storedImage is the representation of a resolved image in the ConfigMap
+type storedImage struct {
+	Image       string    `json:"image"`
+	URL         string    `json:"url"`
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+// configMapStore keeps the resolved images in a ConfigMap, so they are shared between replicas and
+// survive restarts. The ConfigMap has to exist.
+type configMapStore struct {
+	reader client.Reader
+	writer client.Writer
+	key    types.NamespacedName
+	now    func() time.Time
+}
+
+// NewImageCacherWithConfigMap returns an ImageCacher which persists the resolved images in the provided
+// ConfigMap. The ConfigMap has to exist. The reader should not be cached, because the ConfigMap is
+// updated by multiple replicas.
+func NewImageCacherWithConfigMap(reader client.Reader, writer client.Writer, name, namespace string) ImageCacher {
+	return &imageCache{
+		images:     map[string]imageData{},
+		fetchImage: queryImageWithSHA,
+		store: &configMapStore{
+			reader: reader,
+			writer: writer,
+			key:    types.NamespacedName{Name: name, Namespace: namespace},
+			now:    time.Now,
+		},
+	}
+}
+
+func (s *configMapStore) get(image string) (imageData, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	cm := &corev1.ConfigMap{}
+	if err := s.reader.Get(ctx, s.key, cm); err != nil {
+		return imageData{}, false, err
+	}
+
+	value, ok := cm.Data[storeKey(image)]
+	if !ok {
+		return imageData{}, false, nil
+	}
+	stored := storedImage{}
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return imageData{}, false, err
+	}
+	if stored.Image != image {
+		return imageData{}, false, nil
+	}
+	return imageData{url: stored.URL, lastUpdated: stored.LastUpdated}, true, nil
+}
+
+func (s *configMapStore) set(image string, data imageData) error {
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+
+	value, err := json.Marshal(storedImage{Image: image, URL: data.url, LastUpdated: data.lastUpdated})
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		if err := s.reader.Get(ctx, s.key, cm); err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		s.prune(cm.Data)
+		cm.Data[storeKey(image)] = string(value)
+		return s.writer.Update(ctx, cm)
+	})
+}
+
+// prune drops stale entries to keep the ConfigMap from growing unbounded
+func (s *configMapStore) prune(data map[string]string) {
+	for k, v := range data {
+		stored := storedImage{}
+		if err := json.Unmarshal([]byte(v), &stored); err != nil || stored.LastUpdated.Add(refreshPeriod).Before(s.now()) {
+			delete(data, k)
+		}
+	}
+}
+
+// storeKey returns a valid ConfigMap key for the image
+func storeKey(image string) string {
+	h := sha256.Sum256([]byte(image))
+	return hex.EncodeToString(h[:])
+}
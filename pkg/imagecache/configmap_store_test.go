@@ -0,0 +1,70 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package imagecache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigMapStore(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "image-digests", Namespace: "mondoo-operator"}}
+	kubeClient := fake.NewClientBuilder().WithObjects(cm).Build()
+
+	first := NewImageCacherWithConfigMap(kubeClient, kubeClient, cm.Name, cm.Namespace).(*imageCache)
+	first.fetchImage = func(string) (string, error) { return testCurrentImageDigest, nil }
+
+	img, err := first.GetImage(testImage)
+	require.NoError(t, err)
+	assert.Equal(t, testCurrentImageDigest, img)
+
+	// another replica uses the stored resolution without querying the registry
+	second := NewImageCacherWithConfigMap(kubeClient, kubeClient, cm.Name, cm.Namespace).(*imageCache)
+	second.fetchImage = func(string) (string, error) { return "", fmt.Errorf("should not call fetchImage") }
+
+	img, err = second.GetImage(testImage)
+	require.NoError(t, err)
+	assert.Equal(t, testCurrentImageDigest, img)
+}
+
+func TestConfigMapStorePrunesStaleEntries(t *testing.T) {
+	stale, err := json.Marshal(storedImage{Image: "imageB:latest", URL: "imageB@sha256:OLD", LastUpdated: time.Now().Add(-25 * time.Hour)})
+	require.NoError(t, err)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "image-digests", Namespace: "mondoo-operator"},
+		Data:       map[string]string{storeKey("imageB:latest"): string(stale)},
+	}
+	kubeClient := fake.NewClientBuilder().WithObjects(cm).Build()
+
+	cache := NewImageCacherWithConfigMap(kubeClient, kubeClient, cm.Name, cm.Namespace).(*imageCache)
+	cache.fetchImage = func(string) (string, error) { return testCurrentImageDigest, nil }
+	_, err = cache.GetImage(testImage)
+	require.NoError(t, err)
+
+	updated := &corev1.ConfigMap{}
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(cm), updated))
+	assert.Len(t, updated.Data, 1)
+	assert.Contains(t, updated.Data, storeKey(testImage))
+}
+
+func TestConfigMapStoreMissingConfigMap(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().Build()
+
+	// the store is only an optimization, so the image is still resolved
+	cache := NewImageCacherWithConfigMap(kubeClient, kubeClient, "missing", "mondoo-operator").(*imageCache)
+	cache.fetchImage = func(string) (string, error) { return testCurrentImageDigest, nil }
+	img, err := cache.GetImage(testImage)
+	require.NoError(t, err)
+	assert.Equal(t, testCurrentImageDigest, img)
+}
@@ -7,6 +7,8 @@ import (
 	"sync"
 	"time"
 
+	ctrl "sigs.k8s.io/controller-runtime"
+
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
@@ -15,6 +17,8 @@ const (
 	refreshPeriod = time.Hour * 24
 )
 
+var logger = ctrl.Log.WithName("imagecache")
+
 type ImageCacher interface {
 	GetImage(string) (string, error)
 }
@@ -22,7 +26,12 @@ type ImageCacher interface {
 type imageCache struct {
 	images      map[string]imageData
 	imagesMutex sync.RWMutex
-	fetchImage  func(string) (string, error)
+	// imageLocks serialize the resolution of each image, so a slow registry or store only blocks
+	// the requests for the same image
+	imageLocks map[string]*sync.Mutex
+	fetchImage func(string) (string, error)
+	// store optionally persists the resolved images beyond the lifetime of the process
+	store resolutionStore
 }
 
 type imageData struct {
@@ -43,41 +52,80 @@ func (i *imageCache) GetImage(image string) (string, error) {
 }
 
 func (i *imageCache) getImageWithSHA(image string) (string, error) {
-	i.imagesMutex.Lock()
-	defer i.imagesMutex.Unlock()
+	lock := i.imageLock(image)
+	lock.Lock()
+	defer lock.Unlock()
 
+	i.imagesMutex.RLock()
 	img, ok := i.images[image]
-	if !ok {
-		if err := i.updateImage(image); err != nil {
-			return "", err
+	i.imagesMutex.RUnlock()
+
+	if !ok && i.store != nil {
+		stored, found, err := i.store.get(image)
+		if err != nil {
+			// the store is only an optimization, so fall back to querying the registry
+			logger.Error(err, "failed to read resolved image from store", "image", image)
+		}
+		if found {
+			i.setImage(image, stored)
+			img, ok = stored, true
 		}
-		img = i.images[image]
 	}
 
-	// refresh, if image data is stale
-	if img.lastUpdated.Add(refreshPeriod).Before(time.Now()) {
-		if err := i.updateImage(image); err != nil {
+	// refresh, if image data is missing or stale
+	if !ok || img.lastUpdated.Add(refreshPeriod).Before(time.Now()) {
+		updated, err := i.updateImage(image)
+		if err != nil {
 			return "", err
 		}
-		img = i.images[image]
+		img = updated
 	}
 
 	return img.url, nil
 }
 
+// imageLock returns the lock which serializes the resolution of the image
+func (i *imageCache) imageLock(image string) *sync.Mutex {
+	i.imagesMutex.Lock()
+	defer i.imagesMutex.Unlock()
+
+	if i.imageLocks == nil {
+		i.imageLocks = map[string]*sync.Mutex{}
+	}
+	lock, ok := i.imageLocks[image]
+	if !ok {
+		lock = &sync.Mutex{}
+		i.imageLocks[image] = lock
+	}
+	return lock
+}
+
+func (i *imageCache) setImage(image string, data imageData) {
+	i.imagesMutex.Lock()
+	defer i.imagesMutex.Unlock()
+	i.images[image] = data
+}
+
 // updateImage will make a query out to the registry and store the sha for the image
-func (i *imageCache) updateImage(image string) error {
+func (i *imageCache) updateImage(image string) (imageData, error) {
 	imageUrl, err := i.fetchImage(image)
 	if err != nil {
-		return err
+		return imageData{}, err
 	}
 
-	i.images[image] = imageData{
+	data := imageData{
 		url:         imageUrl,
 		lastUpdated: time.Now(),
 	}
+	i.setImage(image, data)
+
+	if i.store != nil {
+		if err := i.store.set(image, data); err != nil {
+			logger.Error(err, "failed to persist resolved image", "image", image)
+		}
+	}
 
-	return nil
+	return data, nil
 }
 
 func queryImageWithSHA(image string) (string, error) {
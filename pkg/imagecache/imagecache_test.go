@@ -95,3 +95,53 @@ func TestCache(t *testing.T) {
 		})
 	}
 }
+
+// blockingStore blocks reading the test image until it is released
+type blockingStore struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s blockingStore) get(image string) (imageData, bool, error) {
+	if image == testImage {
+		close(s.started)
+		<-s.release
+	}
+	return imageData{}, false, nil
+}
+
+func (s blockingStore) set(string, imageData) error {
+	return nil
+}
+
+func TestCacheSlowStoreOnlyBlocksSameImage(t *testing.T) {
+	store := blockingStore{started: make(chan struct{}), release: make(chan struct{})}
+	testCache := &imageCache{
+		images:     map[string]imageData{},
+		fetchImage: func(image string) (string, error) { return image + "@sha256:CURRENT", nil },
+		store:      store,
+	}
+
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		_, _ = testCache.GetImage(testImage)
+	}()
+	<-store.started
+
+	resolved := make(chan string)
+	go func() {
+		img, _ := testCache.GetImage("imageB:latest")
+		resolved <- img
+	}()
+
+	select {
+	case img := <-resolved:
+		assert.Equal(t, "imageB:latest@sha256:CURRENT", img)
+	case <-time.After(5 * time.Second):
+		t.Fatal("resolving another image was blocked by the store")
+	}
+
+	close(store.release)
+	<-blocked
+}
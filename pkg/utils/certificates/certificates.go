@@ -0,0 +1,125 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package certificates
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// SelfSignedValidity is the validity of the generated CA and serving certificates
+const SelfSignedValidity = 365 * 24 * time.Hour
+
+// GenerateSelfSigned creates a CA and a serving certificate signed by it. It returns the PEM encoded
+// CA certificate, serving certificate and private key, and the expiry of the serving certificate.
+func GenerateSelfSigned(dnsNames []string, now time.Time) ([]byte, []byte, []byte, time.Time, error) {
+	notBefore := now.Add(-time.Hour)
+	notAfter := now.Add(SelfSignedValidity)
+
+	caSerial, err := randomSerial()
+	if err != nil {
+		return nil, nil, nil, time.Time{}, err
+	}
+	ca := &x509.Certificate{
+		SerialNumber: caSerial,
+		Subject: pkix.Name{
+			CommonName:   "Mondoo Operator Webhook CA",
+			Organization: []string{"mondoo.com"},
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, nil, time.Time{}, fmt.Errorf("failed to generate private key for CA: %w", err)
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, time.Time{}, fmt.Errorf("failed to create self-signed certificate for CA: %w", err)
+	}
+
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, nil, time.Time{}, err
+	}
+	serving := &x509.Certificate{
+		DNSNames:     dnsNames,
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   dnsNames[0],
+			Organization: []string{"mondoo.com"},
+		},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, nil, time.Time{}, fmt.Errorf("failed to generate private key for the webhook: %w", err)
+	}
+	servingDER, err := x509.CreateCertificate(rand.Reader, serving, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, nil, time.Time{}, fmt.Errorf("failed to sign the serving certificate for the webhook: %w", err)
+	}
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: servingDER})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return caPEM, certPEM, keyPEM, notAfter, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate certificate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+// Valid returns the certificates of a PEM bundle which did not expire yet
+func Valid(data []byte, now time.Time) []byte {
+	var valid bytes.Buffer
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return valid.Bytes()
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || now.After(cert.NotAfter) {
+			continue
+		}
+		_ = pem.Encode(&valid, block)
+	}
+}
+
+// Parse parses the certificates of a PEM bundle. The first one is the serving certificate.
+func Parse(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
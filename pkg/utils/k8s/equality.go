@@ -11,6 +11,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
 )
 
 // AreDeploymentsEqual returns a value indicating whether 2 deployments are equal. Note that it does not perform a full
@@ -113,6 +114,9 @@ func AreCronJobsEqual(a, b batchv1.CronJob) bool {
 	if a.Spec.Schedule != b.Spec.Schedule {
 		return false
 	}
+	if ptr.Deref(a.Spec.Suspend, false) != ptr.Deref(b.Spec.Suspend, false) {
+		return false
+	}
 	if !reflect.DeepEqual(a.Spec.FailedJobsHistoryLimit, b.Spec.FailedJobsHistoryLimit) {
 		return false
 	}
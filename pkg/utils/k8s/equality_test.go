@@ -596,6 +596,24 @@ func TestAreCronJobsEqual(t *testing.T) {
 			},
 			shouldBeEqual: false,
 		},
+		{
+			name: "should not be equal when suspend differs",
+			createB: func(a batchv1.CronJob) batchv1.CronJob {
+				b := *a.DeepCopy()
+				b.Spec.Suspend = ptr.To(true)
+				return b
+			},
+			shouldBeEqual: false,
+		},
+		{
+			name: "should be equal when suspend is defaulted",
+			createB: func(a batchv1.CronJob) batchv1.CronJob {
+				b := *a.DeepCopy()
+				b.Spec.Suspend = ptr.To(false)
+				return b
+			},
+			shouldBeEqual: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,89 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package k8s
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+// ClusterDiscoveryTarget is the k8s inventory discovery target for the cluster asset itself
+const ClusterDiscoveryTarget = "clusters"
+
+// ScannedKind is a kind of Kubernetes resources which is scanned by the operator
+type ScannedKind struct {
+	schema.GroupVersionKind
+	// Resource is the plural name of the kind. It is also the k8s inventory discovery target for the kind.
+	Resource string
+	// NewObject creates an empty object of the kind
+	NewObject func() client.Object
+}
+
+// ScannedKinds lists the kinds of Kubernetes resources which are scanned by the operator
+var ScannedKinds = []ScannedKind{
+	{corev1.SchemeGroupVersion.WithKind("Pod"), "pods", func() client.Object { return &corev1.Pod{} }},
+	{appsv1.SchemeGroupVersion.WithKind("Deployment"), "deployments", func() client.Object { return &appsv1.Deployment{} }},
+	{appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), "replicasets", func() client.Object { return &appsv1.ReplicaSet{} }},
+	{appsv1.SchemeGroupVersion.WithKind("StatefulSet"), "statefulsets", func() client.Object { return &appsv1.StatefulSet{} }},
+	{appsv1.SchemeGroupVersion.WithKind("DaemonSet"), "daemonsets", func() client.Object { return &appsv1.DaemonSet{} }},
+	{batchv1.SchemeGroupVersion.WithKind("Job"), "jobs", func() client.Object { return &batchv1.Job{} }},
+	{batchv1.SchemeGroupVersion.WithKind("CronJob"), "cronjobs", func() client.Object { return &batchv1.CronJob{} }},
+}
+
+// AllowKind checks whether the kind passes the filtering. Setting Include overrides the Exclude list.
+func AllowKind(gvk schema.GroupVersionKind, filtering v1alpha2.KindFilteringSpec) bool {
+	if len(filtering.Include) > 0 {
+		return matchesAnyKind(gvk, filtering.Include)
+	}
+	return !matchesAnyKind(gvk, filtering.Exclude)
+}
+
+func matchesAnyKind(gvk schema.GroupVersionKind, kinds []v1alpha2.GroupVersionKind) bool {
+	for _, k := range kinds {
+		if k.Group == gvk.Group &&
+			(k.Version == "" || k.Version == gvk.Version) &&
+			strings.EqualFold(k.Kind, gvk.Kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKindFilteringEnabled checks whether any kind is included or excluded
+func IsKindFilteringEnabled(filtering v1alpha2.KindFilteringSpec) bool {
+	return len(filtering.Include) > 0 || len(filtering.Exclude) > 0
+}
+
+// FilterScannedKinds returns the scanned kinds which pass the filtering
+func FilterScannedKinds(filtering v1alpha2.KindFilteringSpec) []ScannedKind {
+	kinds := []ScannedKind{}
+	for _, k := range ScannedKinds {
+		if AllowKind(k.GroupVersionKind, filtering) {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
+
+// DiscoveryTargets returns the k8s inventory discovery targets for the kinds which pass the filtering. The
+// cluster is always discovered. Returns nil if the kinds are not filtered, so the inventory can fall back
+// to discovering everything.
+func DiscoveryTargets(filtering v1alpha2.KindFilteringSpec) []string {
+	if !IsKindFilteringEnabled(filtering) {
+		return nil
+	}
+
+	targets := []string{ClusterDiscoveryTarget}
+	for _, k := range FilterScannedKinds(filtering) {
+		targets = append(targets, k.Resource)
+	}
+	return targets
+}
@@ -0,0 +1,84 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+func TestAllowKind(t *testing.T) {
+	deployment := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	pod := corev1.SchemeGroupVersion.WithKind("Pod")
+
+	tests := []struct {
+		name      string
+		filtering v1alpha2.KindFilteringSpec
+		allowed   bool
+	}{
+		{
+			name:    "no filtering",
+			allowed: true,
+		},
+		{
+			name:      "included",
+			filtering: v1alpha2.KindFilteringSpec{Include: []v1alpha2.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "Deployment"}}},
+			allowed:   true,
+		},
+		{
+			name:      "included without version",
+			filtering: v1alpha2.KindFilteringSpec{Include: []v1alpha2.GroupVersionKind{{Group: "apps", Kind: "deployment"}}},
+			allowed:   true,
+		},
+		{
+			name:      "included in another group",
+			filtering: v1alpha2.KindFilteringSpec{Include: []v1alpha2.GroupVersionKind{{Kind: "Deployment"}}},
+			allowed:   false,
+		},
+		{
+			name:      "excluded",
+			filtering: v1alpha2.KindFilteringSpec{Exclude: []v1alpha2.GroupVersionKind{{Group: "apps", Kind: "Deployment"}}},
+			allowed:   false,
+		},
+		{
+			name: "include overrides exclude",
+			filtering: v1alpha2.KindFilteringSpec{
+				Include: []v1alpha2.GroupVersionKind{{Group: "apps", Kind: "Deployment"}},
+				Exclude: []v1alpha2.GroupVersionKind{{Group: "apps", Kind: "Deployment"}},
+			},
+			allowed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.allowed, AllowKind(deployment, test.filtering))
+		})
+	}
+
+	assert.False(t, AllowKind(pod, v1alpha2.KindFilteringSpec{Include: []v1alpha2.GroupVersionKind{{Group: "apps", Kind: "Deployment"}}}))
+}
+
+func TestDiscoveryTargets(t *testing.T) {
+	assert.Nil(t, DiscoveryTargets(v1alpha2.KindFilteringSpec{}))
+
+	assert.Equal(t,
+		[]string{"clusters", "deployments", "cronjobs"},
+		DiscoveryTargets(v1alpha2.KindFilteringSpec{Include: []v1alpha2.GroupVersionKind{
+			{Group: "apps", Kind: "Deployment"},
+			{Group: "batch", Kind: "CronJob"},
+		}}))
+
+	assert.Equal(t,
+		[]string{"clusters", "deployments", "replicasets", "statefulsets", "daemonsets", "cronjobs"},
+		DiscoveryTargets(v1alpha2.KindFilteringSpec{Exclude: []v1alpha2.GroupVersionKind{
+			{Kind: "Pod"},
+			{Group: "batch", Kind: "Job"},
+		}}))
+}
@@ -6,12 +6,17 @@ package k8s
 import (
 	"context"
 	"os"
+	"slices"
 
 	"github.com/go-logr/logr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils"
 )
 
 // GetRunningNamespace will return the namespace the Pod is running under
@@ -46,3 +51,78 @@ func GetClusterUID(ctx context.Context, kubeClient client.Client, log logr.Logge
 	clusterID := string(namespace.UID)
 	return clusterID, nil
 }
+
+// ListMatchingNamespaces returns the names of the Namespaces matching the label selector as well as the
+// include and exclude lists of the provided filtering
+func ListMatchingNamespaces(ctx context.Context, kubeClient client.Client, filtering v1alpha2.FilteringSpec) ([]string, error) {
+	selector := labels.Everything()
+	if filtering.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(filtering.Selector); err != nil {
+			return nil, err
+		}
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := kubeClient.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, ns := range namespaces.Items {
+		allow, err := utils.AllowNamespace(ns.Name, filtering.Include, filtering.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		if allow {
+			names = append(names, ns.Name)
+		}
+	}
+	return names, nil
+}
+
+// ResolveNamespaceSelector returns a copy of the MondooAuditConfig with the Namespace label selector replaced by
+// the list of matching Namespaces. This is needed for scans which cannot access the Namespaces themselves. The
+// Namespaces opted out of scanning are excluded as well, because the scans cannot see the opt-out. The
+// returned boolean is false if no Namespace matches, in which case there is nothing to scan.
+func ResolveNamespaceSelector(ctx context.Context, kubeClient client.Client, m v1alpha2.MondooAuditConfig) (v1alpha2.MondooAuditConfig, bool, error) {
+	optedOut, err := listOptedOutNamespaces(ctx, kubeClient)
+	if err != nil {
+		return m, false, err
+	}
+
+	if m.Spec.Filtering.Namespaces.Selector == nil {
+		if len(optedOut) == 0 {
+			return m, true, nil
+		}
+		resolved := m.DeepCopy()
+		resolved.Spec.Filtering.Namespaces.Exclude = append(resolved.Spec.Filtering.Namespaces.Exclude, optedOut...)
+		return *resolved, true, nil
+	}
+
+	names, err := ListMatchingNamespaces(ctx, kubeClient, m.Spec.Filtering.Namespaces)
+	if err != nil {
+		return m, false, err
+	}
+	names = slices.DeleteFunc(names, func(name string) bool { return slices.Contains(optedOut, name) })
+
+	resolved := m.DeepCopy()
+	resolved.Spec.Filtering.Namespaces = v1alpha2.FilteringSpec{Include: names}
+	return *resolved, len(names) > 0, nil
+}
+
+// listOptedOutNamespaces returns the names of the Namespaces which are opted out of scanning
+func listOptedOutNamespaces(ctx context.Context, kubeClient client.Client) ([]string, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := kubeClient.List(ctx, namespaces); err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, ns := range namespaces.Items {
+		if IsScanOptedOut(&ns) {
+			names = append(names, ns.Name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+func TestListMatchingNamespaces(t *testing.T) {
+	namespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	kubeClient := fake.NewClientBuilder().WithObjects(
+		namespace("team-a", map[string]string{"mondoo": "enabled"}),
+		namespace("team-b", map[string]string{"mondoo": "enabled"}),
+		namespace("team-c", nil),
+		namespace("kube-system", map[string]string{"mondoo": "enabled"}),
+	).Build()
+
+	tests := []struct {
+		name      string
+		filtering v1alpha2.FilteringSpec
+		expected  []string
+	}{
+		{
+			name:     "no filtering",
+			expected: []string{"kube-system", "team-a", "team-b", "team-c"},
+		},
+		{
+			name:      "selector",
+			filtering: v1alpha2.FilteringSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "enabled"}}},
+			expected:  []string{"kube-system", "team-a", "team-b"},
+		},
+		{
+			name: "selector and exclude",
+			filtering: v1alpha2.FilteringSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "enabled"}},
+				Exclude:  []string{"kube-*"},
+			},
+			expected: []string{"team-a", "team-b"},
+		},
+		{
+			name: "selector and include",
+			filtering: v1alpha2.FilteringSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "enabled"}},
+				Include:  []string{"team-*"},
+			},
+			expected: []string{"team-a", "team-b"},
+		},
+		{
+			name:      "nothing matches",
+			filtering: v1alpha2.FilteringSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "disabled"}}},
+			expected:  []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names, err := ListMatchingNamespaces(context.TODO(), kubeClient, test.filtering)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.expected, names)
+		})
+	}
+}
+
+func TestResolveNamespaceSelector(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"mondoo": "enabled"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+	).Build()
+
+	m := v1alpha2.MondooAuditConfig{}
+	m.Spec.Filtering.Namespaces.Exclude = []string{"kube-system"}
+	resolved, anyNamespace, err := ResolveNamespaceSelector(context.TODO(), kubeClient, m)
+	require.NoError(t, err)
+	assert.True(t, anyNamespace)
+	assert.Equal(t, m, resolved, "expected the MondooAuditConfig to be unchanged without a selector")
+
+	m.Spec.Filtering.Namespaces.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "enabled"}}
+	resolved, anyNamespace, err = ResolveNamespaceSelector(context.TODO(), kubeClient, m)
+	require.NoError(t, err)
+	assert.True(t, anyNamespace)
+	assert.Equal(t, v1alpha2.FilteringSpec{Include: []string{"team-a"}}, resolved.Spec.Filtering.Namespaces)
+	assert.NotNil(t, m.Spec.Filtering.Namespaces.Selector, "expected the original MondooAuditConfig to be unchanged")
+
+	m.Spec.Filtering.Namespaces.Selector.MatchLabels["mondoo"] = "disabled"
+	_, anyNamespace, err = ResolveNamespaceSelector(context.TODO(), kubeClient, m)
+	require.NoError(t, err)
+	assert.False(t, anyNamespace)
+}
+
+func TestResolveNamespaceSelector_OptedOut(t *testing.T) {
+	kubeClient := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"mondoo": "enabled"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-b",
+			Labels:      map[string]string{"mondoo": "enabled"},
+			Annotations: map[string]string{"k8s.mondoo.com/scan": "false"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-c", Labels: map[string]string{"k8s.mondoo.com/scan": "false"}}},
+	).Build()
+
+	m := v1alpha2.MondooAuditConfig{}
+	m.Spec.Filtering.Namespaces.Exclude = []string{"kube-system"}
+	resolved, anyNamespace, err := ResolveNamespaceSelector(context.TODO(), kubeClient, m)
+	require.NoError(t, err)
+	assert.True(t, anyNamespace)
+	assert.ElementsMatch(t, []string{"kube-system", "team-b", "team-c"}, resolved.Spec.Filtering.Namespaces.Exclude)
+	assert.Equal(t, []string{"kube-system"}, m.Spec.Filtering.Namespaces.Exclude, "expected the original MondooAuditConfig to be unchanged")
+
+	m.Spec.Filtering.Namespaces.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"mondoo": "enabled"}}
+	resolved, anyNamespace, err = ResolveNamespaceSelector(context.TODO(), kubeClient, m)
+	require.NoError(t, err)
+	assert.True(t, anyNamespace)
+	assert.Equal(t, v1alpha2.FilteringSpec{Include: []string{"team-a"}}, resolved.Spec.Filtering.Namespaces)
+}
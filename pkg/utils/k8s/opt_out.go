@@ -0,0 +1,17 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package k8s
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"go.mondoo.com/mondoo-operator/pkg/constants"
+)
+
+// IsScanOptedOut checks whether the object is excluded from scanning with the k8s.mondoo.com/scan: "false"
+// annotation. The label is honored as well, because it can also be used in object selectors.
+func IsScanOptedOut(obj metav1.Object) bool {
+	return obj.GetAnnotations()[constants.MondooScanOptOutKey] == constants.MondooScanOptOutValue ||
+		obj.GetLabels()[constants.MondooScanOptOutKey] == constants.MondooScanOptOutValue
+}
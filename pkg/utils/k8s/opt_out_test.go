@@ -0,0 +1,34 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsScanOptedOut(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		labels      map[string]string
+		expected    bool
+	}{
+		{name: "no annotation"},
+		{name: "annotation", annotations: map[string]string{"k8s.mondoo.com/scan": "false"}, expected: true},
+		{name: "label", labels: map[string]string{"k8s.mondoo.com/scan": "false"}, expected: true},
+		{name: "scanning enabled", annotations: map[string]string{"k8s.mondoo.com/scan": "true"}},
+		{name: "other annotation", annotations: map[string]string{"scan": "false"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations, Labels: test.labels}}
+			assert.Equal(t, test.expected, IsScanOptedOut(pod))
+		})
+	}
+}
@@ -32,3 +32,18 @@ func AllowNamespace(namespace string, includeNamespaces, excludeNamespaces []str
 	}
 	return true, nil
 }
+
+// MatchesAny checks whether value matches any of the provided glob patterns. Returns an error
+// if one of the patterns is not a valid glob.
+func MatchesAny(value string, patterns []string) (bool, error) {
+	for _, p := range patterns {
+		g, err := glob.Compile(p)
+		if err != nil {
+			return false, err
+		}
+		if g.Match(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -78,3 +78,20 @@ func TestFiltering(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchesAny(t *testing.T) {
+	match, err := MatchesAny("system:serviceaccount:kube-system:replicaset-controller", []string{"admin", "system:serviceaccount:kube-system:*"})
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = MatchesAny("admin", []string{"root", "system:*"})
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	match, err = MatchesAny("admin", nil)
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	_, err = MatchesAny("admin", []string{"[invalid"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package crdwebhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.mondoo.com/mondoo-operator/pkg/utils/certificates"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var logger = ctrl.Log.WithName("crd-webhooks")
+
+const (
+	CertificateSecretName = "mondoo-operator-crd-webhook-cert"
+
+	// caCertKey is the key of the CA bundle in the certificate Secret
+	caCertKey = "ca.crt"
+
+	// refreshInterval is the interval in which the certificate is checked for rotation and the CA bundle
+	// of the webhook configurations is repaired
+	refreshInterval = time.Hour
+	// rotationWindow is the remaining validity below which the certificate is rotated
+	rotationWindow = 30 * 24 * time.Hour
+)
+
+// WebhookConfigurationLabels select the webhook configurations of the operator CRDs. The webhook configurations
+// and their Service are installed with the operator, so they are removed when the operator is uninstalled.
+var WebhookConfigurationLabels = map[string]string{"app.kubernetes.io/component": "mondoo-operator-crd-webhook"}
+
+// Provisioner provides the serving certificate of the operator webhook server and injects its CA bundle into
+// the webhook configurations of the operator CRDs. The certificate is self-signed and stored in a Secret, so
+// all operator replicas serve the same one. The CA private key is not stored, every rotation creates a new CA.
+type Provisioner struct {
+	// KubeClient must not be cached, Setup runs before the manager is started
+	KubeClient client.Client
+	// Namespace is the namespace the operator runs in
+	Namespace string
+
+	cert atomic.Pointer[tls.Certificate]
+	// installed is whether the last Setup found the webhook configurations
+	installed atomic.Bool
+	// now returns the current time. Defaults to time.Now.
+	now func() time.Time
+}
+
+// GetCertificate returns the serving certificate loaded by the last Setup. It is meant to be used as
+// tls.Config.GetCertificate of the webhook server.
+func (p *Provisioner) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := p.cert.Load()
+	if cert == nil {
+		return nil, errors.New("the webhook serving certificate is not loaded yet")
+	}
+	return cert, nil
+}
+
+// Setup makes sure the Secret holds a valid serving certificate for the Service of the webhook configurations,
+// loads it and injects the CA bundle into the webhook configurations. If the webhook configurations are not
+// installed, e.g. with OLM, there is nothing to set up.
+func (p *Provisioner) Setup(ctx context.Context) error {
+	mwcs := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	if err := p.KubeClient.List(ctx, mwcs, client.MatchingLabels(WebhookConfigurationLabels)); err != nil {
+		logger.Error(err, "Failed to list the operator MutatingWebhookConfigurations")
+		return err
+	}
+	vwcs := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := p.KubeClient.List(ctx, vwcs, client.MatchingLabels(WebhookConfigurationLabels)); err != nil {
+		logger.Error(err, "Failed to list the operator ValidatingWebhookConfigurations")
+		return err
+	}
+
+	service := webhookService(mwcs, vwcs)
+	p.installed.Store(service != nil)
+	if service == nil {
+		logger.Info("The webhook configurations of the operator CRDs are not installed, skipping the webhook setup")
+		return nil
+	}
+	if service.Namespace != p.Namespace {
+		return fmt.Errorf("the operator webhook Service %s/%s is not in the operator namespace %s", service.Namespace, service.Name, p.Namespace)
+	}
+
+	var caBundle []byte
+	var err error
+	// another operator replica might store a certificate at the same time, in that case its certificate is used
+	for attempt := 0; attempt < 2; attempt++ {
+		caBundle, err = p.setupCertificate(ctx, dnsNames(service))
+		if err == nil || !(kerrors.IsAlreadyExists(err) || kerrors.IsConflict(err)) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.injectCABundle(ctx, mwcs, vwcs, caBundle)
+}
+
+// Installed returns whether the last Setup found the webhook configurations. Without them, no certificate
+// is loaded, so the webhook server must not be started.
+func (p *Provisioner) Installed() bool {
+	return p.installed.Load()
+}
+
+// Start repeats the Setup periodically until the context is cancelled, so the certificate is rotated
+// before it expires and picked up after another replica rotated it
+func (p *Provisioner) Start(ctx context.Context) error {
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := p.Setup(ctx); err != nil {
+				logger.Error(err, "Failed to refresh the operator webhooks")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, since every operator replica serves the webhooks
+func (p *Provisioner) NeedLeaderElection() bool {
+	return false
+}
+
+// setupCertificate makes sure the Secret holds a valid serving certificate and loads it. It returns the
+// CA bundle to inject into the webhook configurations.
+func (p *Provisioner) setupCertificate(ctx context.Context, dnsNames []string) ([]byte, error) {
+	now := p.clock()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: CertificateSecretName, Namespace: p.Namespace},
+	}
+
+	exists, err := k8s.CheckIfExists(ctx, p.KubeClient, secret, secret)
+	if err != nil {
+		logger.Error(err, "Failed to check for existing operator webhook certificate Secret")
+		return nil, err
+	}
+
+	var previousCAs []byte
+	if exists {
+		certs, err := certificates.Parse(secret.Data[corev1.TLSCertKey])
+		if err == nil && len(secret.Data[caCertKey]) > 0 && slices.Equal(certs[0].DNSNames, dnsNames) &&
+			certs[0].NotAfter.Sub(now) > rotationWindow {
+			return secret.Data[caCertKey], p.loadCertificate(secret)
+		}
+		// the previous CA stays trusted until all replicas serve the new certificate
+		previousCAs = certificates.Valid(secret.Data[caCertKey], now)
+		logger.Info("Rotating the operator webhook certificate")
+	}
+
+	caBundle, certPEM, keyPEM, _, err := certificates.GenerateSelfSigned(dnsNames, now)
+	if err != nil {
+		logger.Error(err, "Failed to generate the operator webhook certificate")
+		return nil, err
+	}
+	caBundle = append(caBundle, previousCAs...)
+
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+		caCertKey:               caBundle,
+	}
+	if exists {
+		err = p.KubeClient.Update(ctx, secret)
+	} else {
+		err = p.KubeClient.Create(ctx, secret)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to store the operator webhook certificate")
+		return nil, err
+	}
+	return caBundle, p.loadCertificate(secret)
+}
+
+func (p *Provisioner) loadCertificate(secret *corev1.Secret) error {
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		logger.Error(err, "Failed to load the operator webhook certificate")
+		return err
+	}
+	p.cert.Store(&cert)
+	return nil
+}
+
+// webhookService returns the Service the webhook configurations refer to
+func webhookService(mwcs *admissionregistrationv1.MutatingWebhookConfigurationList, vwcs *admissionregistrationv1.ValidatingWebhookConfigurationList) *admissionregistrationv1.ServiceReference {
+	for _, mwc := range mwcs.Items {
+		for _, w := range mwc.Webhooks {
+			if w.ClientConfig.Service != nil {
+				return w.ClientConfig.Service
+			}
+		}
+	}
+	for _, vwc := range vwcs.Items {
+		for _, w := range vwc.Webhooks {
+			if w.ClientConfig.Service != nil {
+				return w.ClientConfig.Service
+			}
+		}
+	}
+	return nil
+}
+
+// injectCABundle sets the CA bundle of the webhooks which are served by the operator
+func (p *Provisioner) injectCABundle(ctx context.Context, mwcs *admissionregistrationv1.MutatingWebhookConfigurationList, vwcs *admissionregistrationv1.ValidatingWebhookConfigurationList, caBundle []byte) error {
+	for i := range mwcs.Items {
+		mwc := &mwcs.Items[i]
+		changed := false
+		for j := range mwc.Webhooks {
+			changed = setCABundle(&mwc.Webhooks[j].ClientConfig, caBundle) || changed
+		}
+		if !changed {
+			continue
+		}
+		if err := p.KubeClient.Update(ctx, mwc); err != nil {
+			logger.Error(err, "Failed to inject the CA bundle into the operator MutatingWebhookConfiguration", "name", mwc.Name)
+			return err
+		}
+	}
+	for i := range vwcs.Items {
+		vwc := &vwcs.Items[i]
+		changed := false
+		for j := range vwc.Webhooks {
+			changed = setCABundle(&vwc.Webhooks[j].ClientConfig, caBundle) || changed
+		}
+		if !changed {
+			continue
+		}
+		if err := p.KubeClient.Update(ctx, vwc); err != nil {
+			logger.Error(err, "Failed to inject the CA bundle into the operator ValidatingWebhookConfiguration", "name", vwc.Name)
+			return err
+		}
+	}
+	return nil
+}
+
+// setCABundle sets the CA bundle of a webhook served by a Service. It returns whether it changed.
+func setCABundle(clientConfig *admissionregistrationv1.WebhookClientConfig, caBundle []byte) bool {
+	if clientConfig.Service == nil || bytes.Equal(clientConfig.CABundle, caBundle) {
+		return false
+	}
+	clientConfig.CABundle = caBundle
+	return true
+}
+
+func (p *Provisioner) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+func dnsNames(service *admissionregistrationv1.ServiceReference) []string {
+	return []string{
+		fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", service.Name, service.Namespace),
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package crdwebhooks
+
+import (
+	"context"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"go.mondoo.com/mondoo-operator/pkg/utils/certificates"
+)
+
+const testNamespace = "mondoo-operator"
+
+func testWebhook(path string) admissionregistrationv1.WebhookClientConfig {
+	return admissionregistrationv1.WebhookClientConfig{
+		Service: &admissionregistrationv1.ServiceReference{
+			Namespace: testNamespace,
+			Name:      "mondoo-operator-crd-webhook",
+			Path:      ptr.To(path),
+			Port:      ptr.To(int32(443)),
+		},
+	}
+}
+
+// testProvisioner returns a Provisioner with the webhook configurations installed like by the manifests
+// in config/crd-webhook
+func testProvisioner(now time.Time) *Provisioner {
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "mondoo-operator-crd-defaulting", Labels: WebhookConfigurationLabels},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{Name: "mondooauditconfig.k8s.mondoo.com", ClientConfig: testWebhook(mondooAuditConfigMutatePath)},
+		},
+	}
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "mondoo-operator-crd-validation", Labels: WebhookConfigurationLabels},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "mondooauditconfig.k8s.mondoo.com", ClientConfig: testWebhook(mondooAuditConfigValidatePath)},
+			{Name: "mondoooperatorconfig.k8s.mondoo.com", ClientConfig: testWebhook(mondooOperatorConfigValidatePath)},
+		},
+	}
+	// the webhook configuration of the admission controller is managed by the MondooAuditConfig controller
+	other := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "mondoo-operator-mondoo-client-mondoo"},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{Name: "policy.k8s.mondoo.com", ClientConfig: testWebhook("/validate-k8s-mondoo-com")},
+		},
+	}
+	return &Provisioner{
+		KubeClient: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(mwc, vwc, other).Build(),
+		Namespace:  testNamespace,
+		now:        func() time.Time { return now },
+	}
+}
+
+func getSecret(t *testing.T, kubeClient client.Client) *corev1.Secret {
+	secret := &corev1.Secret{}
+	require.NoError(t, kubeClient.Get(context.Background(), client.ObjectKey{Name: CertificateSecretName, Namespace: testNamespace}, secret))
+	return secret
+}
+
+func TestProvisioner_Setup(t *testing.T) {
+	p := testProvisioner(time.Now())
+	require.NoError(t, p.Setup(context.Background()))
+	assert.True(t, p.Installed())
+
+	secret := getSecret(t, p.KubeClient)
+	certs, err := certificates.Parse(secret.Data[corev1.TLSCertKey])
+	require.NoError(t, err)
+	assert.Contains(t, certs[0].DNSNames, "mondoo-operator-crd-webhook.mondoo-operator.svc")
+
+	// the served certificate is the stored one and is trusted by the CA bundle
+	served, err := p.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, certs[0].Raw, served.Certificate[0])
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(secret.Data[caCertKey]))
+	_, err = certs[0].Verify(x509.VerifyOptions{Roots: pool, DNSName: certs[0].DNSNames[0]})
+	assert.NoError(t, err)
+
+	mwc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	require.NoError(t, p.KubeClient.Get(context.Background(), client.ObjectKey{Name: "mondoo-operator-crd-defaulting"}, mwc))
+	assert.Equal(t, secret.Data[caCertKey], mwc.Webhooks[0].ClientConfig.CABundle)
+
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	require.NoError(t, p.KubeClient.Get(context.Background(), client.ObjectKey{Name: "mondoo-operator-crd-validation"}, vwc))
+	for _, w := range vwc.Webhooks {
+		assert.Equal(t, secret.Data[caCertKey], w.ClientConfig.CABundle)
+	}
+
+	other := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	require.NoError(t, p.KubeClient.Get(context.Background(), client.ObjectKey{Name: "mondoo-operator-mondoo-client-mondoo"}, other))
+	assert.Empty(t, other.Webhooks[0].ClientConfig.CABundle)
+}
+
+func TestProvisioner_Setup_KeepsValidCertificate(t *testing.T) {
+	now := time.Now()
+	p := testProvisioner(now)
+	require.NoError(t, p.Setup(context.Background()))
+	before := getSecret(t, p.KubeClient)
+
+	// another replica loads the stored certificate instead of generating its own
+	other := &Provisioner{KubeClient: p.KubeClient, Namespace: testNamespace, now: p.now}
+	require.NoError(t, other.Setup(context.Background()))
+	assert.Equal(t, before.Data, getSecret(t, p.KubeClient).Data)
+
+	served, err := other.GetCertificate(nil)
+	require.NoError(t, err)
+	certs, err := certificates.Parse(before.Data[corev1.TLSCertKey])
+	require.NoError(t, err)
+	assert.Equal(t, certs[0].Raw, served.Certificate[0])
+}
+
+func TestProvisioner_Setup_RotatesExpiringCertificate(t *testing.T) {
+	now := time.Now()
+	p := testProvisioner(now)
+	require.NoError(t, p.Setup(context.Background()))
+	before := getSecret(t, p.KubeClient)
+
+	p.now = func() time.Time { return now.Add(certificates.SelfSignedValidity - rotationWindow + time.Hour) }
+	require.NoError(t, p.Setup(context.Background()))
+	after := getSecret(t, p.KubeClient)
+
+	assert.NotEqual(t, before.Data[corev1.TLSCertKey], after.Data[corev1.TLSCertKey])
+	// the previous CA stays in the bundle until all replicas serve the new certificate
+	assert.Contains(t, string(after.Data[caCertKey]), string(before.Data[caCertKey]))
+
+	vwc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	require.NoError(t, p.KubeClient.Get(context.Background(), client.ObjectKey{Name: "mondoo-operator-crd-validation"}, vwc))
+	assert.Equal(t, after.Data[caCertKey], vwc.Webhooks[0].ClientConfig.CABundle)
+}
+
+func TestProvisioner_Setup_NotInstalled(t *testing.T) {
+	p := &Provisioner{
+		KubeClient: fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		Namespace:  testNamespace,
+	}
+	require.NoError(t, p.Setup(context.Background()))
+	assert.False(t, p.Installed(), "the webhook server must not be started without webhook configurations")
+
+	// without webhook configurations, no certificate is needed
+	secrets := &corev1.SecretList{}
+	require.NoError(t, p.KubeClient.List(context.Background(), secrets))
+	assert.Empty(t, secrets.Items)
+	_, err := p.GetCertificate(nil)
+	assert.Error(t, err)
+}
+
+func TestProvisioner_Setup_ServiceInOtherNamespace(t *testing.T) {
+	p := testProvisioner(time.Now())
+	p.Namespace = "other"
+	assert.Error(t, p.Setup(context.Background()))
+}
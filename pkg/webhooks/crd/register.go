@@ -0,0 +1,32 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package crdwebhooks
+
+import (
+	"go.mondoo.com/mondoo-operator/api/v1alpha2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	// Port is the port the webhook server of the operator listens on
+	Port = 9443
+
+	mondooAuditConfigMutatePath      = "/mutate-k8s-mondoo-com-v1alpha2-mondooauditconfig"
+	mondooAuditConfigValidatePath    = "/validate-k8s-mondoo-com-v1alpha2-mondooauditconfig"
+	mondooOperatorConfigValidatePath = "/validate-k8s-mondoo-com-v1alpha2-mondoooperatorconfig"
+)
+
+// Register registers the defaulting and validating webhooks of the operator CRDs with the webhook server
+// of the manager
+func Register(mgr ctrl.Manager) {
+	server := mgr.GetWebhookServer()
+	scheme := mgr.GetScheme()
+	server.Register(mondooAuditConfigMutatePath,
+		admission.WithCustomDefaulter(scheme, &v1alpha2.MondooAuditConfig{}, &v1alpha2.MondooAuditConfigDefaulter{}))
+	server.Register(mondooAuditConfigValidatePath,
+		admission.WithCustomValidator(scheme, &v1alpha2.MondooAuditConfig{}, &v1alpha2.MondooAuditConfigValidator{}))
+	server.Register(mondooOperatorConfigValidatePath,
+		admission.WithCustomValidator(scheme, &v1alpha2.MondooOperatorConfig{}, &v1alpha2.MondooOperatorConfigValidator{}))
+}
@@ -0,0 +1,153 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
+)
+
+const (
+	// mondooScoreAnnotation holds the worst score of the last scan of the object
+	mondooScoreAnnotation = mondooLabelPrefix + "score"
+	// mondooScannedAtAnnotation holds the time of the last scan of the object
+	mondooScannedAtAnnotation = mondooLabelPrefix + "scanned-at"
+	// mondooPolicyVersionAnnotation holds the version of the admission policy used for the last scan
+	mondooPolicyVersionAnnotation = mondooLabelPrefix + "policy-version"
+
+	// The mutating and validating webhook scan the same object. Without a configured cache, a small one
+	// shares the scan result between both, so objects are only scanned once.
+	annotatorCacheSize = 100
+	annotatorCacheTTL  = time.Minute
+)
+
+// scanResultAnnotations are set by the annotator and ignored when comparing objects
+var scanResultAnnotations = []string{mondooScoreAnnotation, mondooScannedAtAnnotation, mondooPolicyVersionAnnotation}
+
+// Have kubebuilder generate a MutatingWebhookConfiguration under the path /mutate-k8s-mondoo-com which annotates the scanned workloads
+//+kubebuilder:webhook:path=/mutate-k8s-mondoo-com,mutating=true,failurePolicy=ignore,sideEffects=None,groups="";apps;batch,resources=pods;deployments;daemonsets;statefulsets;jobs;cronjobs,verbs=create;update,versions=v1,name=scan-result.k8s.mondoo.com,admissionReviewVersions=v1,reinvocationPolicy=Never
+
+// scanResultAnnotator is a mutating webhook which annotates objects with their scan result.
+// It never rejects an object.
+type scanResultAnnotator struct {
+	validator *webhookValidator
+}
+
+func (m *scanResultAnnotator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	a := m.validator
+	resource := fmt.Sprintf("%s/%s", req.Namespace, req.Name)
+
+	if _, enabled, _ := a.namespaceMode(ctx, req.Namespace); !enabled {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	obj, err := a.objFromRaw(req.Object)
+	if err != nil {
+		handlerlog.Error(err, "failed to decode object for annotation", "resource", resource)
+		return admission.Allowed(defaultScanPass)
+	}
+	if !shouldScanObject(obj) {
+		return admission.Allowed(defaultScanPass)
+	}
+	// objects opted out of scanning are not annotated, the validating webhook counts them
+	if objMeta, err := meta.Accessor(obj); err == nil && k8s.IsScanOptedOut(objMeta) {
+		return admission.Allowed(defaultScanPass)
+	}
+	if skip, err := a.skipNamespace(ctx, obj); err != nil || skip {
+		return admission.Allowed(defaultScanPass)
+	}
+	if req.AdmissionRequest.Operation == admissionv1.Update && req.AdmissionRequest.OldObject.Raw != nil {
+		// keep the annotations if nothing but the server-side apply metadata changed
+		if skip, err := objectsOnlyDifferInSSAFields(req.AdmissionRequest); err != nil || skip {
+			return admission.Allowed(defaultScanPass)
+		}
+	}
+
+	k8sLabels, err := a.generateLabels(req, obj)
+	if err != nil {
+		handlerlog.Error(err, "failed to set labels for incoming request")
+		return admission.Allowed(defaultScanPass)
+	}
+
+	scanJob, err := newScanJob(req, k8sLabels)
+	if err != nil {
+		handlerlog.Error(err, "failed to create scan job from admission request")
+		return admission.Allowed(defaultScanPass)
+	}
+
+	result, err := a.scanWithTimeout(ctx, req, scanJob)
+	if err != nil {
+		handlerlog.Error(err, "error returned from scan request, not annotating the resource", "resource", resource)
+		return admission.Allowed(defaultScanPass)
+	}
+	if result.WorstScore == nil || result.WorstScore.Type != scanapiclient.ValidScanResult {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	mutated, err := annotateScanResult(req.Object.Raw, map[string]string{
+		mondooScoreAnnotation:         strconv.FormatUint(uint64(result.WorstScore.Value), 10),
+		mondooScannedAtAnnotation:     time.Now().UTC().Format(time.RFC3339),
+		mondooPolicyVersionAnnotation: a.policyVersion,
+	})
+	if err != nil {
+		handlerlog.Error(err, "failed to annotate the resource", "resource", resource)
+		return admission.Allowed(defaultScanPass)
+	}
+
+	handlerlog.V(5).Info("annotating resource with the scan result", "kind", req.Kind.Kind, "resource", resource, "score", result.WorstScore.Value)
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// annotateScanResult adds the annotations to the raw JSON object
+func annotateScanResult(rawObj []byte, annotations map[string]string) ([]byte, error) {
+	objMapData := make(map[string]interface{})
+	if err := json.Unmarshal(rawObj, &objMapData); err != nil {
+		return nil, err
+	}
+
+	metadata, ok := objMapData["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		objMapData["metadata"] = metadata
+	}
+	existing, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		existing = map[string]interface{}{}
+		metadata["annotations"] = existing
+	}
+	for k, v := range annotations {
+		existing[k] = v
+	}
+
+	return json.Marshal(objMapData)
+}
+
+// removeScanResultAnnotations deletes the scan result annotations from the metadata of a JSON object
+func removeScanResultAnnotations(metadata map[string]interface{}) {
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, key := range scanResultAnnotations {
+		delete(annotations, key)
+	}
+	if len(annotations) == 0 {
+		delete(metadata, "annotations")
+	}
+}
+
+// ScanResultAnnotator returns the mutating webhook which annotates objects with their scan result
+func (a *webhookValidator) ScanResultAnnotator() admission.Handler {
+	return &scanResultAnnotator{validator: a}
+}
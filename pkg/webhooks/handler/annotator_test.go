@@ -0,0 +1,135 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+)
+
+func TestScanResultAnnotator(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// the validating webhook uses the result of the mutating webhook
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Times(1).Return(&scanapiclient.ScanResult{
+		Ok:         true,
+		WorstScore: &scanapiclient.Score{Type: scanapiclient.ValidScanResult, Value: 70},
+	}, nil)
+
+	validator := &webhookValidator{
+		decoder:       setupDecoder(t),
+		mode:          mondoov1alpha2.Enforcing,
+		scanner:       scanner,
+		uniDecoder:    serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		cache:         newScanResultCache(annotatorCacheSize, annotatorCacheTTL),
+		policyVersion: "abc123",
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Annotations = map[string]string{"team": "a"}
+			}),
+		},
+	}
+
+	response := validator.ScanResultAnnotator().Handle(context.TODO(), request)
+	require.True(t, response.Allowed)
+
+	patched := map[string]string{}
+	for _, op := range response.Patches {
+		require.Equal(t, "add", op.Operation)
+		require.True(t, strings.HasPrefix(op.Path, "/metadata/annotations/"))
+		key := strings.ReplaceAll(strings.TrimPrefix(op.Path, "/metadata/annotations/"), "~1", "/")
+		patched[key] = op.Value.(string)
+	}
+	assert.Equal(t, "70", patched[mondooScoreAnnotation])
+	assert.Equal(t, "abc123", patched[mondooPolicyVersionAnnotation])
+	scannedAt, err := time.Parse(time.RFC3339, patched[mondooScannedAtAnnotation])
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), scannedAt, time.Minute)
+
+	// pass the mutated object to the validating webhook
+	mutated, err := annotateScanResult(request.Object.Raw, patched)
+	require.NoError(t, err)
+	request.Object.Raw = mutated
+	request.Object.Object = nil
+
+	response = validator.Handle(context.TODO(), request)
+	assert.False(t, response.Allowed)
+	assert.Equal(t, failedScan, response.Result.Message)
+}
+
+func TestScanResultAnnotatorScanError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    scanner,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object:    testExampleDeployment(),
+		},
+	}
+
+	// the mutating webhook never rejects objects
+	response := validator.ScanResultAnnotator().Handle(context.TODO(), request)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patches)
+}
+
+func TestScanResultAnnotatorOptedOut(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// opted-out objects are not scanned
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    scanner,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Object: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Annotations = map[string]string{"k8s.mondoo.com/scan": "false"}
+			}),
+		},
+	}
+
+	response := validator.ScanResultAnnotator().Handle(context.TODO(), request)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patches)
+}
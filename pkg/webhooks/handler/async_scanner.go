@@ -0,0 +1,245 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+)
+
+const (
+	// admittedForAsyncScan is the Allowed result in Async mode. The resource is scanned in the background.
+	admittedForAsyncScan  = "ADMITTED, MONDOO SCAN RUNS IN THE BACKGROUND"
+	scanResultEventReason = "MondooScanResult"
+
+	defaultAsyncQueueSize = 1000
+	defaultAsyncWorkers   = 4
+	// patchPermissionTTL is the time the permission to patch a resource type is cached
+	patchPermissionTTL = 5 * time.Minute
+)
+
+// asyncScanRequest is an admitted resource waiting for its background scan
+type asyncScanRequest struct {
+	req     admission.Request
+	scanJob *scanapiclient.AdmissionReviewJob
+}
+
+// asyncScanner scans the resources admitted in async mode in the background. The workers run the
+// same admission review as the synchronous modes, which reports the result to Mondoo Platform, and
+// record an Event on the resource and optionally annotate it with the result.
+// ScheduleKubernetesResourceScan is not used, because it does not return the result and scanning
+// a resource with both would report it twice. Resources which do not fit into the queue are dropped.
+type asyncScanner struct {
+	validator *webhookValidator
+	queue     chan asyncScanRequest
+	workers   int
+	annotate  bool
+
+	mu sync.Mutex
+	// patchable caches whether the webhook is allowed to patch a resource type
+	patchable map[schema.GroupVersionResource]patchPermission
+}
+
+// patchPermission is the cached result of an access review
+type patchPermission struct {
+	allowed   bool
+	checkedAt time.Time
+}
+
+func newAsyncScanner(validator *webhookValidator, queueSize, workers int, annotate bool) (*asyncScanner, error) {
+	if queueSize < 0 || workers < 0 {
+		return nil, fmt.Errorf("async queue size %d and workers %d must not be negative", queueSize, workers)
+	}
+	if queueSize == 0 {
+		queueSize = defaultAsyncQueueSize
+	}
+	if workers == 0 {
+		workers = defaultAsyncWorkers
+	}
+	return &asyncScanner{
+		validator: validator,
+		queue:     make(chan asyncScanRequest, queueSize),
+		workers:   workers,
+		annotate:  annotate,
+		patchable: make(map[schema.GroupVersionResource]patchPermission),
+	}, nil
+}
+
+// enqueue adds an admitted resource to the queue of background scans. If the queue is full, the
+// resource is not scanned.
+func (s *asyncScanner) enqueue(req admission.Request, scanJob *scanapiclient.AdmissionReviewJob) {
+	if s == nil {
+		return
+	}
+
+	select {
+	case s.queue <- asyncScanRequest{req: req, scanJob: scanJob}:
+	default:
+		metricsAsyncScansTotal.WithLabelValues(asyncScanDropped).Inc()
+		handlerlog.Info("async scan queue is full, dropping the scan", "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name)
+	}
+}
+
+// Start runs the workers until the context is cancelled. It implements manager.Runnable.
+func (s *asyncScanner) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item := <-s.queue:
+					s.process(ctx, item)
+				}
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica scans the resources it admitted.
+func (s *asyncScanner) NeedLeaderElection() bool {
+	return false
+}
+
+// process scans a resource and reports the result on the resource
+func (s *asyncScanner) process(ctx context.Context, item asyncScanRequest) {
+	a := s.validator
+	req := item.req
+	resource := fmt.Sprintf("%s/%s", req.Namespace, req.Name)
+
+	result, err := a.scanWithTimeout(ctx, req, item.scanJob)
+	obj := s.liveObject(ctx, req)
+	if err != nil {
+		metricsAsyncScansTotal.WithLabelValues(asyncScanErrored).Inc()
+		handlerlog.Error(err, "background scan failed", "kind", req.Kind.Kind, "resource", resource)
+		if obj != nil && a.recorder != nil {
+			a.recorder.Eventf(obj, corev1.EventTypeWarning, scanFailedEventReason, "Mondoo could not scan the resource: %s", err.Error())
+		}
+		return
+	}
+
+	passed := a.policy.passed(result)
+	status := asyncScanPassed
+	if !passed {
+		status = asyncScanFailed
+	}
+	metricsAsyncScansTotal.WithLabelValues(status).Inc()
+	handlerlog.Info("Background scan result", "passed", passed, "kind", req.Kind.Kind, "resource", resource, "worstscore", result.WorstScore)
+
+	if obj == nil {
+		return
+	}
+
+	if a.recorder != nil {
+		if passed {
+			a.recorder.Event(obj, corev1.EventTypeNormal, scanResultEventReason, passedScan)
+		} else {
+			a.recorder.Event(obj, corev1.EventTypeWarning, scanResultEventReason, denialMessage(result))
+		}
+	}
+
+	if s.annotate && result.WorstScore != nil && result.WorstScore.Type == scanapiclient.ValidScanResult {
+		if !s.canPatch(ctx, req.Resource) {
+			handlerlog.V(5).Info("not annotating the resource, the webhook is not allowed to patch it", "kind", req.Kind.Kind, "resource", resource)
+			return
+		}
+		if err := s.annotateResult(ctx, obj, result); err != nil {
+			handlerlog.Error(err, "failed to annotate the resource with the background scan result", "kind", req.Kind.Kind, "resource", resource)
+		}
+	}
+}
+
+// liveObject fetches the admitted resource, so Events and annotations refer to the persisted object.
+// It returns nil if the resource cannot be found, e.g. because it was deleted in the meantime.
+func (s *asyncScanner) liveObject(ctx context.Context, req admission.Request) *unstructured.Unstructured {
+	if s.validator.client == nil || req.Name == "" {
+		return nil
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{Group: req.Kind.Group, Version: req.Kind.Version, Kind: req.Kind.Kind})
+	if err := s.validator.client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, obj); err != nil {
+		handlerlog.Error(err, "failed to get the resource to report the background scan result", "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name)
+		return nil
+	}
+	return obj
+}
+
+// annotateResult adds the scan result annotations to the resource
+func (s *asyncScanner) annotateResult(ctx context.Context, obj client.Object, result *scanapiclient.ScanResult) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				mondooScoreAnnotation:         strconv.FormatUint(uint64(result.WorstScore.Value), 10),
+				mondooScannedAtAnnotation:     time.Now().UTC().Format(time.RFC3339),
+				mondooPolicyVersionAnnotation: s.validator.policyVersion,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return s.validator.client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch))
+}
+
+// canPatch checks whether the webhook is allowed to patch the resource type. The webhook ClusterRole
+// only covers the workload types, so other configured admission resources are not annotated unless the
+// ClusterRole is extended. The result is cached for patchPermissionTTL.
+func (s *asyncScanner) canPatch(ctx context.Context, gvr metav1.GroupVersionResource) bool {
+	key := schema.GroupVersionResource{Group: gvr.Group, Version: gvr.Version, Resource: gvr.Resource}
+	s.mu.Lock()
+	cached, ok := s.patchable[key]
+	s.mu.Unlock()
+	if ok && time.Since(cached.checkedAt) < patchPermissionTTL {
+		return cached.allowed
+	}
+
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:     "patch",
+				Group:    gvr.Group,
+				Version:  gvr.Version,
+				Resource: gvr.Resource,
+			},
+		},
+	}
+	if err := s.validator.client.Create(ctx, review); err != nil {
+		handlerlog.Error(err, "failed to review the permission to patch the resource", "resource", key.String())
+		return false
+	}
+
+	s.mu.Lock()
+	s.patchable[key] = patchPermission{allowed: review.Status.Allowed, checkedAt: time.Now()}
+	s.mu.Unlock()
+	return review.Status.Allowed
+}
+
+// AsyncScanner returns the runnable which scans the resources admitted in async mode
+func (a *webhookValidator) AsyncScanner() manager.Runnable {
+	return a.asyncScanner
+}
@@ -0,0 +1,167 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+)
+
+func testAsyncRequest() admission.Request {
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Resource:  metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			Name:      "testDeployment",
+			Namespace: testNamespace,
+			Object:    testExampleDeployment(),
+		},
+	}
+}
+
+func TestWebhookAsyncMode(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// the scan API must not be called while admitting the resource
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Async,
+		scanner:    mock.NewMockScanApiClient(mockCtrl),
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	var err error
+	validator.asyncScanner, err = newAsyncScanner(validator, 10, 1, false)
+	require.NoError(t, err)
+
+	response := validator.Handle(context.TODO(), testAsyncRequest())
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, admittedForAsyncScan, response.AdmissionResponse.Result.Message)
+	assert.Len(t, validator.asyncScanner.queue, 1)
+}
+
+// patchReviewClient returns a client which answers the access reviews of the webhook with allowed
+func patchReviewClient(allowed bool, objs ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithObjects(objs...).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if review, ok := obj.(*authorizationv1.SelfSubjectAccessReview); ok {
+				review.Status.Allowed = allowed
+				return nil
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+}
+
+func TestAsyncScannerProcess(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Return(&scanapiclient.ScanResult{
+		WorstScore: &scanapiclient.Score{Type: scanapiclient.ValidScanResult, Value: 20},
+		Full:       testFullReport(testCheck("Container should not run as root", 20)),
+	}, nil)
+
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "testDeployment", Namespace: testNamespace}}
+	kubeClient := patchReviewClient(true, deployment)
+	recorder := record.NewFakeRecorder(10)
+	validator := &webhookValidator{
+		client:        kubeClient,
+		mode:          mondoov1alpha2.Async,
+		scanner:       scanner,
+		recorder:      recorder,
+		policyVersion: "3f2a9c1b7d4e",
+	}
+	asyncScanner, err := newAsyncScanner(validator, 10, 1, true)
+	require.NoError(t, err)
+
+	req := testAsyncRequest()
+	scanJob, err := newScanJob(req, map[string]string{})
+	require.NoError(t, err)
+	asyncScanner.process(context.TODO(), asyncScanRequest{req: req, scanJob: scanJob})
+
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Warning MondooScanResult "+failedScan+": Container should not run as root (score 20)", <-recorder.Events)
+
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(deployment), deployment))
+	assert.Equal(t, "20", deployment.Annotations[mondooScoreAnnotation])
+	assert.Equal(t, "3f2a9c1b7d4e", deployment.Annotations[mondooPolicyVersionAnnotation])
+	assert.NotEmpty(t, deployment.Annotations[mondooScannedAtAnnotation])
+}
+
+func TestAsyncScannerNotPatchable(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Return(&scanapiclient.ScanResult{
+		WorstScore: &scanapiclient.Score{Type: scanapiclient.ValidScanResult, Value: 100},
+	}, nil)
+
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "testDeployment", Namespace: testNamespace}}
+	kubeClient := patchReviewClient(false, deployment)
+	recorder := record.NewFakeRecorder(10)
+	validator := &webhookValidator{
+		client:   kubeClient,
+		mode:     mondoov1alpha2.Async,
+		scanner:  scanner,
+		recorder: recorder,
+	}
+	asyncScanner, err := newAsyncScanner(validator, 10, 1, true)
+	require.NoError(t, err)
+
+	req := testAsyncRequest()
+	scanJob, err := newScanJob(req, map[string]string{})
+	require.NoError(t, err)
+	asyncScanner.process(context.TODO(), asyncScanRequest{req: req, scanJob: scanJob})
+
+	// the Event is still recorded, but the resource is not annotated
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal MondooScanResult "+passedScan, <-recorder.Events)
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(deployment), deployment))
+	assert.Empty(t, deployment.Annotations[mondooScoreAnnotation])
+	assert.False(t, asyncScanner.patchable[schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}].allowed)
+}
+
+func TestAsyncScannerDropped(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// the scan API is only called by the workers
+	validator := &webhookValidator{scanner: mock.NewMockScanApiClient(mockCtrl)}
+	asyncScanner, err := newAsyncScanner(validator, 1, 1, false)
+	require.NoError(t, err)
+
+	other := testAsyncRequest()
+	other.Name = "otherDeployment"
+	asyncScanner.enqueue(testAsyncRequest(), nil)
+
+	dropped := metricsAsyncScansTotal.WithLabelValues(asyncScanDropped)
+	before := testutil.ToFloat64(dropped)
+	asyncScanner.enqueue(other, nil)
+	assert.Equal(t, before+1, testutil.ToFloat64(dropped))
+	assert.Len(t, asyncScanner.queue, 1)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+const defaultAuditFlushInterval = 30 * time.Second
+
+// AuditReporter aggregates the resources admitted in audit mode which enforcing mode would have
+// denied as well as the resources opted out of scanning and periodically adds them to the status
+// of the MondooAuditConfig. Aggregating the counts keeps the webhook from updating the status for
+// every admission request.
+type AuditReporter struct {
+	reader   client.Reader
+	writer   client.StatusClient
+	key      types.NamespacedName
+	interval time.Duration
+	now      func() time.Time
+
+	mu              sync.Mutex
+	pending         int64
+	last            time.Time
+	pendingOptedOut int64
+}
+
+// NewAuditReporter creates an AuditReporter for the MondooAuditConfig with the provided name.
+// The reader should not be cached, because the status is updated by multiple webhook replicas.
+func NewAuditReporter(reader client.Reader, writer client.StatusClient, name, namespace string) *AuditReporter {
+	return &AuditReporter{
+		reader:   reader,
+		writer:   writer,
+		key:      types.NamespacedName{Name: name, Namespace: namespace},
+		interval: defaultAuditFlushInterval,
+		now:      time.Now,
+	}
+}
+
+// record registers a resource which enforcing mode would have denied
+func (r *AuditReporter) record() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending++
+	r.last = r.now()
+}
+
+// recordOptedOut registers a resource which was skipped because it is opted out of scanning
+func (r *AuditReporter) recordOptedOut() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pendingOptedOut++
+}
+
+// Start flushes the pending count until the context is cancelled. It implements manager.Runnable.
+func (r *AuditReporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the manager context is already cancelled, so use a fresh one for the final flush
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := r.flush(flushCtx); err != nil {
+				handlerlog.Error(err, "failed to report audit results on shutdown", "mondooauditconfig", r.key)
+			}
+			return nil
+		case <-ticker.C:
+			if err := r.flush(ctx); err != nil {
+				handlerlog.Error(err, "failed to report audit results", "mondooauditconfig", r.key)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica reports its own count.
+func (r *AuditReporter) NeedLeaderElection() bool {
+	return false
+}
+
+// flush adds the pending counts to the MondooAuditConfig status. If the update fails, the
+// counts are kept for the next flush.
+func (r *AuditReporter) flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending, last, pendingOptedOut := r.pending, r.last, r.pendingOptedOut
+	r.pending, r.pendingOptedOut = 0, 0
+	r.mu.Unlock()
+
+	if pending == 0 && pendingOptedOut == 0 {
+		return nil
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		mac := &mondoov1alpha2.MondooAuditConfig{}
+		if err := r.reader.Get(ctx, r.key, mac); err != nil {
+			return err
+		}
+		if pending > 0 {
+			mac.Status.Admission.WouldBeDeniedCount += pending
+			lastTime := metav1.NewTime(last)
+			mac.Status.Admission.LastWouldBeDeniedTime = &lastTime
+		}
+		mac.Status.Admission.OptedOutCount += pendingOptedOut
+		return r.writer.Status().Update(ctx, mac)
+	})
+	if err != nil {
+		r.mu.Lock()
+		r.pending += pending
+		r.pendingOptedOut += pendingOptedOut
+		r.mu.Unlock()
+	}
+	return err
+}
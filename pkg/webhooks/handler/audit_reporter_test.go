@@ -0,0 +1,78 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+func TestAuditReporterFlush(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(mondoov1alpha2.AddToScheme(scheme))
+
+	mac := &mondoov1alpha2.MondooAuditConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "mondoo-client", Namespace: "mondoo-operator"},
+		Status: mondoov1alpha2.MondooAuditConfigStatus{
+			Admission: mondoov1alpha2.AdmissionStatus{WouldBeDeniedCount: 3},
+		},
+	}
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(mac).WithStatusSubresource(mac).Build()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	reporter := NewAuditReporter(kubeClient, kubeClient, mac.Name, mac.Namespace)
+	reporter.now = func() time.Time { return now }
+
+	// nothing to report
+	require.NoError(t, reporter.flush(context.TODO()))
+
+	reporter.record()
+	reporter.record()
+	require.NoError(t, reporter.flush(context.TODO()))
+	assert.Zero(t, reporter.pending)
+
+	updated := &mondoov1alpha2.MondooAuditConfig{}
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(mac), updated))
+	assert.Equal(t, int64(5), updated.Status.Admission.WouldBeDeniedCount)
+	require.NotNil(t, updated.Status.Admission.LastWouldBeDeniedTime)
+	assert.True(t, now.Equal(updated.Status.Admission.LastWouldBeDeniedTime.Time))
+	assert.Zero(t, updated.Status.Admission.OptedOutCount)
+
+	// opted out resources don't change the last would be denied time
+	reporter.now = func() time.Time { return now.Add(time.Hour) }
+	reporter.recordOptedOut()
+	require.NoError(t, reporter.flush(context.TODO()))
+	assert.Zero(t, reporter.pendingOptedOut)
+
+	require.NoError(t, kubeClient.Get(context.TODO(), client.ObjectKeyFromObject(mac), updated))
+	assert.Equal(t, int64(1), updated.Status.Admission.OptedOutCount)
+	assert.Equal(t, int64(5), updated.Status.Admission.WouldBeDeniedCount)
+	assert.True(t, now.Equal(updated.Status.Admission.LastWouldBeDeniedTime.Time))
+}
+
+func TestAuditReporterFlushKeepsCountOnError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(mondoov1alpha2.AddToScheme(scheme))
+	kubeClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	reporter := NewAuditReporter(kubeClient, kubeClient, "missing", "mondoo-operator")
+	reporter.record()
+	reporter.recordOptedOut()
+	assert.Error(t, reporter.flush(context.TODO()))
+	assert.Equal(t, int64(1), reporter.pending)
+	assert.Equal(t, int64(1), reporter.pendingOptedOut)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+)
+
+// scanResultCache is a size-bounded LRU cache of scan results whose entries expire after a TTL.
+type scanResultCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+	now        func() time.Time
+}
+
+type cacheEntry struct {
+	key     string
+	result  *scanapiclient.ScanResult
+	expires time.Time
+}
+
+func newScanResultCache(maxEntries int, ttl time.Duration) *scanResultCache {
+	return &scanResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+		now:        time.Now,
+	}
+}
+
+// get returns the cached scan result for the key, if present and not expired.
+func (c *scanResultCache) get(key string) (*scanapiclient.ScanResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(e)
+	return entry.result, true
+}
+
+// add stores the scan result for the key and evicts the least recently used entry if the cache is full.
+func (c *scanResultCache) add(key string, result *scanapiclient.ScanResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*cacheEntry)
+		entry.result = result
+		entry.expires = c.now().Add(c.ttl)
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, result: result, expires: c.now().Add(c.ttl)})
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheKey hashes the object together with the policy version. Metadata which changes without
+// affecting the scan result, like the resourceVersion or the managedFields, is dropped before hashing.
+func cacheKey(rawObj []byte, policyVersion string) (string, error) {
+	objMapData := make(map[string]interface{})
+	if err := json.Unmarshal(rawObj, &objMapData); err != nil {
+		return "", err
+	}
+
+	delete(objMapData, "status")
+	if metadata, ok := objMapData["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"resourceVersion", "managedFields", "generation", "uid", "creationTimestamp"} {
+			delete(metadata, field)
+		}
+		// the mutating webhook adds the scan result annotations before the validating webhook is called
+		removeScanResultAnnotations(metadata)
+	}
+
+	// json.Marshal sorts map keys, so the result is stable
+	data, err := json.Marshal(objMapData)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(policyVersion))
+	h.Write([]byte{0})
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+)
+
+func TestScanResultCache_Eviction(t *testing.T) {
+	cache := newScanResultCache(2, time.Minute)
+
+	cache.add("a", &scanapiclient.ScanResult{Ok: true})
+	cache.add("b", &scanapiclient.ScanResult{Ok: true})
+	_, ok := cache.get("a")
+	require.True(t, ok)
+
+	// "b" is the least recently used entry now
+	cache.add("c", &scanapiclient.ScanResult{Ok: true})
+
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+}
+
+func TestScanResultCache_Expiry(t *testing.T) {
+	now := time.Now()
+	cache := newScanResultCache(10, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.add("a", &scanapiclient.ScanResult{Ok: true})
+	_, ok := cache.get("a")
+	require.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+	assert.Empty(t, cache.entries)
+}
+
+func TestCacheKey(t *testing.T) {
+	dep := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "testDeployment",
+			Namespace:       testNamespace,
+			ResourceVersion: "1",
+			Generation:      1,
+		},
+	}
+	raw, err := json.Marshal(dep)
+	require.NoError(t, err)
+	key, err := cacheKey(raw, "v1")
+	require.NoError(t, err)
+
+	// metadata noise does not change the key
+	dep.ResourceVersion = "2"
+	dep.Generation = 2
+	dep.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "kubectl"}}
+	dep.Status.Replicas = 3
+	raw, err = json.Marshal(dep)
+	require.NoError(t, err)
+	otherKey, err := cacheKey(raw, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, key, otherKey)
+
+	// the scan result annotations do not change the key
+	dep.Annotations = map[string]string{mondooScoreAnnotation: "70", mondooScannedAtAnnotation: "2024-01-01T00:00:00Z"}
+	raw, err = json.Marshal(dep)
+	require.NoError(t, err)
+	otherKey, err = cacheKey(raw, "v1")
+	require.NoError(t, err)
+	assert.Equal(t, key, otherKey)
+
+	// the policy version changes the key
+	otherKey, err = cacheKey(raw, "v2")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+
+	// spec changes change the key
+	dep.Spec.Paused = true
+	raw, err = json.Marshal(dep)
+	require.NoError(t, err)
+	otherKey, err = cacheKey(raw, "v1")
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+}
+
+func TestWebhookCache(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Times(1).Return(&scanapiclient.ScanResult{
+		Ok:         true,
+		WorstScore: &scanapiclient.Score{Type: scanapiclient.ValidScanResult, Value: 20},
+	}, nil)
+
+	validator := &webhookValidator{
+		decoder:       setupDecoder(t),
+		mode:          mondoov1alpha2.Enforcing,
+		scanner:       scanner,
+		uniDecoder:    serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		cache:         newScanResultCache(10, time.Minute),
+		policyVersion: "v1",
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Object: testExampleDeployment(),
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		response := validator.Handle(context.TODO(), request)
+		assert.False(t, response.AdmissionResponse.Allowed)
+		assert.Equal(t, failedScan, response.AdmissionResponse.Result.Message)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultBreakerOpenDuration is the time the circuit breaker stays open if no duration is configured
+const defaultBreakerOpenDuration = 30 * time.Second
+
+var (
+	errCircuitOpen      = errors.New("circuit breaker for the scan API is open")
+	errConcurrencyLimit = errors.New("too many concurrent scans")
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+// circuitBreaker stops calling the scan API after consecutive failures. While it is open, scans
+// fail immediately, so the webhook answers according to the failure policy instead of waiting
+// for the timeout. After the open duration, a single scan probes whether the scan API recovered.
+// A nil circuitBreaker is disabled.
+type circuitBreaker struct {
+	failureThreshold int
+	openDuration     time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// newCircuitBreaker creates a circuit breaker which opens after the number of consecutive failures.
+// A threshold of zero disables the circuit breaker.
+func newCircuitBreaker(failureThreshold int, openDuration time.Duration) (*circuitBreaker, error) {
+	if failureThreshold < 0 {
+		return nil, fmt.Errorf("circuit breaker failure threshold %d is not valid, must not be negative", failureThreshold)
+	}
+	if failureThreshold == 0 {
+		return nil, nil
+	}
+	if openDuration <= 0 {
+		openDuration = defaultBreakerOpenDuration
+	}
+	metricsCircuitBreakerState.Set(float64(breakerClosed))
+	return &circuitBreaker{failureThreshold: failureThreshold, openDuration: openDuration, now: time.Now}, nil
+}
+
+// allow returns an error if the scan should not be attempted
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return errCircuitOpen
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		// only a single probe is allowed until it completes
+		if b.probing {
+			return errCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record updates the circuit breaker with the result of a scan
+func (b *circuitBreaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.failureThreshold {
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+	}
+}
+
+// currentState returns the state of the circuit breaker. A disabled circuit breaker is closed.
+func (b *circuitBreaker) currentState() breakerState {
+	if b == nil {
+		return breakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *circuitBreaker) setState(state breakerState) {
+	if b.state != state {
+		handlerlog.Info("scan API circuit breaker changed state", "from", b.state.String(), "to", state.String())
+	}
+	b.state = state
+	metricsCircuitBreakerState.Set(float64(state))
+}
+
+// concurrencyLimiter limits the number of concurrent scans. Scans beyond the limit are rejected
+// right away instead of queueing up behind a slow scan API. A nil concurrencyLimiter is disabled.
+type concurrencyLimiter struct {
+	slots chan struct{}
+}
+
+// newConcurrencyLimiter creates a limiter for the number of concurrent scans. A limit of zero
+// disables the limiter.
+func newConcurrencyLimiter(limit int) (*concurrencyLimiter, error) {
+	if limit < 0 {
+		return nil, fmt.Errorf("concurrent scan limit %d is not valid, must not be negative", limit)
+	}
+	if limit == 0 {
+		return nil, nil
+	}
+	return &concurrencyLimiter{slots: make(chan struct{}, limit)}, nil
+}
+
+// acquire reserves a slot for a scan. The returned function releases the slot.
+func (l *concurrencyLimiter) acquire() (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	default:
+		return nil, errConcurrencyLimit
+	}
+}
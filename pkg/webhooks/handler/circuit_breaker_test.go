@@ -0,0 +1,145 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/common"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker, err := newCircuitBreaker(2, time.Minute)
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+	scanErr := fmt.Errorf("scan API unavailable")
+
+	// a success resets the consecutive failures
+	require.NoError(t, breaker.allow())
+	breaker.record(scanErr)
+	breaker.record(nil)
+	breaker.record(scanErr)
+	assert.Equal(t, breakerClosed, breaker.state)
+
+	breaker.record(scanErr)
+	assert.Equal(t, breakerOpen, breaker.state)
+	assert.ErrorIs(t, breaker.allow(), errCircuitOpen)
+	assert.Equal(t, float64(breakerOpen), testutil.ToFloat64(metricsCircuitBreakerState))
+
+	// after the open duration, a single probe is allowed
+	now = now.Add(time.Minute)
+	require.NoError(t, breaker.allow())
+	assert.Equal(t, breakerHalfOpen, breaker.state)
+	assert.ErrorIs(t, breaker.allow(), errCircuitOpen)
+
+	// a failed probe opens the circuit breaker again
+	breaker.record(scanErr)
+	assert.Equal(t, breakerOpen, breaker.state)
+	assert.ErrorIs(t, breaker.allow(), errCircuitOpen)
+
+	// a successful probe closes it
+	now = now.Add(time.Minute)
+	require.NoError(t, breaker.allow())
+	breaker.record(nil)
+	assert.Equal(t, breakerClosed, breaker.state)
+	assert.NoError(t, breaker.allow())
+	assert.Equal(t, float64(breakerClosed), testutil.ToFloat64(metricsCircuitBreakerState))
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker, err := newCircuitBreaker(0, time.Minute)
+	require.NoError(t, err)
+	assert.Nil(t, breaker)
+	assert.NoError(t, breaker.allow())
+	breaker.record(fmt.Errorf("scan API unavailable"))
+	assert.NoError(t, breaker.allow())
+
+	_, err = newCircuitBreaker(-1, time.Minute)
+	assert.Error(t, err)
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter, err := newConcurrencyLimiter(2)
+	require.NoError(t, err)
+
+	release1, err := limiter.acquire()
+	require.NoError(t, err)
+	release2, err := limiter.acquire()
+	require.NoError(t, err)
+
+	_, err = limiter.acquire()
+	assert.ErrorIs(t, err, errConcurrencyLimit)
+
+	release1()
+	release3, err := limiter.acquire()
+	require.NoError(t, err)
+	release2()
+	release3()
+
+	disabled, err := newConcurrencyLimiter(0)
+	require.NoError(t, err)
+	release, err := disabled.acquire()
+	require.NoError(t, err)
+	release()
+}
+
+func TestWebhookCircuitBreaker(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// the scan API is only called until the circuit breaker opens
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("scan API unavailable")).Times(2)
+	scanner.EXPECT().HealthCheck(gomock.Any(), gomock.Any()).Return(&common.HealthCheckResponse{}, nil)
+
+	breaker, err := newCircuitBreaker(2, time.Minute)
+	require.NoError(t, err)
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    scanner,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		breaker:    breaker,
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Object: testExampleDeployment(),
+		},
+	}
+
+	rejected := metricsScansRejectedTotal.WithLabelValues(rejectedCircuitOpen)
+	rejectedBefore := testutil.ToFloat64(rejected)
+	for i := 0; i < 3; i++ {
+		response := validator.Handle(context.TODO(), request)
+		assert.False(t, response.AdmissionResponse.Allowed)
+		assert.Equal(t, defaultScanFail, response.AdmissionResponse.Result.Message)
+	}
+	assert.Equal(t, rejectedBefore+1, testutil.ToFloat64(rejected))
+
+	// an open circuit breaker does not fail the probes, it is reported by its own check and metric
+	err = validator.HealthChecker()(httptest.NewRequest("GET", "/healthz", nil))
+	assert.NoError(t, err)
+	err = validator.CircuitBreakerChecker()(httptest.NewRequest("GET", "/readyz/circuit-breaker", nil))
+	assert.EqualError(t, err, "circuit breaker for the scan API is open")
+	assert.Equal(t, float64(breakerOpen), testutil.ToFloat64(metricsCircuitBreakerState))
+}
@@ -0,0 +1,335 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"go.mondoo.com/cnquery/v11/providers-sdk/v1/inventory"
+	"go.mondoo.com/mondoo-operator/pkg/constants"
+)
+
+// Have kubebuilder generate a ValidatingWebhookConfiguration under the path /audit-connect-k8s-mondoo-com which records interactive access to Pods
+//+kubebuilder:webhook:path=/audit-connect-k8s-mondoo-com,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=pods/exec;pods/attach;pods/portforward,verbs=connect,versions=v1,name=connect.k8s.mondoo.com,admissionReviewVersions=v1
+
+const (
+	// connectRecorded is the Allowed result for a recorded exec, attach or port-forward request
+	connectRecorded = "MONDOO RECORDED INTERACTIVE ACCESS"
+	// connectDeniedInProduction is the Denied result for exec and attach requests in production Namespaces
+	connectDeniedInProduction = "MONDOO DENIES INTERACTIVE ACCESS TO PODS IN PRODUCTION NAMESPACES"
+	// connectDeniedUnknownNamespace is the Denied result for exec and attach requests if the Namespace cannot be checked
+	connectDeniedUnknownNamespace = "MONDOO DENIES INTERACTIVE ACCESS, THE NAMESPACE COULD NOT BE CHECKED"
+	connectEventReason            = "MondooInteractiveAccess"
+
+	// DefaultProductionNamespaceSelector selects the production Namespaces if no selector is configured
+	DefaultProductionNamespaceSelector = mondooLabelPrefix + "environment=production"
+
+	mondooSubresourceLabel = mondooLabelPrefix + "subresource"
+	mondooContainerLabel   = mondooLabelPrefix + "container"
+	mondooCommandLabel     = mondooLabelPrefix + "command"
+	mondooPortsLabel       = mondooLabelPrefix + "ports"
+
+	// connectReportTimeout is the time budget for reporting an interactive access to Mondoo
+	connectReportTimeout = 30 * time.Second
+	connectQueueSize     = 100
+	connectWorkers       = 2
+)
+
+// connectSubresources are the Pod subresources which give interactive access to a Pod
+var connectSubresources = []string{"exec", "attach", "portforward"}
+
+// connectAccess describes an exec, attach or port-forward request
+type connectAccess struct {
+	container string
+	command   []string
+	ports     []int32
+}
+
+// connectAuditor is a validating webhook for exec, attach and port-forward requests to Pods. Every
+// request is recorded as an Event on the Pod and reported to Mondoo as an admission review labeled
+// with the user, the Pod and the command. Exec and attach can be denied in production Namespaces.
+type connectAuditor struct {
+	validator *webhookValidator
+	// denyExecInProduction denies exec and attach requests to Pods in Namespaces matching productionNamespaces
+	denyExecInProduction bool
+	productionNamespaces labels.Selector
+	// report queues the access for the connect reporter, so the request is not delayed by the Event
+	// or the scan API
+	report func(report connectReport)
+}
+
+// connectReport is a recorded access waiting to be reported as an Event and to Mondoo
+type connectReport struct {
+	req      admission.Request
+	access   connectAccess
+	decision string
+	labels   map[string]string
+}
+
+func (c *connectAuditor) Handle(ctx context.Context, req admission.Request) admission.Response {
+	a := c.validator
+	resource := fmt.Sprintf("%s/%s", req.Namespace, req.Name)
+
+	if req.Operation != admissionv1.Connect || req.Resource.Resource != "pods" || !slices.Contains(connectSubresources, req.SubResource) {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	access, err := connectAccessFromRaw(req.SubResource, req.Object.Raw)
+	if err != nil {
+		handlerlog.Error(err, "failed to decode the connect options", "subresource", req.SubResource, "resource", resource)
+	}
+
+	exempt, err := a.exemptions.isExempt(req.UserInfo)
+	if err != nil {
+		handlerlog.Error(err, "failed to check whether the requester is exempted", "user", req.UserInfo.Username)
+	}
+
+	response := admission.Allowed(connectRecorded)
+	decision := decisionAllowed
+	if c.denyExecInProduction && req.SubResource != "portforward" && !exempt {
+		// fail closed, an exec into a production Pod must not be allowed because the Namespace lookup failed
+		production, err := c.isProductionNamespace(ctx, req.Namespace)
+		if err != nil {
+			handlerlog.Error(err, "failed to get namespace to check whether it is a production namespace", "namespace", req.Namespace)
+			response = admission.Denied(connectDeniedUnknownNamespace)
+			decision = decisionDenied
+		} else if production {
+			response = admission.Denied(connectDeniedInProduction)
+			decision = decisionDenied
+		}
+	}
+	handlerlog.Info("Interactive access to Pod", "subresource", req.SubResource, "resource", resource, "user", req.UserInfo.Username,
+		"container", access.container, "command", access.command, "ports", access.ports, "decision", decision)
+	metricsConnectRequestsTotal.WithLabelValues(req.SubResource, req.Namespace, decision).Inc()
+
+	k8sLabels := connectLabels(req, access)
+	k8sLabels[mondooClusterIDLabel] = a.clusterID
+	if a.integrationMRN != "" {
+		k8sLabels[constants.MondooAssetsIntegrationLabel] = a.integrationMRN
+	}
+	if exempt {
+		k8sLabels[mondooExemptedLabel] = "true"
+	}
+	c.report(connectReport{req: req, access: access, decision: decision, labels: k8sLabels})
+
+	return response
+}
+
+// isProductionNamespace checks whether the Namespace matches the production Namespace selector
+func (c *connectAuditor) isProductionNamespace(ctx context.Context, namespace string) (bool, error) {
+	if c.validator.client == nil || c.productionNamespaces == nil || c.productionNamespaces.Empty() {
+		return false, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.validator.client.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return false, err
+	}
+	return c.productionNamespaces.Matches(labels.Set(ns.Labels)), nil
+}
+
+// connectMessage describes the access for the Event on the Pod
+func connectMessage(user, subresource string, access connectAccess) string {
+	switch subresource {
+	case "exec":
+		message := fmt.Sprintf("%s executed %q", user, strings.Join(access.command, " "))
+		if access.container != "" {
+			message += " in container " + access.container
+		}
+		return message
+	case "attach":
+		if access.container != "" {
+			return fmt.Sprintf("%s attached to container %s", user, access.container)
+		}
+		return user + " attached to the Pod"
+	default:
+		if len(access.ports) > 0 {
+			return fmt.Sprintf("%s forwarded ports %s", user, joinPorts(access.ports))
+		}
+		return user + " forwarded ports of the Pod"
+	}
+}
+
+// connectReporter records the interactive accesses as Events and reports them to Mondoo in the
+// background, both on a best-effort basis. The accesses are queued, so a burst of requests does not
+// start an unbounded number of scans. Accesses which do not fit into the queue are not reported.
+type connectReporter struct {
+	validator *webhookValidator
+	queue     chan connectReport
+	workers   int
+}
+
+func newConnectReporter(validator *webhookValidator) *connectReporter {
+	return &connectReporter{
+		validator: validator,
+		queue:     make(chan connectReport, connectQueueSize),
+		workers:   connectWorkers,
+	}
+}
+
+// Start runs the workers until the context is cancelled. It implements manager.Runnable.
+func (r *connectReporter) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item := <-r.queue:
+					r.process(ctx, item)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica reports the accesses it recorded.
+func (r *connectReporter) NeedLeaderElection() bool {
+	return false
+}
+
+// process records the access as an Event on the Pod and sends it to Mondoo as an admission review.
+// The admission request is not scanned as a workload, so only the admission review asset with the
+// access labels is created.
+func (r *connectReporter) process(ctx context.Context, item connectReport) {
+	ctx, cancel := context.WithTimeout(ctx, connectReportTimeout)
+	defer cancel()
+
+	r.recordEvent(ctx, item)
+
+	scanJob, err := newScanJob(item.req, item.labels)
+	if err != nil {
+		handlerlog.Error(err, "failed to create scan job for the interactive access")
+		return
+	}
+	scanJob.Discovery = &inventory.Discovery{Targets: []string{"admissionreviews"}}
+	if _, err := r.validator.runAdmissionReview(ctx, scanJob); err != nil {
+		handlerlog.Error(err, "failed to report the interactive access to Mondoo", "namespace", item.req.Namespace, "name", item.req.Name)
+	}
+}
+
+// recordEvent records the access as an Event on the Pod. The Pod is read without the cache, because
+// the webhook is not allowed to watch Pods.
+func (r *connectReporter) recordEvent(ctx context.Context, item connectReport) {
+	a := r.validator
+	if a.recorder == nil || a.apiReader == nil {
+		return
+	}
+
+	pod := &corev1.Pod{}
+	if err := a.apiReader.Get(ctx, client.ObjectKey{Namespace: item.req.Namespace, Name: item.req.Name}, pod); err != nil {
+		handlerlog.Error(err, "failed to get the pod to record the interactive access", "namespace", item.req.Namespace, "name", item.req.Name)
+		return
+	}
+
+	message := connectMessage(item.req.UserInfo.Username, item.req.SubResource, item.access)
+	if item.decision == decisionDenied {
+		a.recorder.Event(pod, corev1.EventTypeWarning, connectEventReason, truncate("Mondoo denied: "+message, maxMessageLength))
+		return
+	}
+	a.recorder.Event(pod, corev1.EventTypeNormal, connectEventReason, truncate(message, maxMessageLength))
+}
+
+// reportConnect queues the access for the connect reporter
+func (a *webhookValidator) reportConnect(report connectReport) {
+	select {
+	case a.connectReporter.queue <- report:
+	default:
+		metricsConnectReportsDroppedTotal.Inc()
+		handlerlog.Info("connect report queue is full, the interactive access is not reported", "namespace", report.req.Namespace, "name", report.req.Name)
+	}
+}
+
+// connectAccessFromRaw decodes the exec, attach or port-forward options of a connect request
+func connectAccessFromRaw(subresource string, raw []byte) (connectAccess, error) {
+	if len(raw) == 0 {
+		return connectAccess{}, nil
+	}
+
+	switch subresource {
+	case "exec":
+		opts := corev1.PodExecOptions{}
+		if err := json.Unmarshal(raw, &opts); err != nil {
+			return connectAccess{}, err
+		}
+		return connectAccess{container: opts.Container, command: opts.Command}, nil
+	case "attach":
+		opts := corev1.PodAttachOptions{}
+		if err := json.Unmarshal(raw, &opts); err != nil {
+			return connectAccess{}, err
+		}
+		return connectAccess{container: opts.Container}, nil
+	case "portforward":
+		opts := corev1.PodPortForwardOptions{}
+		if err := json.Unmarshal(raw, &opts); err != nil {
+			return connectAccess{}, err
+		}
+		return connectAccess{ports: opts.Ports}, nil
+	}
+	return connectAccess{}, nil
+}
+
+// connectLabels builds the labels which describe the access in Mondoo
+func connectLabels(req admission.Request, access connectAccess) map[string]string {
+	k8sLabels := map[string]string{
+		mondooNamespaceLabel:   req.Namespace,
+		mondooNameLabel:        req.Name,
+		mondooKindLabel:        "Pod",
+		mondooAuthorLabel:      req.UserInfo.Username,
+		mondooOperationLabel:   string(req.Operation),
+		mondooSubresourceLabel: req.SubResource,
+	}
+	if access.container != "" {
+		k8sLabels[mondooContainerLabel] = access.container
+	}
+	if len(access.command) > 0 {
+		k8sLabels[mondooCommandLabel] = truncate(strings.Join(access.command, " "), maxMessageLength)
+	}
+	if len(access.ports) > 0 {
+		k8sLabels[mondooPortsLabel] = joinPorts(access.ports)
+	}
+	return k8sLabels
+}
+
+func joinPorts(ports []int32) string {
+	p := make([]string, 0, len(ports))
+	for _, port := range ports {
+		p = append(p, strconv.Itoa(int(port)))
+	}
+	return strings.Join(p, ",")
+}
+
+// ConnectReporter returns the runnable which reports the recorded interactive accesses to Mondoo
+func (a *webhookValidator) ConnectReporter() manager.Runnable {
+	return a.connectReporter
+}
+
+// ConnectAuditor returns the validating webhook which records exec, attach and port-forward requests to Pods
+func (a *webhookValidator) ConnectAuditor(denyExecInProduction bool, productionNamespaces labels.Selector) admission.Handler {
+	return &connectAuditor{
+		validator:            a,
+		denyExecInProduction: denyExecInProduction,
+		productionNamespaces: productionNamespaces,
+		report:               a.reportConnect,
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+	"go.mondoo.com/mondoo-operator/pkg/constants"
+)
+
+func testConnectRequest(t *testing.T, namespace, subresource, user string, opts runtime.Object) admission.Request {
+	raw, err := json.Marshal(opts)
+	require.NoError(t, err)
+	return admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:        metav1.GroupVersionKind{Version: "v1", Kind: "PodExecOptions"},
+			Resource:    metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+			SubResource: subresource,
+			Name:        "nginx",
+			Namespace:   namespace,
+			Operation:   admissionv1.Connect,
+			UserInfo:    authenticationv1.UserInfo{Username: user},
+			Object:      runtime.RawExtension{Raw: raw},
+		},
+	}
+}
+
+func TestConnectAuditor(t *testing.T) {
+	production := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{"env": "prod"}}}
+	staging := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "staging"}}
+	productionPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "shop"}}
+	stagingPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "staging"}}
+	selector, err := labels.Parse("env=prod")
+	require.NoError(t, err)
+
+	execOpts := &corev1.PodExecOptions{Container: "nginx", Command: []string{"sh", "-c", "cat /etc/passwd"}}
+	portForwardOpts := &corev1.PodPortForwardOptions{Ports: []int32{8080, 9090}}
+
+	tests := []struct {
+		name          string
+		req           admission.Request
+		expectAllowed bool
+		expectEvent   string
+	}{
+		{
+			name:          "exec in staging",
+			req:           testConnectRequest(t, "staging", "exec", "alice", execOpts),
+			expectAllowed: true,
+			expectEvent:   `Normal MondooInteractiveAccess alice executed "sh -c cat /etc/passwd" in container nginx`,
+		},
+		{
+			name:          "exec in production",
+			req:           testConnectRequest(t, "shop", "exec", "alice", execOpts),
+			expectAllowed: false,
+			expectEvent:   `Warning MondooInteractiveAccess Mondoo denied: alice executed "sh -c cat /etc/passwd" in container nginx`,
+		},
+		{
+			name:          "attach in production",
+			req:           testConnectRequest(t, "shop", "attach", "alice", &corev1.PodAttachOptions{Container: "nginx"}),
+			expectAllowed: false,
+			expectEvent:   "Warning MondooInteractiveAccess Mondoo denied: alice attached to container nginx",
+		},
+		{
+			name:          "port-forward in production",
+			req:           testConnectRequest(t, "shop", "portforward", "alice", portForwardOpts),
+			expectAllowed: true,
+			expectEvent:   "Normal MondooInteractiveAccess alice forwarded ports 8080,9090",
+		},
+		{
+			name:          "exempted user in production",
+			req:           testConnectRequest(t, "shop", "exec", "oncall", execOpts),
+			expectAllowed: true,
+			expectEvent:   `Normal MondooInteractiveAccess oncall executed "sh -c cat /etc/passwd" in container nginx`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			kubeClient := fake.NewClientBuilder().WithObjects(production, staging, productionPod, stagingPod).Build()
+			validator := &webhookValidator{
+				client:     kubeClient,
+				apiReader:  kubeClient,
+				recorder:   recorder,
+				exemptions: exemptions{users: []string{"oncall"}},
+				clusterID:  "cluster-uid",
+			}
+			auditor := validator.ConnectAuditor(true, selector).(*connectAuditor)
+			var reported *connectReport
+			auditor.report = func(report connectReport) { reported = &report }
+
+			response := auditor.Handle(context.TODO(), test.req)
+			assert.Equal(t, test.expectAllowed, response.AdmissionResponse.Allowed)
+			if !test.expectAllowed {
+				assert.Equal(t, connectDeniedInProduction, response.AdmissionResponse.Result.Message)
+			}
+
+			// the Event is recorded by the connect reporter, not while admitting the request
+			assert.Empty(t, recorder.Events)
+			require.NotNil(t, reported, "expected the access to be reported")
+			newConnectReporter(validator).recordEvent(context.TODO(), *reported)
+			require.Len(t, recorder.Events, 1)
+			assert.Equal(t, test.expectEvent, <-recorder.Events)
+
+			assert.Equal(t, test.req.UserInfo.Username, reported.labels[mondooAuthorLabel])
+			assert.Equal(t, "nginx", reported.labels[mondooNameLabel])
+			assert.Equal(t, test.req.Namespace, reported.labels[mondooNamespaceLabel])
+			assert.Equal(t, "Pod", reported.labels[mondooKindLabel])
+			assert.Equal(t, test.req.SubResource, reported.labels[mondooSubresourceLabel])
+			assert.Equal(t, "cluster-uid", reported.labels[mondooClusterIDLabel])
+		})
+	}
+}
+
+func TestConnectAuditorIgnoresOtherRequests(t *testing.T) {
+	validator := &webhookValidator{}
+	auditor := validator.ConnectAuditor(true, labels.Everything()).(*connectAuditor)
+	auditor.report = func(connectReport) { t.Fatal("unexpected report") }
+
+	req := testConnectRequest(t, "shop", "log", "alice", &corev1.PodLogOptions{})
+	response := auditor.Handle(context.TODO(), req)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, defaultScanPass, response.AdmissionResponse.Result.Message)
+}
+
+func TestConnectAuditorDeniesUnknownNamespace(t *testing.T) {
+	selector, err := labels.Parse("env=prod")
+	require.NoError(t, err)
+	validator := &webhookValidator{client: fake.NewClientBuilder().Build()}
+	auditor := validator.ConnectAuditor(true, selector).(*connectAuditor)
+	auditor.report = func(connectReport) {}
+
+	// the namespace cannot be looked up, so exec is denied instead of treating it as a non-production namespace
+	response := auditor.Handle(context.TODO(), testConnectRequest(t, "shop", "exec", "alice", &corev1.PodExecOptions{}))
+	assert.False(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, connectDeniedUnknownNamespace, response.AdmissionResponse.Result.Message)
+
+	// port-forward does not depend on the namespace
+	response = auditor.Handle(context.TODO(), testConnectRequest(t, "shop", "portforward", "alice", &corev1.PodPortForwardOptions{}))
+	assert.True(t, response.AdmissionResponse.Allowed)
+}
+
+func TestConnectLabels(t *testing.T) {
+	req := testConnectRequest(t, "shop", "exec", "alice", &corev1.PodExecOptions{})
+	access := connectAccess{container: "nginx", command: []string{"ls", "-la"}}
+	assert.Equal(t, map[string]string{
+		mondooNamespaceLabel:   "shop",
+		mondooNameLabel:        "nginx",
+		mondooKindLabel:        "Pod",
+		mondooAuthorLabel:      "alice",
+		mondooOperationLabel:   "CONNECT",
+		mondooSubresourceLabel: "exec",
+		mondooContainerLabel:   "nginx",
+		mondooCommandLabel:     "ls -la",
+	}, connectLabels(req, access))
+
+	access = connectAccess{ports: []int32{8080, 9090}}
+	assert.Equal(t, "8080,9090", connectLabels(req, access)[mondooPortsLabel])
+}
+
+func TestReportConnect(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	jobs := make(chan *scanapiclient.AdmissionReviewJob, 1)
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, job *scanapiclient.AdmissionReviewJob) (*scanapiclient.ScanResult, error) {
+			jobs <- job
+			return &scanapiclient.ScanResult{}, nil
+		})
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "shop"}}
+	recorder := record.NewFakeRecorder(10)
+	validator := &webhookValidator{
+		scanner:   scanner,
+		apiReader: fake.NewClientBuilder().WithObjects(pod).Build(),
+		recorder:  recorder,
+	}
+	validator.connectReporter = newConnectReporter(validator)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = validator.ConnectReporter().Start(ctx) }()
+
+	req := testConnectRequest(t, "shop", "exec", "alice", &corev1.PodExecOptions{Command: []string{"sh"}})
+	validator.reportConnect(connectReport{
+		req:      req,
+		access:   connectAccess{command: []string{"sh"}},
+		decision: decisionAllowed,
+		labels: map[string]string{
+			mondooCommandLabel:                     "sh",
+			constants.MondooAssetsIntegrationLabel: "integration-mrn",
+		},
+	})
+
+	select {
+	case job := <-jobs:
+		assert.Equal(t, []string{"admissionreviews"}, job.Discovery.Targets)
+		assert.Equal(t, "sh", job.Labels[mondooCommandLabel])
+		assert.Equal(t, "integration-mrn", job.Labels[constants.MondooAssetsIntegrationLabel])
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the access to be reported to the scan API")
+	}
+	// the Event is recorded before the access is reported to the scan API
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, `Normal MondooInteractiveAccess alice executed "sh"`, <-recorder.Events)
+}
+
+func TestReportConnectQueueFull(t *testing.T) {
+	validator := &webhookValidator{}
+	validator.connectReporter = newConnectReporter(validator)
+	validator.connectReporter.queue = make(chan connectReport, 1)
+	dropped := testutil.ToFloat64(metricsConnectReportsDroppedTotal)
+
+	// without running workers the second access does not fit into the queue
+	req := testConnectRequest(t, "shop", "exec", "alice", &corev1.PodExecOptions{Command: []string{"sh"}})
+	validator.reportConnect(connectReport{req: req})
+	validator.reportConnect(connectReport{req: req})
+
+	assert.Len(t, validator.connectReporter.queue, 1)
+	assert.Equal(t, dropped+1, testutil.ToFloat64(metricsConnectReportsDroppedTotal))
+}
@@ -0,0 +1,43 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+
+	"go.mondoo.com/mondoo-operator/pkg/utils"
+)
+
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// exemptions lists the requesters which bypass enforcement. All entries are glob patterns.
+type exemptions struct {
+	users           []string
+	groups          []string
+	serviceAccounts []string
+}
+
+// isExempt checks whether the requesting user is exempted from enforcement.
+func (e exemptions) isExempt(userInfo authenticationv1.UserInfo) (bool, error) {
+	if match, err := utils.MatchesAny(userInfo.Username, e.users); match || err != nil {
+		return match, err
+	}
+
+	for _, g := range userInfo.Groups {
+		if match, err := utils.MatchesAny(g, e.groups); match || err != nil {
+			return match, err
+		}
+	}
+
+	// service accounts authenticate as "system:serviceaccount:<namespace>:<name>"
+	if sa, ok := strings.CutPrefix(userInfo.Username, serviceAccountUserPrefix); ok {
+		if ns, name, ok := strings.Cut(sa, ":"); ok {
+			return utils.MatchesAny(ns+"/"+name, e.serviceAccounts)
+		}
+	}
+
+	return false, nil
+}
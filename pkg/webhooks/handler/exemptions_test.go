@@ -0,0 +1,70 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+)
+
+func TestExemptions(t *testing.T) {
+	e := exemptions{
+		users:           []string{"admin@example.com", "break-glass-*"},
+		groups:          []string{"system:masters"},
+		serviceAccounts: []string{"argocd/*", "kube-system/replicaset-controller"},
+	}
+
+	tests := []struct {
+		name     string
+		userInfo authenticationv1.UserInfo
+		expected bool
+	}{
+		{
+			name:     "exempted user",
+			userInfo: authenticationv1.UserInfo{Username: "admin@example.com"},
+			expected: true,
+		},
+		{
+			name:     "exempted user glob",
+			userInfo: authenticationv1.UserInfo{Username: "break-glass-alice"},
+			expected: true,
+		},
+		{
+			name:     "exempted group",
+			userInfo: authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated", "system:masters"}},
+			expected: true,
+		},
+		{
+			name:     "exempted service account glob",
+			userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:argocd:argocd-application-controller"},
+			expected: true,
+		},
+		{
+			name:     "exempted service account",
+			userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:kube-system:replicaset-controller"},
+			expected: true,
+		},
+		{
+			name:     "service account in other namespace",
+			userInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:default:argocd"},
+			expected: false,
+		},
+		{
+			name:     "not exempted",
+			userInfo: authenticationv1.UserInfo{Username: "bob", Groups: []string{"system:authenticated"}},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exempt, err := e.isExempt(test.userInfo)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, exempt)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+)
+
+func TestWebhookFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          mondoov1alpha2.AdmissionMode
+		failurePolicy mondoov1alpha2.AdmissionFailurePolicy
+		expectAllowed bool
+		expectEvent   bool
+	}{
+		{
+			name:          "enforcing without policy fails closed",
+			mode:          mondoov1alpha2.Enforcing,
+			expectAllowed: false,
+		},
+		{
+			name:          "enforcing fail-closed",
+			mode:          mondoov1alpha2.Enforcing,
+			failurePolicy: mondoov1alpha2.FailClosed,
+			expectAllowed: false,
+		},
+		{
+			name:          "enforcing fail-open",
+			mode:          mondoov1alpha2.Enforcing,
+			failurePolicy: mondoov1alpha2.FailOpen,
+			expectAllowed: true,
+		},
+		{
+			name:          "enforcing fail-open-with-event",
+			mode:          mondoov1alpha2.Enforcing,
+			failurePolicy: mondoov1alpha2.FailOpenWithEvent,
+			expectAllowed: true,
+			expectEvent:   true,
+		},
+		{
+			name:          "permissive fail-closed",
+			mode:          mondoov1alpha2.Permissive,
+			failurePolicy: mondoov1alpha2.FailClosed,
+			expectAllowed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			scanner := mock.NewMockScanApiClient(mockCtrl)
+			scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("scan API unavailable"))
+
+			recorder := record.NewFakeRecorder(10)
+			validator := &webhookValidator{
+				decoder:       setupDecoder(t),
+				mode:          test.mode,
+				scanner:       scanner,
+				uniDecoder:    serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+				recorder:      recorder,
+				failurePolicy: test.failurePolicy,
+			}
+
+			response := validator.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Object: testExampleDeployment(),
+				},
+			})
+
+			assert.Equal(t, test.expectAllowed, response.AdmissionResponse.Allowed)
+			if test.expectEvent {
+				assert.Len(t, recorder.Events, 1)
+			} else {
+				assert.Empty(t, recorder.Events)
+			}
+		})
+	}
+}
+
+func TestWebhookFailurePolicyNamespaceOverride(t *testing.T) {
+	tests := []struct {
+		name          string
+		failurePolicy mondoov1alpha2.AdmissionFailurePolicy
+		expectAllowed bool
+	}{
+		{
+			name:          "without policy fails closed",
+			expectAllowed: false,
+		},
+		{
+			name:          "explicit fail-open",
+			failurePolicy: mondoov1alpha2.FailOpen,
+			expectAllowed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			scanner := mock.NewMockScanApiClient(mockCtrl)
+			scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("scan API unavailable"))
+
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   testNamespace,
+					Labels: map[string]string{mondooAdmissionModeKey: string(mondoov1alpha2.Enforcing)},
+				},
+			}
+			validator := &webhookValidator{
+				client:        fake.NewClientBuilder().WithObjects(ns).Build(),
+				decoder:       setupDecoder(t),
+				mode:          mondoov1alpha2.Permissive,
+				scanner:       scanner,
+				uniDecoder:    serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+				failurePolicy: test.failurePolicy,
+			}
+
+			response := validator.Handle(context.TODO(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Namespace: testNamespace,
+					Object:    testExampleDeployment(),
+				},
+			})
+
+			assert.Equal(t, test.expectAllowed, response.AdmissionResponse.Allowed)
+		})
+	}
+}
+
+func TestWebhookScanTimeout(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	scanner := mock.NewMockScanApiClient(mockCtrl)
+	scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ *scanapiclient.AdmissionReviewJob) (*scanapiclient.ScanResult, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	validator := &webhookValidator{
+		decoder:       setupDecoder(t),
+		mode:          mondoov1alpha2.Enforcing,
+		scanner:       scanner,
+		uniDecoder:    serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		failurePolicy: mondoov1alpha2.FailOpen,
+		scanTimeout:   10 * time.Millisecond,
+	}
+
+	start := time.Now()
+	response := validator.Handle(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Object: testExampleDeployment(),
+		},
+	})
+
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, defaultScanPass, response.AdmissionResponse.Result.Message)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
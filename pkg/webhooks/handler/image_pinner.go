@@ -0,0 +1,223 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"go.mondoo.com/mondoo-operator/pkg/imagecache"
+	"go.mondoo.com/mondoo-operator/pkg/utils"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
+)
+
+// Have kubebuilder generate a MutatingWebhookConfiguration under the path /pin-k8s-mondoo-com which pins the images of workloads to digests
+//+kubebuilder:webhook:path=/pin-k8s-mondoo-com,mutating=true,failurePolicy=ignore,sideEffects=None,groups="";apps;batch,resources=pods;deployments;daemonsets;statefulsets;jobs;cronjobs,verbs=create;update,versions=v1,name=pin.k8s.mondoo.com,admissionReviewVersions=v1,reinvocationPolicy=Never
+
+// podSpecPaths maps the workload kinds to the location of their Pod spec
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// pinOnUpdateKinds are the workload controllers whose images are also pinned on updates. Changing their
+// Pod template rolls out new Pods anyway. Bare Pods and the immutable Pod template of Jobs are only
+// pinned on creation.
+var pinOnUpdateKinds = map[string]bool{
+	"Deployment":  true,
+	"DaemonSet":   true,
+	"StatefulSet": true,
+	"ReplicaSet":  true,
+	"CronJob":     true,
+}
+
+// podSpecContainerFields are the fields of a Pod spec which hold containers
+var podSpecContainerFields = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// imageDigestPinner is a mutating webhook which replaces the image tags of workloads with digests,
+// so the scanned image is exactly the image which runs. It never rejects an object.
+type imageDigestPinner struct {
+	validator *webhookValidator
+	images    imagecache.ImageCacher
+	// registries limits pinning to images from these registries. An empty list allows all registries.
+	registries []string
+}
+
+func (p *imageDigestPinner) Handle(ctx context.Context, req admission.Request) admission.Response {
+	a := p.validator
+	resource := fmt.Sprintf("%s/%s", req.Namespace, req.Name)
+
+	update := req.Operation == admissionv1.Update && pinOnUpdateKinds[req.Kind.Kind]
+	if req.Operation != admissionv1.Create && !update {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	if _, enabled, _ := a.namespaceMode(ctx, req.Namespace); !enabled {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	obj, err := a.objFromRaw(req.Object)
+	if err != nil {
+		handlerlog.Error(err, "failed to decode object for image pinning", "resource", resource)
+		return admission.Allowed(defaultScanPass)
+	}
+	if !shouldScanObject(obj) {
+		return admission.Allowed(defaultScanPass)
+	}
+	// objects opted out of scanning are not pinned, the validating webhook counts them
+	if objMeta, err := meta.Accessor(obj); err == nil && k8s.IsScanOptedOut(objMeta) {
+		return admission.Allowed(defaultScanPass)
+	}
+	if skip, err := a.skipNamespace(ctx, obj); err != nil || skip {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	path, ok := podSpecPaths[req.Kind.Kind]
+	if !ok {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	objMapData := make(map[string]interface{})
+	if err := json.Unmarshal(req.Object.Raw, &objMapData); err != nil {
+		handlerlog.Error(err, "failed to unmarshal object for image pinning", "resource", resource)
+		return admission.Allowed(defaultScanPass)
+	}
+
+	podSpec := nestedMap(objMapData, path)
+	if podSpec == nil {
+		return admission.Allowed(defaultScanPass)
+	}
+
+	// On updates only changed images are pinned, so unrelated changes do not roll out a newer digest
+	// of an unchanged tag
+	var oldImages map[string]string
+	if update {
+		oldObjMapData := make(map[string]interface{})
+		if err := json.Unmarshal(req.OldObject.Raw, &oldObjMapData); err != nil {
+			handlerlog.Error(err, "failed to unmarshal old object for image pinning", "resource", resource)
+			return admission.Allowed(defaultScanPass)
+		}
+		oldImages = containerImages(nestedMap(oldObjMapData, path))
+	}
+
+	var warnings []string
+	pinned := 0
+	for _, field := range podSpecContainerFields {
+		containers, _ := podSpec[field].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			image, _ := container["image"].(string)
+			if update {
+				name, _ := container["name"].(string)
+				if oldImage, ok := oldImages[field+"/"+name]; ok && oldImage == image {
+					continue
+				}
+			}
+			digest, err := p.pin(image)
+			if err != nil {
+				handlerlog.Error(err, "failed to pin image to digest", "resource", resource, "image", image)
+				metricsPinnedImagesTotal.WithLabelValues(pinStatusFailed).Inc()
+				warnings = append(warnings, fmt.Sprintf("Mondoo: could not pin image %s to a digest", image))
+				continue
+			}
+			if digest == "" {
+				continue
+			}
+			container["image"] = digest
+			metricsPinnedImagesTotal.WithLabelValues(pinStatusPinned).Inc()
+			pinned++
+		}
+	}
+
+	if pinned == 0 {
+		return admission.Allowed(defaultScanPass).WithWarnings(warnings...)
+	}
+
+	mutated, err := json.Marshal(objMapData)
+	if err != nil {
+		handlerlog.Error(err, "failed to marshal object with pinned images", "resource", resource)
+		return admission.Allowed(defaultScanPass)
+	}
+
+	handlerlog.V(5).Info("pinned images to digests", "kind", req.Kind.Kind, "resource", resource, "count", pinned)
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated).WithWarnings(warnings...)
+}
+
+// pin returns the image with its tag replaced by the digest. It returns an empty string if the
+// image is already pinned or its registry is not allowed.
+func (p *imageDigestPinner) pin(image string) (string, error) {
+	if image == "" || strings.Contains(image, "@") {
+		return "", nil
+	}
+
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	if len(p.registries) > 0 {
+		allowed, err := utils.MatchesAny(ref.Context().RegistryStr(), p.registries)
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			return "", nil
+		}
+	}
+
+	return p.images.GetImage(image)
+}
+
+// containerImages returns the images of the containers in the Pod spec, keyed by the container field
+// and name
+func containerImages(podSpec map[string]interface{}) map[string]string {
+	images := map[string]string{}
+	for _, field := range podSpecContainerFields {
+		containers, _ := podSpec[field].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := container["name"].(string)
+			image, _ := container["image"].(string)
+			images[field+"/"+name] = image
+		}
+	}
+	return images
+}
+
+// nestedMap returns the map at the path or nil if it does not exist
+func nestedMap(obj map[string]interface{}, path []string) map[string]interface{} {
+	current := obj
+	for _, field := range path {
+		next, ok := current[field].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// ImageDigestPinner returns the mutating webhook which pins the images of workloads to digests.
+// Only images from the provided registries are pinned. An empty list allows all registries.
+func (a *webhookValidator) ImageDigestPinner(images imagecache.ImageCacher, registries []string) admission.Handler {
+	return &imageDigestPinner{validator: a, images: images, registries: registries}
+}
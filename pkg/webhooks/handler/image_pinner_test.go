@@ -0,0 +1,213 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+type fakeImageCacher map[string]string
+
+func (f fakeImageCacher) GetImage(image string) (string, error) {
+	digest, ok := f[image]
+	if !ok {
+		return "", fmt.Errorf("image %s not found", image)
+	}
+	return digest, nil
+}
+
+func TestImageDigestPinner(t *testing.T) {
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	images := fakeImageCacher{
+		"ghcr.io/mondoohq/app:1.0":  "ghcr.io/mondoohq/app@sha256:1111",
+		"ghcr.io/mondoohq/init:1.0": "ghcr.io/mondoohq/init@sha256:2222",
+		"nginx:1.25":                "index.docker.io/library/nginx@sha256:3333",
+	}
+	pinner := validator.ImageDigestPinner(images, []string{"ghcr.io"})
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Operation: admissionv1.Create,
+			Object: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.InitContainers = []corev1.Container{{Name: "init", Image: "ghcr.io/mondoohq/init:1.0"}}
+				d.Spec.Template.Spec.Containers = []corev1.Container{
+					{Name: "app", Image: "ghcr.io/mondoohq/app:1.0"},
+					{Name: "pinned", Image: "ghcr.io/mondoohq/sidecar@sha256:4444"},
+					{Name: "nginx", Image: "nginx:1.25"},
+					{Name: "unknown", Image: "ghcr.io/mondoohq/unknown:1.0"},
+				}
+			}),
+		},
+	}
+
+	response := pinner.Handle(context.TODO(), request)
+	require.True(t, response.Allowed)
+
+	patched := map[string]interface{}{}
+	for _, op := range response.Patches {
+		patched[op.Path] = op.Value
+	}
+	assert.Equal(t, map[string]interface{}{
+		"/spec/template/spec/initContainers/0/image": "ghcr.io/mondoohq/init@sha256:2222",
+		"/spec/template/spec/containers/0/image":     "ghcr.io/mondoohq/app@sha256:1111",
+	}, patched)
+	assert.Equal(t, []string{"Mondoo: could not pin image ghcr.io/mondoohq/unknown:1.0 to a digest"}, response.Warnings)
+}
+
+func TestImageDigestPinnerAllRegistries(t *testing.T) {
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	pinner := validator.ImageDigestPinner(fakeImageCacher{"nginx:1.25": "index.docker.io/library/nginx@sha256:3333"}, nil)
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Operation: admissionv1.Create,
+			Object: testExamplePod(func(p *corev1.Pod) {
+				p.Spec.Containers = []corev1.Container{{Name: "nginx", Image: "nginx:1.25"}}
+			}),
+		},
+	}
+
+	response := pinner.Handle(context.TODO(), request)
+	require.True(t, response.Allowed)
+	require.Len(t, response.Patches, 1)
+	assert.Equal(t, "/spec/containers/0/image", response.Patches[0].Path)
+	assert.Equal(t, "index.docker.io/library/nginx@sha256:3333", response.Patches[0].Value)
+}
+
+func TestImageDigestPinnerSkipsUpdates(t *testing.T) {
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	pinner := validator.ImageDigestPinner(fakeImageCacher{"nginx:1.25": "index.docker.io/library/nginx@sha256:3333"}, nil)
+
+	// e.g. a label update of a Pod created before pinning was enabled
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Operation: admissionv1.Update,
+			Object: testExamplePod(func(p *corev1.Pod) {
+				p.Spec.Containers = []corev1.Container{{Name: "nginx", Image: "nginx:1.25"}}
+			}),
+		},
+	}
+
+	response := pinner.Handle(context.TODO(), request)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patches)
+}
+
+func TestImageDigestPinnerSkipsJobUpdates(t *testing.T) {
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	pinner := validator.ImageDigestPinner(fakeImageCacher{"nginx:1.25": "index.docker.io/library/nginx@sha256:3333"}, nil)
+
+	// the Pod template of a Job is immutable
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"},
+			Operation: admissionv1.Update,
+			Object: testExampleJob(func(j *batchv1.Job) {
+				j.Spec.Template.Spec.Containers = []corev1.Container{{Name: "nginx", Image: "nginx:1.25"}}
+			}),
+			OldObject: testExampleJob(),
+		},
+	}
+
+	response := pinner.Handle(context.TODO(), request)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patches)
+}
+
+func TestImageDigestPinnerUpdate(t *testing.T) {
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	images := fakeImageCacher{
+		"ghcr.io/mondoohq/app:2.0": "ghcr.io/mondoohq/app@sha256:5555",
+		"nginx:1.25":               "index.docker.io/library/nginx@sha256:3333",
+	}
+	pinner := validator.ImageDigestPinner(images, nil)
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Operation: admissionv1.Update,
+			Object: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers = []corev1.Container{
+					{Name: "app", Image: "ghcr.io/mondoohq/app:2.0"},
+					{Name: "nginx", Image: "nginx:1.25"},
+				}
+			}),
+			OldObject: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Spec.Template.Spec.Containers = []corev1.Container{
+					{Name: "app", Image: "ghcr.io/mondoohq/app@sha256:1111"},
+					{Name: "nginx", Image: "nginx:1.25"},
+				}
+			}),
+		},
+	}
+
+	// only the changed image is pinned, the unchanged tag keeps running
+	response := pinner.Handle(context.TODO(), request)
+	require.True(t, response.Allowed)
+	require.Len(t, response.Patches, 1)
+	assert.Equal(t, "/spec/template/spec/containers/0/image", response.Patches[0].Path)
+	assert.Equal(t, "ghcr.io/mondoohq/app@sha256:5555", response.Patches[0].Value)
+}
+
+func TestImageDigestPinnerOptedOut(t *testing.T) {
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	pinner := validator.ImageDigestPinner(fakeImageCacher{"nginx:1.25": "index.docker.io/library/nginx@sha256:3333"}, nil)
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Operation: admissionv1.Create,
+			Object: testExamplePod(func(p *corev1.Pod) {
+				p.Labels = map[string]string{"k8s.mondoo.com/scan": "false"}
+				p.Spec.Containers = []corev1.Container{{Name: "nginx", Image: "nginx:1.25"}}
+			}),
+		},
+	}
+
+	response := pinner.Handle(context.TODO(), request)
+	assert.True(t, response.Allowed)
+	assert.Empty(t, response.Patches)
+}
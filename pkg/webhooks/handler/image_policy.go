@@ -0,0 +1,147 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"go.mondoo.com/mondoo-operator/pkg/utils"
+)
+
+// failedImagePolicy is the Denied result when in Enforcing mode and an image violates the image policy
+const failedImagePolicy = "FAILED MONDOO IMAGE POLICY"
+
+// imagePolicy restricts the images of workloads. It is evaluated locally without a scan.
+type imagePolicy struct {
+	// allowedRegistries are glob patterns matched against the registry and the repository of an image.
+	// An empty list allows all registries.
+	allowedRegistries []string
+	// disallowedTags are glob patterns of the tags which are not allowed
+	disallowedTags []string
+	// requireDigest only allows images which reference a digest
+	requireDigest bool
+}
+
+// enabled returns whether any restriction is configured
+func (p imagePolicy) enabled() bool {
+	return len(p.allowedRegistries) > 0 || len(p.disallowedTags) > 0 || p.requireDigest
+}
+
+// violations returns the violations of the images of a workload. Objects without a Pod spec
+// have no violations.
+func (p imagePolicy) violations(kind string, rawObj []byte) ([]string, error) {
+	if !p.enabled() {
+		return nil, nil
+	}
+
+	images, err := podSpecImages(kind, rawObj)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, image := range images {
+		imageViolations, err := p.imageViolations(image)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, imageViolations...)
+	}
+	return violations, nil
+}
+
+// imageViolations checks a single image against the policy
+func (p imagePolicy) imageViolations(image string) ([]string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return []string{fmt.Sprintf("image %s is not a valid image reference", image)}, nil
+	}
+
+	var violations []string
+	if len(p.allowedRegistries) > 0 {
+		repo := ref.Context()
+		allowed, err := utils.MatchesAny(repo.RegistryStr(), p.allowedRegistries)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			if allowed, err = utils.MatchesAny(repo.Name(), p.allowedRegistries); err != nil {
+				return nil, err
+			}
+		}
+		if !allowed {
+			violations = append(violations, fmt.Sprintf("image %s is not from an allowed registry", image))
+		}
+	}
+
+	// digests are immutable, so the tag of a pinned image does not matter
+	if tag, ok := ref.(name.Tag); ok {
+		disallowed, err := utils.MatchesAny(tag.TagStr(), p.disallowedTags)
+		if err != nil {
+			return nil, err
+		}
+		if disallowed {
+			violations = append(violations, fmt.Sprintf("image %s uses the disallowed tag %s", image, tag.TagStr()))
+		}
+		if p.requireDigest {
+			violations = append(violations, fmt.Sprintf("image %s does not reference a digest", image))
+		}
+	}
+	return violations, nil
+}
+
+// podSpecImages returns the images of all containers of a workload
+func podSpecImages(kind string, rawObj []byte) ([]string, error) {
+	path, ok := podSpecPaths[kind]
+	if !ok {
+		return nil, nil
+	}
+
+	objMapData := make(map[string]interface{})
+	if err := json.Unmarshal(rawObj, &objMapData); err != nil {
+		return nil, err
+	}
+
+	podSpec := nestedMap(objMapData, path)
+	if podSpec == nil {
+		return nil, nil
+	}
+
+	var images []string
+	for _, field := range podSpecContainerFields {
+		containers, _ := podSpec[field].([]interface{})
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, _ := container["image"].(string); image != "" {
+				images = append(images, image)
+			}
+		}
+	}
+	return images, nil
+}
+
+// imagePolicyMessage builds the denial message for the image policy violations
+func imagePolicyMessage(violations []string) string {
+	return truncate(failedImagePolicy+": "+strings.Join(violations, "; "), maxMessageLength)
+}
+
+// imagePolicyWarnings builds the admission warnings for image policy violations of an admitted resource
+func imagePolicyWarnings(violations []string) []string {
+	var warnings []string
+	for i, v := range violations {
+		if i == maxWarnings-1 && len(violations) > maxWarnings {
+			warnings = append(warnings, fmt.Sprintf("Mondoo: %d more image policy violations", len(violations)-i))
+			break
+		}
+		warnings = append(warnings, truncate("Mondoo: "+v, maxWarningLength))
+	}
+	return warnings
+}
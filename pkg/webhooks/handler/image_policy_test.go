@@ -0,0 +1,143 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/mock"
+)
+
+const testDigest = "3b4e6d1c0f8a9b2c7d5e4f6a1b0c9d8e7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c"
+
+func TestImagePolicyViolations(t *testing.T) {
+	policy := imagePolicy{
+		allowedRegistries: []string{"registry.example.com", "ghcr.io/mondoohq/*"},
+		disallowedTags:    []string{"latest", "*-dev"},
+	}
+
+	tests := []struct {
+		image      string
+		violations []string
+	}{
+		{image: "registry.example.com/app:1.0"},
+		{image: "ghcr.io/mondoohq/client:1.0"},
+		{image: "registry.example.com/app@sha256:" + testDigest},
+		{
+			image:      "ghcr.io/other/client:1.0",
+			violations: []string{"image ghcr.io/other/client:1.0 is not from an allowed registry"},
+		},
+		{
+			image: "nginx",
+			violations: []string{
+				"image nginx is not from an allowed registry",
+				"image nginx uses the disallowed tag latest",
+			},
+		},
+		{
+			image:      "registry.example.com/app:1.0-dev",
+			violations: []string{"image registry.example.com/app:1.0-dev uses the disallowed tag 1.0-dev"},
+		},
+		{
+			image:      "registry.example.com/app:INVALID TAG",
+			violations: []string{"image registry.example.com/app:INVALID TAG is not a valid image reference"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			violations, err := policy.imageViolations(test.image)
+			require.NoError(t, err)
+			assert.Equal(t, test.violations, violations)
+		})
+	}
+}
+
+func TestImagePolicyRequireDigest(t *testing.T) {
+	policy := imagePolicy{requireDigest: true}
+
+	violations, err := policy.imageViolations("registry.example.com/app:1.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"image registry.example.com/app:1.0 does not reference a digest"}, violations)
+
+	violations, err = policy.imageViolations("registry.example.com/app:1.0@sha256:" + testDigest)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestWebhookImagePolicy(t *testing.T) {
+	deployment := testExampleDeployment(func(d *appsv1.Deployment) {
+		d.Spec.Template.Spec.InitContainers = []corev1.Container{{Name: "init", Image: "registry.example.com/init:1.0"}}
+		d.Spec.Template.Spec.Containers = []corev1.Container{{Name: "app", Image: "nginx:latest"}}
+	})
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Object: deployment,
+		},
+	}
+	policy := imagePolicy{allowedRegistries: []string{"registry.example.com"}}
+
+	t.Run("enforcing denies without a scan", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		// the scan API must not be called
+		scanner := mock.NewMockScanApiClient(mockCtrl)
+		validator := &webhookValidator{
+			decoder:     setupDecoder(t),
+			mode:        mondoov1alpha2.Enforcing,
+			scanner:     scanner,
+			uniDecoder:  serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+			imagePolicy: policy,
+		}
+
+		response := validator.Handle(context.TODO(), request)
+		assert.False(t, response.AdmissionResponse.Allowed)
+		assert.Equal(t, failedImagePolicy+": image nginx:latest is not from an allowed registry", response.AdmissionResponse.Result.Message)
+	})
+
+	t.Run("audit reports violations if the scan API is unavailable", func(t *testing.T) {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		scanner := mock.NewMockScanApiClient(mockCtrl)
+		scanner.EXPECT().RunAdmissionReview(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("scan API unavailable"))
+
+		recorder := record.NewFakeRecorder(10)
+		validator := &webhookValidator{
+			decoder:     setupDecoder(t),
+			mode:        mondoov1alpha2.Audit,
+			scanner:     scanner,
+			uniDecoder:  serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+			recorder:    recorder,
+			imagePolicy: policy,
+		}
+
+		response := validator.Handle(context.TODO(), request)
+		assert.True(t, response.AdmissionResponse.Allowed)
+		assert.Equal(t, failedScanAudited, response.AdmissionResponse.Result.Message)
+		assert.Equal(t, []string{
+			"Mondoo enforcing mode would deny this resource",
+			"Mondoo: image nginx:latest is not from an allowed registry",
+		}, response.AdmissionResponse.Warnings)
+		require.Len(t, recorder.Events, 1)
+		assert.Equal(t, "Warning MondooWouldDeny Mondoo enforcing mode would deny the resource: "+failedImagePolicy+": image nginx:latest is not from an allowed registry", <-recorder.Events)
+	})
+}
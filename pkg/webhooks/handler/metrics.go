@@ -0,0 +1,149 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	decisionAllowed = "allowed"
+	decisionDenied  = "denied"
+	decisionErrored = "errored"
+	decisionSkipped = "skipped"
+	// decisionWouldDeny is an admitted request which enforcing mode would have denied
+	decisionWouldDeny = "would-deny"
+	// decisionQueued is a request admitted in async mode which is scanned in the background
+	decisionQueued = "queued"
+
+	pinStatusPinned = "pinned"
+	pinStatusFailed = "failed"
+
+	rejectedCircuitOpen      = "circuit-open"
+	rejectedConcurrencyLimit = "concurrency-limit"
+
+	asyncScanPassed  = "passed"
+	asyncScanFailed  = "failed"
+	asyncScanErrored = "errored"
+	asyncScanDropped = "dropped"
+)
+
+var metricsDecisionsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_decisions_total",
+		Help: "Number of admission decisions by kind, namespace, effective mode and decision (allowed, denied, would-deny, queued, errored, skipped)",
+	},
+	[]string{"kind", "namespace", "mode", "decision"},
+)
+
+var metricsScanDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "mondoo_admission_scan_duration_seconds",
+		Help:    "Latency of the scan API calls from the admission webhook",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 20, 30},
+	},
+	[]string{"status"},
+)
+
+var metricsScanApiHealthy = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "mondoo_admission_scan_api_healthy",
+		Help: "Whether the last health check of the scan API succeeded (1) or failed (0)",
+	},
+)
+
+var metricsBreakGlassTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_break_glass_total",
+		Help: "Number of admission requests which bypassed enforcement with the break-glass annotation",
+	},
+	[]string{"kind", "namespace", "user"},
+)
+
+var metricsCacheHitsTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_cache_hits_total",
+		Help: "Number of admission requests answered from the scan result cache",
+	},
+)
+
+var metricsCacheMissesTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_cache_misses_total",
+		Help: "Number of admission requests not found in the scan result cache",
+	},
+)
+
+var metricsPinnedImagesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_pinned_images_total",
+		Help: "Number of container images the admission webhook pinned to a digest (pinned) or failed to resolve (failed)",
+	},
+	[]string{"status"},
+)
+
+var metricsScansInFlight = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "mondoo_admission_scans_in_flight",
+		Help: "Number of scan API calls from the admission webhook which are in progress",
+	},
+)
+
+var metricsScansRejectedTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_scans_rejected_total",
+		Help: "Number of scans which were not attempted because the circuit breaker was open (circuit-open) or too many scans were in progress (concurrency-limit)",
+	},
+	[]string{"reason"},
+)
+
+var metricsCircuitBreakerState = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "mondoo_admission_circuit_breaker_state",
+		Help: "State of the scan API circuit breaker: closed (0), half-open (1) or open (2)",
+	},
+)
+
+var metricsAsyncScansTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_async_scans_total",
+		Help: "Number of background scans in async mode by status (passed, failed, errored, dropped because the queue was full)",
+	},
+	[]string{"status"},
+)
+
+var metricsConnectRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_connect_requests_total",
+		Help: "Number of exec, attach and port-forward requests to Pods by subresource, namespace and decision (allowed, denied)",
+	},
+	[]string{"subresource", "namespace", "decision"},
+)
+
+var metricsConnectReportsDroppedTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "mondoo_admission_connect_reports_dropped_total",
+		Help: "Number of exec, attach and port-forward requests to Pods neither recorded as Events nor reported to Mondoo because the report queue was full",
+	},
+)
+
+func init() {
+	// Register custom metrics with the global prometheus registry
+	metrics.Registry.MustRegister(
+		metricsDecisionsTotal,
+		metricsScanDuration,
+		metricsScanApiHealthy,
+		metricsBreakGlassTotal,
+		metricsCacheHitsTotal,
+		metricsCacheMissesTotal,
+		metricsPinnedImagesTotal,
+		metricsScansInFlight,
+		metricsScansRejectedTotal,
+		metricsCircuitBreakerState,
+		metricsAsyncScansTotal,
+		metricsConnectRequestsTotal,
+		metricsConnectReportsDroppedTotal,
+	)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/fakeserver"
+)
+
+func TestWebhookDecisionMetrics(t *testing.T) {
+	testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok:         true,
+		WorstScore: &scanapiclient.Score{Type: scanapiclient.ValidScanResult, Value: 20},
+	})
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	validator := &webhookValidator{
+		decoder:    setupDecoder(t),
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    clnt,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+
+	denied := metricsDecisionsTotal.WithLabelValues("Deployment", testNamespace, string(mondoov1alpha2.Enforcing), decisionDenied)
+	skipped := metricsDecisionsTotal.WithLabelValues("Pod", testNamespace, string(mondoov1alpha2.Enforcing), decisionSkipped)
+	deniedBefore := testutil.ToFloat64(denied)
+	skippedBefore := testutil.ToFloat64(skipped)
+
+	validator.Handle(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Namespace: testNamespace,
+			Object:    testExampleDeployment(),
+		},
+	})
+	validator.Handle(context.TODO(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			Namespace: testNamespace,
+			Object: testExamplePod(func(p *corev1.Pod) {
+				p.OwnerReferences = append(p.OwnerReferences, metav1.OwnerReference{
+					Kind:       "ReplicaSet",
+					Name:       "testReplicaSet",
+					UID:        types.UID("abcd-1234"),
+					Controller: ptr.To(true),
+				})
+			}),
+		},
+	})
+
+	assert.Equal(t, deniedBefore+1, testutil.ToFloat64(denied))
+	assert.Equal(t, skippedBefore+1, testutil.ToFloat64(skipped))
+	assert.Positive(t, testutil.CollectAndCount(metricsScanDuration))
+}
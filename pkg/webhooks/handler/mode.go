@@ -0,0 +1,58 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	wutils "go.mondoo.com/mondoo-operator/pkg/webhooks/utils"
+)
+
+const (
+	// mondooAdmissionModeKey is the Namespace label (or annotation) used to override the
+	// admission mode for all resources within that Namespace.
+	mondooAdmissionModeKey = mondooLabelPrefix + "admission-mode"
+	// admissionModeDisabled is the override value which turns off admission for a Namespace.
+	admissionModeDisabled = "disabled"
+)
+
+// namespaceMode looks up the admission mode override for the provided Namespace. It returns
+// the globally configured mode if the Namespace has no override or cannot be fetched. The
+// second return value is false if admission is disabled for the Namespace. The third return
+// value is true if the Namespace overrides the mode.
+func (a *webhookValidator) namespaceMode(ctx context.Context, namespace string) (mondoov1alpha2.AdmissionMode, bool, bool) {
+	if namespace == "" || a.client == nil {
+		return a.mode, true, false
+	}
+
+	ns := &corev1.Namespace{}
+	if err := a.client.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		handlerlog.Error(err, "failed to get namespace for admission mode override, using the global mode", "namespace", namespace)
+		return a.mode, true, false
+	}
+
+	// labels take precedence over annotations
+	override, ok := ns.Labels[mondooAdmissionModeKey]
+	if !ok {
+		override, ok = ns.Annotations[mondooAdmissionModeKey]
+	}
+	if !ok {
+		return a.mode, true, false
+	}
+
+	if override == admissionModeDisabled {
+		return a.mode, false, true
+	}
+
+	mode, err := wutils.ModeStringToAdmissionMode(override)
+	if err != nil {
+		handlerlog.Error(err, "invalid admission mode override on namespace, using the global mode", "namespace", namespace)
+		return a.mode, true, false
+	}
+	return mode, true, true
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	wutils "go.mondoo.com/mondoo-operator/pkg/webhooks/utils"
+)
+
+// admissionPolicy holds the criteria a scan result has to meet for a resource to be admitted.
+type admissionPolicy struct {
+	// scoreThreshold is the minimum score needed. A nil value means no threshold is configured.
+	scoreThreshold *uint32
+	// maxSeverity is the highest severity which is still admitted. An empty value means no
+	// maximum severity is configured.
+	maxSeverity mondoov1alpha2.AdmissionSeverity
+}
+
+// passed evaluates the scan result against the policy. If neither a score threshold nor a
+// maximum severity is configured, only a perfect score of 100 passes. If both are configured,
+// both have to be met.
+func (p admissionPolicy) passed(result *scanapiclient.ScanResult) bool {
+	if result == nil || result.WorstScore == nil || result.WorstScore.Type != scanapiclient.ValidScanResult {
+		return false
+	}
+	score := result.WorstScore.Value
+
+	if p.scoreThreshold == nil && p.maxSeverity == "" {
+		return score == 100
+	}
+
+	if p.scoreThreshold != nil && score < *p.scoreThreshold {
+		return false
+	}
+
+	if p.maxSeverity != "" && wutils.SeverityExceeds(wutils.SeverityFromScore(score), p.maxSeverity) {
+		return false
+	}
+
+	return true
+}
+
+// version returns a fingerprint of the policy and the operator version. It changes whenever the
+// evaluation of a scan result might change.
+func (p admissionPolicy) version(operatorVersion string) string {
+	threshold := "none"
+	if p.scoreThreshold != nil {
+		threshold = fmt.Sprintf("%d", *p.scoreThreshold)
+	}
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s", operatorVersion, threshold, p.maxSeverity)))
+	return hex.EncodeToString(h[:])[:12]
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+)
+
+const (
+	// maxMessageLength limits the length of the denial message. The API server passes the message
+	// on to the client as part of the error, so we keep it short enough to stay readable.
+	maxMessageLength = 1024
+	// maxWarnings is the number of warnings returned to the client. The API server truncates
+	// warnings beyond 4096 characters in total.
+	maxWarnings = 10
+	// maxWarningLength is the length of a single warning. Longer warnings are truncated by kubectl.
+	maxWarningLength = 120
+)
+
+// failingChecks returns the checks which did not pass, sorted by score with the worst first.
+func failingChecks(result *scanapiclient.ScanResult) []*scanapiclient.CheckResult {
+	if result == nil {
+		return nil
+	}
+
+	var failing []*scanapiclient.CheckResult
+	for _, c := range result.Checks() {
+		if c == nil || c.Score == nil || c.Score.Type != scanapiclient.ValidScanResult || c.Score.Value >= 100 {
+			continue
+		}
+		failing = append(failing, c)
+	}
+
+	sort.SliceStable(failing, func(i, j int) bool {
+		return failing[i].Score.Value < failing[j].Score.Value
+	})
+	return failing
+}
+
+func checkDescription(c *scanapiclient.CheckResult) string {
+	title := c.Title
+	if title == "" {
+		title = c.Mrn
+	}
+	return fmt.Sprintf("%s (score %d)", title, c.Score.Value)
+}
+
+// denialMessage builds the message for a denied resource listing the failing checks. Checks which
+// don't fit into maxMessageLength are summarized.
+func denialMessage(result *scanapiclient.ScanResult) string {
+	checks := failingChecks(result)
+	if len(checks) == 0 {
+		return failedScan
+	}
+
+	msg := failedScan + ": "
+	for i, c := range checks {
+		desc := checkDescription(c)
+		if i == 0 {
+			// the first check is always listed, shortened if it alone exceeds the limit
+			msg += truncate(desc, maxMessageLength-len(msg)-len(moreChecks(len(checks)-1)))
+			continue
+		}
+		desc = "; " + desc
+		remaining := moreChecks(len(checks) - i)
+		if len(msg)+len(desc)+len(remaining) > maxMessageLength {
+			return msg + remaining
+		}
+		msg += desc
+	}
+	return msg
+}
+
+// moreChecks is the suffix of the denial message for the n failing checks which are not listed
+func moreChecks(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf("; and %d more", n)
+}
+
+// scanWarnings builds the admission warnings for a resource which failed the scan, but was admitted.
+func scanWarnings(result *scanapiclient.ScanResult) []string {
+	checks := failingChecks(result)
+
+	var warnings []string
+	for i, c := range checks {
+		if i == maxWarnings-1 && len(checks) > maxWarnings {
+			warnings = append(warnings, fmt.Sprintf("Mondoo: %d more failing checks", len(checks)-i))
+			break
+		}
+		warnings = append(warnings, truncate("Mondoo: failing check "+checkDescription(c), maxWarningLength))
+	}
+	return warnings
+}
+
+// truncate shortens s to at most length bytes without splitting a multi-byte character
+func truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	cut := length - 3
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(s[:cut]) + "..."
+}
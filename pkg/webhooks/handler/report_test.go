@@ -0,0 +1,124 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+)
+
+func testCheck(title string, score uint32) *scanapiclient.CheckResult {
+	return &scanapiclient.CheckResult{
+		Mrn:   "//policy.api.mondoo.app/queries/" + title,
+		Title: title,
+		Score: &scanapiclient.Score{Type: scanapiclient.ValidScanResult, Value: score},
+	}
+}
+
+// testFullReport returns a full report of a single asset holding the checks
+func testFullReport(checks ...*scanapiclient.CheckResult) *scanapiclient.ReportCollection {
+	assetMrn := "//assets.api.mondoo.app/spaces/test/assets/test"
+	report := &scanapiclient.Report{ScoringMrn: assetMrn, EntityMrn: assetMrn, Scores: map[string]*scanapiclient.Score{}}
+	bundle := &scanapiclient.Bundle{}
+	for _, c := range checks {
+		bundle.Queries = append(bundle.Queries, &scanapiclient.Query{Mrn: c.Mrn, Title: c.Title})
+		report.Scores[c.Mrn] = c.Score
+	}
+	return &scanapiclient.ReportCollection{Bundle: bundle, Reports: map[string]*scanapiclient.Report{assetMrn: report}}
+}
+
+func TestDenialMessage(t *testing.T) {
+	result := &scanapiclient.ScanResult{
+		Full: testFullReport(
+			testCheck("Container should not run as root", 20),
+			testCheck("Passing check", 100),
+			testCheck("Container should have a memory limit", 60),
+			testCheck("Container should not be privileged", 0),
+		),
+	}
+
+	assert.Equal(t,
+		failedScan+": Container should not be privileged (score 0); Container should not run as root (score 20); Container should have a memory limit (score 60)",
+		denialMessage(result))
+}
+
+func TestDenialMessage_FullReport(t *testing.T) {
+	data, err := os.ReadFile("../../../tests/data/admission-review-full-result.json")
+	require.NoError(t, err)
+	result := &scanapiclient.ScanResult{}
+	require.NoError(t, json.Unmarshal(data, result))
+
+	assert.Equal(t,
+		failedScan+": Container should not run as root (score 20); Container should have a memory limit (score 60)",
+		denialMessage(result))
+}
+
+func TestDenialMessage_NoChecks(t *testing.T) {
+	assert.Equal(t, failedScan, denialMessage(&scanapiclient.ScanResult{}))
+}
+
+func TestDenialMessage_Truncated(t *testing.T) {
+	var checks []*scanapiclient.CheckResult
+	for i := 0; i < 100; i++ {
+		checks = append(checks, testCheck(fmt.Sprintf("Failing check number %d", i), 10))
+	}
+	result := &scanapiclient.ScanResult{Full: testFullReport(checks...)}
+
+	msg := denialMessage(result)
+	assert.LessOrEqual(t, len(msg), maxMessageLength)
+	assert.True(t, strings.HasPrefix(msg, failedScan+": Failing check number 0 (score 10)"))
+	assert.Regexp(t, `; and \d+ more$`, msg)
+}
+
+func TestDenialMessage_LongFirstCheck(t *testing.T) {
+	result := &scanapiclient.ScanResult{Full: testFullReport(
+		testCheck(strings.Repeat("Container should not run as root ", 50), 0),
+		testCheck("Container should have a memory limit", 60),
+	)}
+
+	msg := denialMessage(result)
+	assert.LessOrEqual(t, len(msg), maxMessageLength)
+	assert.True(t, strings.HasPrefix(msg, failedScan+": Container should not run as root"))
+	assert.True(t, strings.HasSuffix(msg, "...; and 1 more"))
+
+	// a single check is shortened without a suffix
+	result = &scanapiclient.ScanResult{Full: testFullReport(testCheck(strings.Repeat("x", 1000), 0))}
+	msg = denialMessage(result)
+	assert.LessOrEqual(t, len(msg), maxMessageLength)
+	assert.True(t, strings.HasSuffix(msg, "..."))
+}
+
+func TestScanWarnings(t *testing.T) {
+	var checks []*scanapiclient.CheckResult
+	for i := 0; i < 20; i++ {
+		checks = append(checks, testCheck(fmt.Sprintf("%d %s", i, strings.Repeat("x", 200)), 10))
+	}
+	result := &scanapiclient.ScanResult{Full: testFullReport(checks...)}
+
+	warnings := scanWarnings(result)
+	assert.Len(t, warnings, maxWarnings)
+	for _, w := range warnings {
+		assert.LessOrEqual(t, len(w), maxWarningLength)
+	}
+	assert.Equal(t, "Mondoo: 11 more failing checks", warnings[maxWarnings-1])
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "abcdefg...", truncate("abcdefghijklmnop", 10))
+
+	// "ü" takes two bytes, the cut must not split it
+	truncated := truncate("abcdefüghijklmnop", 10)
+	assert.Equal(t, "abcdef...", truncated)
+	assert.True(t, utf8.ValidString(truncated))
+}
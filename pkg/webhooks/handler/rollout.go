@@ -0,0 +1,108 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/utils"
+	wutils "go.mondoo.com/mondoo-operator/pkg/webhooks/utils"
+)
+
+// enforcementRollout limits enforcing mode to a share of the admission requests
+type enforcementRollout struct {
+	// percentage of the requests which are enforced. A nil value means only the canary namespaces are enforced.
+	percentage *uint32
+	// canaryNamespaces are always enforced
+	canaryNamespaces []string
+	// shadowMode is the mode for the requests which are not enforced
+	shadowMode mondoov1alpha2.AdmissionMode
+}
+
+// enabled returns whether a rollout is configured
+func (r enforcementRollout) enabled() bool {
+	return r.percentage != nil || len(r.canaryNamespaces) > 0
+}
+
+// mode returns the effective mode for the object. Objects in a canary namespace and objects whose
+// hash falls within the percentage are enforced, all others are handled in the shadow mode.
+func (r enforcementRollout) mode(kind string, obj runtime.Object) (mondoov1alpha2.AdmissionMode, error) {
+	if !r.enabled() {
+		return mondoov1alpha2.Enforcing, nil
+	}
+
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return r.shadowMode, err
+	}
+
+	if objMeta.GetNamespace() != "" {
+		canary, err := utils.MatchesAny(objMeta.GetNamespace(), r.canaryNamespaces)
+		if err != nil {
+			return r.shadowMode, err
+		}
+		if canary {
+			return mondoov1alpha2.Enforcing, nil
+		}
+	}
+
+	if r.percentage != nil && rolloutBucket(kind, objMeta) < *r.percentage {
+		return mondoov1alpha2.Enforcing, nil
+	}
+	return r.shadowMode, nil
+}
+
+// rolloutBucket deterministically maps an object to a bucket between 0 and 99. The object is
+// identified by kind, namespace and name, because new objects do not have a UID yet. Objects
+// created with a generateName have no name at CREATE, so they are identified by the generateName
+// and the UID of their controller instead. Objects without a controller share the bucket of their
+// generateName.
+func rolloutBucket(kind string, objMeta metav1.Object) uint32 {
+	name := objMeta.GetName()
+	if name == "" {
+		name = objMeta.GetGenerateName()
+		if owner := metav1.GetControllerOfNoCopy(objMeta); owner != nil {
+			name = fmt.Sprintf("%s%s", name, owner.UID)
+		}
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(fmt.Sprintf("%s/%s/%s", kind, objMeta.GetNamespace(), name)))
+	return h.Sum32() % 100
+}
+
+// newEnforcementRollout creates the rollout from the webhook options. A negative percentage
+// disables the percentage rollout.
+func newEnforcementRollout(percentage int, canaryNamespaces []string, shadowMode string) (enforcementRollout, error) {
+	if percentage > 100 {
+		return enforcementRollout{}, fmt.Errorf("rollout percentage %d is not valid, must be between 0 and 100", percentage)
+	}
+
+	rollout := enforcementRollout{
+		canaryNamespaces: canaryNamespaces,
+		shadowMode:       mondoov1alpha2.Audit,
+	}
+	if percentage >= 0 {
+		p := uint32(percentage)
+		rollout.percentage = &p
+	}
+
+	if shadowMode != "" {
+		mode, err := wutils.ModeStringToAdmissionMode(shadowMode)
+		if err != nil {
+			return enforcementRollout{}, err
+		}
+		if mode != mondoov1alpha2.Audit && mode != mondoov1alpha2.Permissive {
+			return enforcementRollout{}, fmt.Errorf("rollout shadow mode must be %s or %s", mondoov1alpha2.Audit, mondoov1alpha2.Permissive)
+		}
+		rollout.shadowMode = mode
+	}
+	return rollout, nil
+}
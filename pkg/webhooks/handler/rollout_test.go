@@ -0,0 +1,176 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package webhookhandler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
+	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient/fakeserver"
+)
+
+func TestEnforcementRolloutMode(t *testing.T) {
+	deployment := func(namespace, name string) *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	}
+
+	canary, err := newEnforcementRollout(-1, []string{"team-a-*"}, "")
+	require.NoError(t, err)
+	mode, err := canary.mode("Deployment", deployment("team-a-prod", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, mondoov1alpha2.Enforcing, mode)
+	mode, err = canary.mode("Deployment", deployment("team-b", "app"))
+	require.NoError(t, err)
+	assert.Equal(t, mondoov1alpha2.Audit, mode)
+
+	none, err := newEnforcementRollout(0, nil, string(mondoov1alpha2.Permissive))
+	require.NoError(t, err)
+	all, err := newEnforcementRollout(100, nil, "")
+	require.NoError(t, err)
+	half, err := newEnforcementRollout(50, nil, "")
+	require.NoError(t, err)
+
+	enforced := 0
+	for i := 0; i < 1000; i++ {
+		obj := deployment("default", fmt.Sprintf("app-%d", i))
+
+		mode, err := none.mode("Deployment", obj)
+		require.NoError(t, err)
+		assert.Equal(t, mondoov1alpha2.Permissive, mode)
+
+		mode, err = all.mode("Deployment", obj)
+		require.NoError(t, err)
+		assert.Equal(t, mondoov1alpha2.Enforcing, mode)
+
+		mode, err = half.mode("Deployment", obj)
+		require.NoError(t, err)
+		again, err := half.mode("Deployment", obj)
+		require.NoError(t, err)
+		assert.Equal(t, mode, again, "the rollout must be deterministic")
+		if mode == mondoov1alpha2.Enforcing {
+			enforced++
+		}
+	}
+	assert.InDelta(t, 500, enforced, 75)
+}
+
+func TestRolloutBucket_CreateAndUpdate(t *testing.T) {
+	// at CREATE the object does not have a UID yet
+	created := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	updated := created.DeepCopy()
+	updated.UID = types.UID("3f2b9a8e-5c1d-4e7f-9a0b-1c2d3e4f5a6b")
+
+	for i := 0; i < 100; i++ {
+		created.Name = fmt.Sprintf("app-%d", i)
+		updated.Name = created.Name
+		assert.Equal(t, rolloutBucket("Deployment", created), rolloutBucket("Deployment", updated))
+	}
+}
+
+func TestRolloutBucket_GenerateName(t *testing.T) {
+	owner := func(uid string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app", UID: types.UID(uid), Controller: ptr.To(true)}}
+	}
+
+	buckets := map[uint32]struct{}{}
+	for i := 0; i < 100; i++ {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    "app-",
+			Namespace:       "default",
+			OwnerReferences: owner(fmt.Sprintf("3f2b9a8e-5c1d-4e7f-9a0b-%012d", i)),
+		}}
+		buckets[rolloutBucket("Pod", pod)] = struct{}{}
+	}
+	// objects without a name are spread by the UID of their controller
+	assert.Greater(t, len(buckets), 1)
+
+	named := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "app-", Name: "app-x7k2p", Namespace: "default"}}
+	assert.Equal(t, rolloutBucket("Pod", &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-x7k2p", Namespace: "default"}}), rolloutBucket("Pod", named))
+}
+
+func TestNewEnforcementRollout(t *testing.T) {
+	rollout, err := newEnforcementRollout(-1, nil, "")
+	require.NoError(t, err)
+	assert.False(t, rollout.enabled())
+
+	_, err = newEnforcementRollout(101, nil, "")
+	assert.Error(t, err)
+
+	_, err = newEnforcementRollout(10, nil, string(mondoov1alpha2.Enforcing))
+	assert.Error(t, err)
+
+	_, err = newEnforcementRollout(10, nil, string(mondoov1alpha2.Async))
+	assert.Error(t, err)
+
+	_, err = newEnforcementRollout(10, nil, "invalid")
+	assert.Error(t, err)
+}
+
+func TestWebhookEnforcementRollout(t *testing.T) {
+	decoder := setupDecoder(t)
+	testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok: true,
+		WorstScore: &scanapiclient.Score{
+			Type:  scanapiclient.ValidScanResult,
+			Value: 20,
+		},
+	})
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	rollout, err := newEnforcementRollout(-1, []string{"canary"}, "")
+	require.NoError(t, err)
+	validator := &webhookValidator{
+		decoder:    decoder,
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    clnt,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		rollout:    rollout,
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Namespace: testNamespace,
+			Object:    testExampleDeployment(),
+		},
+	}
+	shadowed := metricsDecisionsTotal.WithLabelValues("Deployment", testNamespace, string(mondoov1alpha2.Audit), decisionWouldDeny)
+	before := testutil.ToFloat64(shadowed)
+
+	response := validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScanAudited, response.AdmissionResponse.Result.Message)
+	assert.Equal(t, before+1, testutil.ToFloat64(shadowed))
+
+	request.Namespace = "canary"
+	request.Object = testExampleDeployment(func(d *appsv1.Deployment) {
+		d.Namespace = "canary"
+	})
+	denied := metricsDecisionsTotal.WithLabelValues("Deployment", "canary", string(mondoov1alpha2.Enforcing), decisionDenied)
+	before = testutil.ToFloat64(denied)
+
+	response = validator.Handle(context.TODO(), request)
+	assert.False(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, before+1, testutil.ToFloat64(denied))
+}
@@ -8,17 +8,24 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"slices"
+	"time"
 
 	"google.golang.org/protobuf/types/known/structpb"
 	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/yaml"
 
@@ -30,7 +37,10 @@ import (
 	"go.mondoo.com/mondoo-operator/pkg/client/scanapiclient"
 	"go.mondoo.com/mondoo-operator/pkg/constants"
 	"go.mondoo.com/mondoo-operator/pkg/feature_flags"
+	"go.mondoo.com/mondoo-operator/pkg/imagecache"
 	"go.mondoo.com/mondoo-operator/pkg/utils"
+	"go.mondoo.com/mondoo-operator/pkg/utils/k8s"
+	"go.mondoo.com/mondoo-operator/pkg/version"
 	wutils "go.mondoo.com/mondoo-operator/pkg/webhooks/utils"
 )
 
@@ -50,23 +60,56 @@ const (
 	failedScanPermitted = "PERMITTING FAILED SCAN"
 	// failedScan is the Denied result when in Enforcing mode and the scan result was a failing result
 	failedScan = "FAILED MONDOO SCAN"
-
-	mondooLabelPrefix          = "k8s.mondoo.com/"
-	mondooNamespaceLabel       = mondooLabelPrefix + "namespace"
-	mondooUIDLabel             = mondooLabelPrefix + "uid"
-	mondooResourceVersionLabel = mondooLabelPrefix + "resource-version"
-	mondooNameLabel            = mondooLabelPrefix + "name"
-	mondooKindLabel            = mondooLabelPrefix + "kind"
-	mondooOwnerNameLabel       = mondooLabelPrefix + "owner-name"
-	mondooOwnerKindLabel       = mondooLabelPrefix + "owner-kind"
-	mondooOwnerUIDLabel        = mondooLabelPrefix + "owner-uid"
-	mondooAuthorLabel          = mondooLabelPrefix + "author"
-	mondooOperationLabel       = mondooLabelPrefix + "operation"
-	mondooClusterIDLabel       = mondooLabelPrefix + "cluster-id"
+	// failedScanExempted is the Allowed result when in Enforcing mode, the scan result was a failing result,
+	// but the requester is exempted from enforcement
+	failedScanExempted = "PERMITTING FAILED SCAN FOR EXEMPTED REQUESTER"
+	// failedScanBreakGlass is the Allowed result when in Enforcing mode, the scan result was a failing result,
+	// but the object carries the break-glass annotation
+	failedScanBreakGlass = "PERMITTING FAILED SCAN WITH BREAK-GLASS"
+	// failedScanAudited is the Allowed result when in Audit mode and the scan result was a failing result
+	failedScanAudited = "PERMITTING FAILED SCAN IN AUDIT MODE"
+
+	mondooLabelPrefix           = "k8s.mondoo.com/"
+	mondooNamespaceLabel        = mondooLabelPrefix + "namespace"
+	mondooUIDLabel              = mondooLabelPrefix + "uid"
+	mondooResourceVersionLabel  = mondooLabelPrefix + "resource-version"
+	mondooNameLabel             = mondooLabelPrefix + "name"
+	mondooKindLabel             = mondooLabelPrefix + "kind"
+	mondooOwnerNameLabel        = mondooLabelPrefix + "owner-name"
+	mondooOwnerKindLabel        = mondooLabelPrefix + "owner-kind"
+	mondooOwnerUIDLabel         = mondooLabelPrefix + "owner-uid"
+	mondooAuthorLabel           = mondooLabelPrefix + "author"
+	mondooOperationLabel        = mondooLabelPrefix + "operation"
+	mondooClusterIDLabel        = mondooLabelPrefix + "cluster-id"
+	mondooExemptedLabel         = mondooLabelPrefix + "exempted"
+	mondooBreakGlassReasonLabel = mondooLabelPrefix + "break-glass-reason"
+	mondooBreakGlassUserLabel   = mondooLabelPrefix + "break-glass-user"
+
+	// mondooBreakGlassAnnotation allows admitting an object in enforcing mode even though it fails
+	// the scan. The value is the reason for the bypass.
+	mondooBreakGlassAnnotation = mondooLabelPrefix + "break-glass"
+	breakGlassEventReason      = "MondooBreakGlass"
+	scanFailedEventReason      = "MondooScanFailed"
+	wouldDenyEventReason       = "MondooWouldDeny"
 )
 
+// resourceDiscoveryTargets maps resources to their dedicated discovery targets
+var resourceDiscoveryTargets = map[string]string{
+	"pods":         "pods",
+	"deployments":  "deployments",
+	"daemonsets":   "daemonsets",
+	"statefulsets": "statefulsets",
+	"replicasets":  "replicasets",
+	"jobs":         "jobs",
+	"cronjobs":     "cronjobs",
+	"ingresses":    "ingresses",
+	"services":     "services",
+	"namespaces":   "namespaces",
+}
+
 type webhookValidator struct {
 	client            client.Client
+	apiReader         client.Reader
 	decoder           *admission.Decoder
 	mode              mondoov1alpha2.AdmissionMode
 	scanner           scanapiclient.ScanApiClient
@@ -75,10 +118,31 @@ type webhookValidator struct {
 	uniDecoder        runtime.Decoder
 	includeNamespaces []string
 	excludeNamespaces []string
+	namespaceSelector labels.Selector
+	policy            admissionPolicy
+	exemptions        exemptions
+	recorder          record.EventRecorder
+	cache             *scanResultCache
+	policyVersion     string
+	failurePolicy     mondoov1alpha2.AdmissionFailurePolicy
+	scanTimeout       time.Duration
+	auditReporter     *AuditReporter
+	rollout           enforcementRollout
+	imagePolicy       imagePolicy
+	breaker           *circuitBreaker
+	limiter           *concurrencyLimiter
+	asyncScanner      *asyncScanner
+	connectReporter   *connectReporter
 }
 
 type NewWebhookValidatorOpts struct {
-	Client            client.Client
+	// Client is used to look up namespace mode overrides and to report async scan results. Without
+	// a Client, e.g. when testing payloads offline, the configured Mode applies to all namespaces.
+	Client client.Client
+	// APIReader reads the objects which the webhook is not allowed to watch, like Pods, without the
+	// cache. Defaults to the Client.
+	APIReader         client.Reader
+	Recorder          record.EventRecorder
 	Mode              string
 	ScanUrl           string
 	Token             string
@@ -86,11 +150,70 @@ type NewWebhookValidatorOpts struct {
 	ClusterId         string
 	IncludeNamespaces []string
 	ExcludeNamespaces []string
+	// NamespaceSelector additionally limits admission to the Namespaces with matching labels. It requires a Client.
+	NamespaceSelector labels.Selector
+	// ScoreThreshold is the minimum score needed for a resource to pass. A negative value disables the threshold.
+	ScoreThreshold int
+	MaxSeverity    string
+	// ExemptUsers, ExemptGroups and ExemptServiceAccounts list the requesters which bypass enforcement
+	ExemptUsers           []string
+	ExemptGroups          []string
+	ExemptServiceAccounts []string
+	// CacheSize is the maximum number of cached scan results. Zero disables the cache.
+	CacheSize int
+	CacheTTL  time.Duration
+	// FailurePolicy defines the response if a resource cannot be scanned. Defaults to fail-closed
+	// for enforced requests, including Namespaces which override the mode to enforcing.
+	FailurePolicy string
+	// ScanTimeout is the time budget for a single scan. Zero means no timeout.
+	ScanTimeout time.Duration
+	// AuditReporter aggregates the would-be denials of audit mode in the MondooAuditConfig status. Optional.
+	AuditReporter *AuditReporter
+	// RolloutPercentage is the percentage of requests which are enforced in enforcing mode. A negative
+	// value disables the percentage rollout.
+	RolloutPercentage int
+	// CanaryNamespaces are always enforced in enforcing mode. If a rollout is configured, all other
+	// requests are handled in the RolloutShadowMode.
+	CanaryNamespaces []string
+	// RolloutShadowMode is the mode for the requests which are not enforced. Defaults to audit.
+	RolloutShadowMode string
+	// AnnotateScanResults makes sure scan results are shared with the ScanResultAnnotator
+	AnnotateScanResults bool
+	// AllowedRegistries, DisallowedImageTags and RequireImageDigest restrict the images of workloads.
+	// They are evaluated without a scan.
+	AllowedRegistries   []string
+	DisallowedImageTags []string
+	RequireImageDigest  bool
+	// MaxConcurrentScans limits the number of concurrent scans. Scans beyond the limit are handled
+	// according to the FailurePolicy. Zero means no limit.
+	MaxConcurrentScans int
+	// CircuitBreakerThreshold is the number of consecutive failed scans after which the scan API is
+	// not called for the CircuitBreakerOpenDuration. Zero disables the circuit breaker.
+	CircuitBreakerThreshold    int
+	CircuitBreakerOpenDuration time.Duration
+	// AsyncQueueSize and AsyncWorkers configure the background scans of the async mode. Zero uses the defaults.
+	AsyncQueueSize int
+	AsyncWorkers   int
+	// AsyncAnnotateScanResults annotates resources with the result of their background scan
+	AsyncAnnotateScanResults bool
 }
 
 type MondooWebhook interface {
 	admission.Handler
 	HealthChecker() healthz.Checker
+	// CircuitBreakerChecker returns a check which fails while the circuit breaker for the scan API is
+	// not closed. It must not be part of the probes.
+	CircuitBreakerChecker() healthz.Checker
+	// ScanResultAnnotator returns the mutating webhook which annotates objects with their scan result
+	ScanResultAnnotator() admission.Handler
+	// ImageDigestPinner returns the mutating webhook which pins the images of workloads to digests
+	ImageDigestPinner(images imagecache.ImageCacher, registries []string) admission.Handler
+	// AsyncScanner returns the runnable which scans the resources admitted in async mode
+	AsyncScanner() manager.Runnable
+	// ConnectAuditor returns the validating webhook which records exec, attach and port-forward requests to Pods
+	ConnectAuditor(denyExecInProduction bool, productionNamespaces labels.Selector) admission.Handler
+	// ConnectReporter returns the runnable which reports the recorded interactive accesses to Mondoo
+	ConnectReporter() manager.Runnable
 }
 
 // NewWebhookValidator will initialize a CoreValidator with the provided k8s Client and
@@ -101,6 +224,30 @@ func NewWebhookValidator(opts *NewWebhookValidatorOpts) (MondooWebhook, error) {
 		return nil, err
 	}
 
+	failurePolicy, err := wutils.FailurePolicyStringToAdmissionFailurePolicy(opts.FailurePolicy, webhookMode)
+	if err != nil {
+		return nil, err
+	}
+	// Without an explicit policy the effective mode of each request decides, so Namespaces which
+	// override the mode to enforcing fail closed even if the global mode does not enforce.
+	if opts.FailurePolicy == "" {
+		failurePolicy = ""
+	}
+
+	maxSeverity, err := wutils.SeverityStringToAdmissionSeverity(opts.MaxSeverity)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := admissionPolicy{maxSeverity: maxSeverity}
+	if opts.ScoreThreshold > 100 {
+		return nil, fmt.Errorf("score threshold %d is not valid, must be between 0 and 100", opts.ScoreThreshold)
+	}
+	if opts.ScoreThreshold >= 0 {
+		threshold := uint32(opts.ScoreThreshold)
+		policy.scoreThreshold = &threshold
+	}
+
 	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
 		ApiEndpoint: opts.ScanUrl,
 		Token:       opts.Token,
@@ -109,43 +256,145 @@ func NewWebhookValidator(opts *NewWebhookValidatorOpts) (MondooWebhook, error) {
 		return nil, err
 	}
 
-	return &webhookValidator{
+	rollout, err := newEnforcementRollout(opts.RolloutPercentage, opts.CanaryNamespaces, opts.RolloutShadowMode)
+	if err != nil {
+		return nil, err
+	}
+
+	breaker, err := newCircuitBreaker(opts.CircuitBreakerThreshold, opts.CircuitBreakerOpenDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := newConcurrencyLimiter(opts.MaxConcurrentScans)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache *scanResultCache
+	if opts.CacheSize > 0 {
+		cache = newScanResultCache(opts.CacheSize, opts.CacheTTL)
+	} else if opts.AnnotateScanResults {
+		cache = newScanResultCache(annotatorCacheSize, annotatorCacheTTL)
+	}
+
+	scheme := clientgoscheme.Scheme
+	if opts.Client != nil {
+		scheme = opts.Client.Scheme()
+	}
+
+	apiReader := opts.APIReader
+	if apiReader == nil && opts.Client != nil {
+		apiReader = opts.Client
+	}
+
+	validator := &webhookValidator{
 		client:            opts.Client,
+		apiReader:         apiReader,
+		recorder:          opts.Recorder,
 		mode:              webhookMode,
 		scanner:           clnt,
 		integrationMRN:    opts.IntegrationMrn,
 		clusterID:         opts.ClusterId,
-		uniDecoder:        serializer.NewCodecFactory(opts.Client.Scheme()).UniversalDeserializer(),
+		uniDecoder:        serializer.NewCodecFactory(scheme).UniversalDeserializer(),
 		includeNamespaces: opts.IncludeNamespaces,
 		excludeNamespaces: opts.ExcludeNamespaces,
-	}, nil
+		namespaceSelector: opts.NamespaceSelector,
+		policy:            policy,
+		exemptions: exemptions{
+			users:           opts.ExemptUsers,
+			groups:          opts.ExemptGroups,
+			serviceAccounts: opts.ExemptServiceAccounts,
+		},
+		cache:         cache,
+		policyVersion: policy.version(version.Version),
+		failurePolicy: failurePolicy,
+		scanTimeout:   opts.ScanTimeout,
+		auditReporter: opts.AuditReporter,
+		rollout:       rollout,
+		imagePolicy: imagePolicy{
+			allowedRegistries: opts.AllowedRegistries,
+			disallowedTags:    opts.DisallowedImageTags,
+			requireDigest:     opts.RequireImageDigest,
+		},
+		breaker: breaker,
+		limiter: limiter,
+	}
+
+	validator.connectReporter = newConnectReporter(validator)
+	validator.asyncScanner, err = newAsyncScanner(validator, opts.AsyncQueueSize, opts.AsyncWorkers, opts.AsyncAnnotateScanResults)
+	if err != nil {
+		return nil, err
+	}
+	return validator, nil
 }
 
 func (a *webhookValidator) Handle(ctx context.Context, req admission.Request) (response admission.Response) {
 	resource := fmt.Sprintf("%s/%s", req.Namespace, req.Name)
 	handlerlog.Info("Webhook triggered", "kind", req.Kind.Kind, "resource", resource)
 
+	mode, enabled, overridden := a.namespaceMode(ctx, req.Namespace)
+	decision := decisionErrored
+	defer func() {
+		handlerlog.Info("Admission decision", "kind", req.Kind.Kind, "resource", resource, "mode", decisionMode(mode, enabled), "decision", decision)
+		metricsDecisionsTotal.WithLabelValues(req.Kind.Kind, req.Namespace, decisionMode(mode, enabled), decision).Inc()
+	}()
+
+	if !enabled {
+		handlerlog.Info("skipping because admission is disabled for the namespace", "resource", resource)
+		decision = decisionSkipped
+		return admission.Allowed(defaultScanPass)
+	}
+
+	exempt, err := a.exemptions.isExempt(req.UserInfo)
+	if err != nil {
+		handlerlog.Error(err, "failed to check whether the requester is exempted", "user", req.UserInfo.Username)
+	}
+
+	obj, objErr := a.objFromRaw(req.Object)
+
+	// explicit namespace overrides are not subject to the rollout
+	if mode == mondoov1alpha2.Enforcing && !overridden && a.rollout.enabled() {
+		rolloutMode, err := a.rollout.mode(req.Kind.Kind, obj)
+		if err != nil {
+			handlerlog.Error(err, "failed to evaluate the enforcement rollout", "resource", resource)
+		}
+		if rolloutMode != mode {
+			handlerlog.Info("not enforcing because of the rollout", "kind", req.Kind.Kind, "resource", resource, "mode", rolloutMode)
+		}
+		mode = rolloutMode
+	}
+
 	// the default/safe response
 	response = admission.Allowed(defaultScanPass)
-	if a.mode == mondoov1alpha2.Enforcing {
+	if mode == mondoov1alpha2.Enforcing && !exempt && a.failurePolicy != mondoov1alpha2.FailOpen && a.failurePolicy != mondoov1alpha2.FailOpenWithEvent {
 		response = admission.Denied(defaultScanFail)
 	}
 
-	obj, err := a.objFromRaw(req.Object)
-	if err == nil {
+	if objErr == nil {
 		if !shouldScanObject(obj) {
 			handlerlog.Info("skipping because the resource has a parent", "resource", resource)
+			decision = decisionSkipped
 			return
 		}
+		if objMeta, err := meta.Accessor(obj); err == nil && k8s.IsScanOptedOut(objMeta) {
+			handlerlog.Info("skipping because the resource is opted out of scanning", "resource", resource)
+			if a.auditReporter != nil {
+				a.auditReporter.recordOptedOut()
+			}
+			decision = decisionSkipped
+			return admission.Allowed(defaultScanPass)
+		}
 	}
 
-	skip, err := a.skipNamespace(obj)
+	skip, err := a.skipNamespace(ctx, obj)
 	if err != nil {
 		handlerlog.Error(err, "error while checking whether to skip resource based on namespace")
 		return
 	}
 	if skip {
 		handlerlog.Info("skipping based on namespace filtering", "resource", resource)
+		decision = decisionSkipped
 		return
 	}
 
@@ -162,6 +411,7 @@ func (a *webhookValidator) Handle(ctx context.Context, req admission.Request) (r
 		}
 		if skip {
 			handlerlog.V(9).Info("skipping because the old and new object only differ in resourceVersion; happens with server-side apply")
+			decision = decisionSkipped
 			return
 		}
 	}
@@ -171,78 +421,250 @@ func (a *webhookValidator) Handle(ctx context.Context, req admission.Request) (r
 		handlerlog.Error(err, "failed to set labels for incoming request")
 		return
 	}
+	if exempt {
+		k8sLabels[mondooExemptedLabel] = "true"
+	}
 
-	// Call into Mondoo Scan Service to scan the resource
-	reqData, err := yaml.Marshal(admissionv1.AdmissionReview{
-		TypeMeta: metav1.TypeMeta{Kind: "AdmissionReview", APIVersion: "admission.k8s.io/v1"},
-		Request:  &req.AdmissionRequest,
-	})
+	// exempted requesters are never denied, so they cannot break the glass
+	breakGlassReason := ""
+	if mode == mondoov1alpha2.Enforcing && !exempt {
+		breakGlassReason = breakGlassReasonOf(obj)
+	}
+	if breakGlassReason != "" {
+		// the scan job is sent before the result is known, so the labels mark the requested bypass
+		k8sLabels[mondooBreakGlassReasonLabel] = breakGlassReason
+		k8sLabels[mondooBreakGlassUserLabel] = req.UserInfo.Username
+	}
+
+	// the image policy does not need a scan, so violations are denied right away
+	imageViolations, err := a.imagePolicy.violations(req.Kind.Kind, req.Object.Raw)
 	if err != nil {
-		handlerlog.Error(err, "failed to marshal incoming request")
+		handlerlog.Error(err, "failed to evaluate the image policy", "resource", resource)
 		return
 	}
-
-	mapData := make(map[string]interface{})
-	if err := yaml.Unmarshal(reqData, &mapData); err != nil {
-		handlerlog.Error(err, "failed to unmarshal object to map")
+	if len(imageViolations) > 0 && mode == mondoov1alpha2.Enforcing && !exempt && breakGlassReason == "" {
+		handlerlog.Info("denying because of the image policy", "kind", req.Kind.Kind, "resource", resource, "violations", imageViolations)
+		response = admission.Denied(imagePolicyMessage(imageViolations))
+		decision = decisionDenied
 		return
 	}
 
-	data, err := structpb.NewStruct(mapData)
+	// Call into Mondoo Scan Service to scan the resource
+	scanJob, err := newScanJob(req, k8sLabels)
 	if err != nil {
-		handlerlog.Error(err, "failed to create proto struct from admission request")
+		handlerlog.Error(err, "failed to create scan job from admission request")
 		return
 	}
-	scanJob := &scanapiclient.AdmissionReviewJob{
-		Data:       data,
-		Labels:     k8sLabels,
-		ReportType: scanapiclient.ReportType_ERROR,
-	}
-
-	scanJob.Discovery = &inventory.Discovery{}
-	scanJob.Options = map[string]string{"all-namespaces": "true"}
-	// do not use auto discovery here, because we do not want to scan the cluster
-	scanJob.Discovery.Targets = []string{"pods", "deployments", "daemonsets", "statefulsets", "replicasets", "jobs", "cronjobs"}
 
-	if feature_flags.GetAdmissionReviewDiscovery() {
-		scanJob.Discovery.Targets = append(scanJob.Discovery.Targets, "admissionreviews")
+	if mode == mondoov1alpha2.Async {
+		a.asyncScanner.enqueue(req, scanJob)
+		response = admission.Allowed(admittedForAsyncScan).WithWarnings(imagePolicyWarnings(imageViolations)...)
+		decision = decisionQueued
+		return
 	}
 
-	result, err := a.scanner.RunAdmissionReview(ctx, scanJob)
+	result, err := a.scanWithTimeout(ctx, req, scanJob)
 	if err != nil {
-		handlerlog.Error(err, "error returned from scan request")
-		return
+		handlerlog.Error(err, "error returned from scan request", "failurePolicy", a.failurePolicy)
+		if a.failurePolicy == mondoov1alpha2.FailOpenWithEvent && a.recorder != nil {
+			a.recorder.Eventf(obj, corev1.EventTypeWarning, scanFailedEventReason,
+				"Mondoo could not scan the resource, admitting it without a scan: %s", err.Error())
+		}
+		if len(imageViolations) == 0 {
+			if !response.Allowed && breakGlassReason != "" {
+				a.recordBreakGlass(req, obj, breakGlassReason)
+				response = admission.Allowed(failedScanBreakGlass)
+			}
+			return
+		}
+		// the image policy violations are still reported without a scan result
+		result = &scanapiclient.ScanResult{}
 	}
 
-	passed := false
-	if result.WorstScore != nil && result.WorstScore.Type == scanapiclient.ValidScanResult && result.WorstScore.Value == 100 {
-		passed = true
+	passed := a.policy.passed(result) && len(imageViolations) == 0
+	warnings := append(imagePolicyWarnings(imageViolations), scanWarnings(result)...)
+	message := denialMessage(result)
+	if len(imageViolations) > 0 {
+		message = imagePolicyMessage(imageViolations)
 	}
 
-	handlerlog.Info("Scan result", "shouldAdmit", passed, "kind", req.Kind.Kind, "resource", resource, "worstscore", result.WorstScore)
+	handlerlog.Info("Scan result", "shouldAdmit", passed, "kind", req.Kind.Kind, "resource", resource, "worstscore", result.WorstScore, "mode", mode, "exempt", exempt)
 
 	// Depending on the mode, we either just allow the resource through no matter the scan result
 	// or allow/deny based on the scan result
-	switch a.mode {
+	wouldDeny := false
+	switch mode {
 	case mondoov1alpha2.Permissive:
 		if passed {
 			response = admission.Allowed(passedScan)
 		} else {
-			response = admission.Allowed(failedScanPermitted)
+			response = admission.Allowed(failedScanPermitted).WithWarnings(warnings...)
+			wouldDeny = true
 		}
 	case mondoov1alpha2.Enforcing:
 		if passed {
 			response = admission.Allowed(passedScan)
+		} else if exempt {
+			response = admission.Allowed(failedScanExempted).WithWarnings(warnings...)
+		} else if breakGlassReason != "" {
+			a.recordBreakGlass(req, obj, breakGlassReason)
+			response = admission.Allowed(failedScanBreakGlass).WithWarnings(warnings...)
 		} else {
-			response = admission.Denied(failedScan)
+			response = admission.Denied(message)
+		}
+	case mondoov1alpha2.Audit:
+		if passed {
+			response = admission.Allowed(passedScan)
+		} else if exempt {
+			response = admission.Allowed(failedScanExempted).WithWarnings(warnings...)
+		} else {
+			response = admission.Allowed(failedScanAudited).WithWarnings(a.auditWouldDeny(obj, message, warnings)...)
+			wouldDeny = true
 		}
 	default:
-		err := fmt.Errorf("neither permissive nor enforcing modes defined")
+		err := fmt.Errorf("neither permissive, enforcing, audit nor async modes defined")
 		handlerlog.Error(err, "unexpected runtime environment, allowing the resource through")
 	}
+	decision = decisionFromResponse(response)
+	if wouldDeny {
+		decision = decisionWouldDeny
+	}
 	return
 }
 
+// decisionMode returns the mode label for the decision metrics
+func decisionMode(mode mondoov1alpha2.AdmissionMode, enabled bool) string {
+	if !enabled {
+		return admissionModeDisabled
+	}
+	return string(mode)
+}
+
+// decisionFromResponse returns the decision label for the decision metrics
+func decisionFromResponse(response admission.Response) string {
+	if response.Allowed {
+		return decisionAllowed
+	}
+	return decisionDenied
+}
+
+// newScanJob builds the scan API request for an admission request
+func newScanJob(req admission.Request, labels map[string]string) (*scanapiclient.AdmissionReviewJob, error) {
+	reqData, err := yaml.Marshal(admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{Kind: "AdmissionReview", APIVersion: "admission.k8s.io/v1"},
+		Request:  &req.AdmissionRequest,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal incoming request: %w", err)
+	}
+
+	mapData := make(map[string]interface{})
+	if err := yaml.Unmarshal(reqData, &mapData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal object to map: %w", err)
+	}
+
+	data, err := structpb.NewStruct(mapData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proto struct from admission request: %w", err)
+	}
+	scanJob := &scanapiclient.AdmissionReviewJob{
+		Data:       data,
+		Labels:     labels,
+		ReportType: scanapiclient.ReportType_FULL,
+	}
+
+	scanJob.Discovery = &inventory.Discovery{}
+	scanJob.Options = map[string]string{"all-namespaces": "true"}
+	// do not use auto discovery here, because we do not want to scan the cluster
+	scanJob.Discovery.Targets = discoveryTargets(req.Resource.Resource)
+	return scanJob, nil
+}
+
+// scanWithTimeout runs the scan within the configured time budget
+func (a *webhookValidator) scanWithTimeout(ctx context.Context, req admission.Request, scanJob *scanapiclient.AdmissionReviewJob) (*scanapiclient.ScanResult, error) {
+	if a.scanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.scanTimeout)
+		defer cancel()
+	}
+	return a.scan(ctx, req, scanJob)
+}
+
+// scan runs the admission review. If the cache is enabled, identical objects are answered from the cache.
+func (a *webhookValidator) scan(ctx context.Context, req admission.Request, scanJob *scanapiclient.AdmissionReviewJob) (*scanapiclient.ScanResult, error) {
+	if a.cache == nil {
+		return a.runAdmissionReview(ctx, scanJob)
+	}
+
+	key, err := cacheKey(req.Object.Raw, a.policyVersion)
+	if err != nil {
+		handlerlog.Error(err, "failed to calculate cache key, skipping the cache")
+		return a.runAdmissionReview(ctx, scanJob)
+	}
+
+	if result, ok := a.cache.get(key); ok {
+		metricsCacheHitsTotal.Inc()
+		handlerlog.V(5).Info("using cached scan result", "kind", req.Kind.Kind, "namespace", req.Namespace, "name", req.Name)
+		return result, nil
+	}
+	metricsCacheMissesTotal.Inc()
+
+	result, err := a.runAdmissionReview(ctx, scanJob)
+	if err != nil {
+		return nil, err
+	}
+	// only cache valid results, so failed scans are retried
+	if result.WorstScore != nil && result.WorstScore.Type == scanapiclient.ValidScanResult {
+		a.cache.add(key, result)
+	}
+	return result, nil
+}
+
+// runAdmissionReview calls the scan API and records the latency. Scans are not attempted while the
+// circuit breaker is open or too many scans are in progress.
+func (a *webhookValidator) runAdmissionReview(ctx context.Context, scanJob *scanapiclient.AdmissionReviewJob) (*scanapiclient.ScanResult, error) {
+	release, err := a.limiter.acquire()
+	if err != nil {
+		metricsScansRejectedTotal.WithLabelValues(rejectedConcurrencyLimit).Inc()
+		return nil, err
+	}
+	defer release()
+
+	if err := a.breaker.allow(); err != nil {
+		metricsScansRejectedTotal.WithLabelValues(rejectedCircuitOpen).Inc()
+		return nil, err
+	}
+
+	metricsScansInFlight.Inc()
+	defer metricsScansInFlight.Dec()
+
+	start := time.Now()
+	result, err := a.scanner.RunAdmissionReview(ctx, scanJob)
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	a.breaker.record(err)
+	metricsScanDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+	return result, err
+}
+
+// discoveryTargets returns the discovery targets for the scan of an admission request. Resources
+// without a dedicated discovery target are scanned as admission reviews.
+func discoveryTargets(resource string) []string {
+	targets := []string{"pods", "deployments", "daemonsets", "statefulsets", "replicasets", "jobs", "cronjobs"}
+
+	target, known := resourceDiscoveryTargets[resource]
+	if known && !slices.Contains(targets, target) {
+		targets = append(targets, target)
+	}
+
+	if (resource != "" && !known) || feature_flags.GetAdmissionReviewDiscovery() {
+		targets = append(targets, "admissionreviews")
+	}
+	return targets
+}
+
 func (a *webhookValidator) generateLabels(req admission.Request, obj runtime.Object) (map[string]string, error) {
 	labels, err := generateLabelsFromAdmissionRequest(req, obj)
 	if err != nil {
@@ -257,10 +679,65 @@ func (a *webhookValidator) generateLabels(req admission.Request, obj runtime.Obj
 	return labels, nil
 }
 
+// breakGlassReasonOf returns the reason from the break-glass annotation of the object
+func breakGlassReasonOf(obj runtime.Object) string {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return objMeta.GetAnnotations()[mondooBreakGlassAnnotation]
+}
+
+// recordBreakGlass records a denial which was overridden by the break-glass annotation as an Event
+// and a metric
+func (a *webhookValidator) recordBreakGlass(req admission.Request, obj runtime.Object, reason string) {
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	handlerlog.Info("break-glass annotation present, admitting the resource regardless of the scan result",
+		"kind", req.Kind.Kind, "namespace", objMeta.GetNamespace(), "name", objMeta.GetName(), "user", req.UserInfo.Username, "reason", reason)
+	metricsBreakGlassTotal.WithLabelValues(req.Kind.Kind, objMeta.GetNamespace(), req.UserInfo.Username).Inc()
+	if a.recorder != nil {
+		a.recorder.Eventf(obj, corev1.EventTypeWarning, breakGlassEventReason,
+			"%s bypassed Mondoo admission enforcement: %s", req.UserInfo.Username, reason)
+	}
+}
+
+// auditWouldDeny records a resource admitted in audit mode which enforcing mode would have denied.
+// It returns the warnings for the client.
+func (a *webhookValidator) auditWouldDeny(obj runtime.Object, message string, warnings []string) []string {
+	if a.recorder != nil {
+		a.recorder.Event(obj, corev1.EventTypeWarning, wouldDenyEventReason, "Mondoo enforcing mode would deny the resource: "+message)
+	}
+	if a.auditReporter != nil {
+		a.auditReporter.record()
+	}
+	return append([]string{"Mondoo enforcing mode would deny this resource"}, warnings...)
+}
+
 func (a *webhookValidator) HealthChecker() healthz.Checker {
 	return func(req *http.Request) error {
 		_, err := a.scanner.HealthCheck(req.Context(), &common.HealthCheckRequest{})
-		return err
+		if err != nil {
+			metricsScanApiHealthy.Set(0)
+			return err
+		}
+		metricsScanApiHealthy.Set(1)
+		// The circuit breaker state is reported by its own check. Failing the probes would restart the
+		// webhook, which resets the breaker, or remove it from the endpoints, so the API server's
+		// failure policy decides instead of the configured one.
+		return nil
+	}
+}
+
+func (a *webhookValidator) CircuitBreakerChecker() healthz.Checker {
+	return func(_ *http.Request) error {
+		if state := a.breaker.currentState(); state != breakerClosed {
+			return fmt.Errorf("circuit breaker for the scan API is %s", state)
+		}
+		return nil
 	}
 }
 
@@ -275,15 +752,28 @@ func (a *webhookValidator) objFromRaw(rawObj runtime.RawExtension) (runtime.Obje
 	return obj, err
 }
 
-func (a *webhookValidator) skipNamespace(obj runtime.Object) (bool, error) {
+func (a *webhookValidator) skipNamespace(ctx context.Context, obj runtime.Object) (bool, error) {
 	objmeta, err := meta.Accessor(obj)
 	if err != nil {
 		handlerlog.Error(err, "error getting metadata from object", "type", reflect.TypeOf(obj))
 		return false, nil
 	}
 
+	// cluster-scoped objects are not subject to namespace filtering
+	if objmeta.GetNamespace() == "" {
+		return false, nil
+	}
+
 	allow, err := utils.AllowNamespace(objmeta.GetNamespace(), a.includeNamespaces, a.excludeNamespaces)
-	return !allow, err
+	if err != nil || !allow || a.namespaceSelector == nil || a.client == nil {
+		return !allow, err
+	}
+
+	ns := &corev1.Namespace{}
+	if err := a.client.Get(ctx, client.ObjectKey{Name: objmeta.GetNamespace()}, ns); err != nil {
+		return false, err
+	}
+	return !a.namespaceSelector.Matches(labels.Set(ns.Labels)), nil
 }
 
 func generateLabelsFromAdmissionRequest(req admission.Request, obj runtime.Object) (map[string]string, error) {
@@ -353,6 +843,10 @@ func objectsOnlyDifferInSSAFields(admissionRequest admissionv1.AdmissionRequest)
 	oldObjMapData["metadata"].(map[string]interface{})["managedFields"] = ""
 	objMapData["metadata"].(map[string]interface{})["managedFields"] = ""
 
+	// the background scans of the async mode set the scan result annotations, which must not trigger another scan
+	removeScanResultAnnotations(oldObjMapData["metadata"].(map[string]interface{}))
+	removeScanResultAnnotations(objMapData["metadata"].(map[string]interface{}))
+
 	if reflect.DeepEqual(oldObjMapData, objMapData) {
 		return true, nil
 	}
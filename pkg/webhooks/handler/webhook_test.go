@@ -9,20 +9,25 @@ import (
 	"os"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 
 	admissionv1 "k8s.io/api/admission/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/yaml"
 
@@ -199,6 +204,499 @@ func TestWebhookValidate(t *testing.T) {
 	}
 }
 
+func TestWebhookScorePolicy(t *testing.T) {
+	decoder := setupDecoder(t)
+	tests := []struct {
+		name          string
+		score         uint32
+		policy        admissionPolicy
+		expectAllowed bool
+		expectReason  string
+	}{
+		{
+			name:          "default policy denies imperfect score",
+			score:         99,
+			expectAllowed: false,
+			expectReason:  failedScan,
+		},
+		{
+			name:          "default policy admits perfect score",
+			score:         100,
+			expectAllowed: true,
+			expectReason:  passedScan,
+		},
+		{
+			name:          "score above threshold",
+			score:         80,
+			policy:        admissionPolicy{scoreThreshold: ptr.To(uint32(70))},
+			expectAllowed: true,
+			expectReason:  passedScan,
+		},
+		{
+			name:          "score below threshold",
+			score:         60,
+			policy:        admissionPolicy{scoreThreshold: ptr.To(uint32(70))},
+			expectAllowed: false,
+			expectReason:  failedScan,
+		},
+		{
+			name:          "high finding with max severity high",
+			score:         20,
+			policy:        admissionPolicy{maxSeverity: mondoov1alpha2.SeverityHigh},
+			expectAllowed: true,
+			expectReason:  passedScan,
+		},
+		{
+			name:          "critical finding with max severity high",
+			score:         5,
+			policy:        admissionPolicy{maxSeverity: mondoov1alpha2.SeverityHigh},
+			expectAllowed: false,
+			expectReason:  failedScan,
+		},
+		{
+			name:          "threshold and severity both have to pass",
+			score:         50,
+			policy:        admissionPolicy{scoreThreshold: ptr.To(uint32(60)), maxSeverity: mondoov1alpha2.SeverityHigh},
+			expectAllowed: false,
+			expectReason:  failedScan,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+				Ok: true,
+				WorstScore: &scanapiclient.Score{
+					Type:  scanapiclient.ValidScanResult,
+					Value: test.score,
+				},
+			})
+			defer testserver.Close()
+			clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+				ApiEndpoint: testserver.URL,
+			})
+			require.NoError(t, err)
+
+			validator := &webhookValidator{
+				decoder:    decoder,
+				mode:       mondoov1alpha2.Enforcing,
+				scanner:    clnt,
+				uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+				policy:     test.policy,
+			}
+
+			request := admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Object: testExampleDeployment(),
+				},
+			}
+
+			// Act
+			response := validator.Handle(context.TODO(), request)
+
+			// Assert
+			assert.Equal(t, test.expectAllowed, response.AdmissionResponse.Allowed)
+			assert.Equal(t, test.expectReason, string(response.AdmissionResponse.Result.Message))
+		})
+	}
+}
+
+func TestWebhookNamespaceModeOverride(t *testing.T) {
+	decoder := setupDecoder(t)
+	tests := []struct {
+		name          string
+		mode          mondoov1alpha2.AdmissionMode
+		labels        map[string]string
+		annotations   map[string]string
+		expectAllowed bool
+		expectReason  string
+	}{
+		{
+			name:          "no override",
+			mode:          mondoov1alpha2.Permissive,
+			expectAllowed: true,
+			expectReason:  failedScanPermitted,
+		},
+		{
+			name:          "enforcing label",
+			mode:          mondoov1alpha2.Permissive,
+			labels:        map[string]string{mondooAdmissionModeKey: string(mondoov1alpha2.Enforcing)},
+			expectAllowed: false,
+			expectReason:  failedScan,
+		},
+		{
+			name:          "permissive annotation",
+			mode:          mondoov1alpha2.Enforcing,
+			annotations:   map[string]string{mondooAdmissionModeKey: string(mondoov1alpha2.Permissive)},
+			expectAllowed: true,
+			expectReason:  failedScanPermitted,
+		},
+		{
+			name:          "label takes precedence over annotation",
+			mode:          mondoov1alpha2.Permissive,
+			labels:        map[string]string{mondooAdmissionModeKey: string(mondoov1alpha2.Enforcing)},
+			annotations:   map[string]string{mondooAdmissionModeKey: string(mondoov1alpha2.Permissive)},
+			expectAllowed: false,
+			expectReason:  failedScan,
+		},
+		{
+			name:          "disabled",
+			mode:          mondoov1alpha2.Enforcing,
+			labels:        map[string]string{mondooAdmissionModeKey: admissionModeDisabled},
+			expectAllowed: true,
+			expectReason:  defaultScanPass,
+		},
+		{
+			name:          "invalid override falls back to global mode",
+			mode:          mondoov1alpha2.Enforcing,
+			labels:        map[string]string{mondooAdmissionModeKey: "invalid"},
+			expectAllowed: false,
+			expectReason:  failedScan,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+				Ok: true,
+				WorstScore: &scanapiclient.Score{
+					Type:  scanapiclient.ValidScanResult,
+					Value: 50,
+				},
+			})
+			defer testserver.Close()
+			clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+				ApiEndpoint: testserver.URL,
+			})
+			require.NoError(t, err)
+
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        testNamespace,
+					Labels:      test.labels,
+					Annotations: test.annotations,
+				},
+			}
+
+			validator := &webhookValidator{
+				client:     fake.NewClientBuilder().WithObjects(ns).Build(),
+				decoder:    decoder,
+				mode:       test.mode,
+				scanner:    clnt,
+				uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+			}
+
+			request := admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{
+					Namespace: testNamespace,
+					Object:    testExamplePod(),
+				},
+			}
+
+			// Act
+			response := validator.Handle(context.TODO(), request)
+
+			// Assert
+			assert.Equal(t, test.expectAllowed, response.AdmissionResponse.Allowed)
+			assert.Equal(t, test.expectReason, string(response.AdmissionResponse.Result.Message))
+		})
+	}
+}
+
+func TestWebhookFailingChecks(t *testing.T) {
+	decoder := setupDecoder(t)
+	testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok: true,
+		WorstScore: &scanapiclient.Score{
+			Type:  scanapiclient.ValidScanResult,
+			Value: 20,
+		},
+		Full: testFullReport(testCheck("Container should not run as root", 20)),
+	})
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Object: testExampleDeployment(),
+		},
+	}
+
+	validator := &webhookValidator{
+		decoder:    decoder,
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    clnt,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+	}
+	response := validator.Handle(context.TODO(), request)
+	assert.False(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScan+": Container should not run as root (score 20)", response.AdmissionResponse.Result.Message)
+
+	validator.mode = mondoov1alpha2.Permissive
+	response = validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScanPermitted, response.AdmissionResponse.Result.Message)
+	assert.Equal(t, []string{"Mondoo: failing check Container should not run as root (score 20)"}, response.AdmissionResponse.Warnings)
+}
+
+func TestWebhookExemptedRequester(t *testing.T) {
+	decoder := setupDecoder(t)
+	testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok: true,
+		WorstScore: &scanapiclient.Score{
+			Type:  scanapiclient.ValidScanResult,
+			Value: 20,
+		},
+	})
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	validator := &webhookValidator{
+		decoder:    decoder,
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    clnt,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		exemptions: exemptions{serviceAccounts: []string{"argocd/*"}},
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Object:   testExampleDeployment(),
+			UserInfo: authenticationv1.UserInfo{Username: "system:serviceaccount:argocd:argocd-application-controller"},
+		},
+	}
+	response := validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScanExempted, response.AdmissionResponse.Result.Message)
+
+	request.UserInfo = authenticationv1.UserInfo{Username: "bob"}
+	response = validator.Handle(context.TODO(), request)
+	assert.False(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScan, response.AdmissionResponse.Result.Message)
+}
+
+func TestWebhookBreakGlass(t *testing.T) {
+	decoder := setupDecoder(t)
+	testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok: true,
+		WorstScore: &scanapiclient.Score{
+			Type:  scanapiclient.ValidScanResult,
+			Value: 20,
+		},
+	})
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	validator := &webhookValidator{
+		decoder:    decoder,
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    clnt,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		recorder:   recorder,
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Object: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Annotations = map[string]string{mondooBreakGlassAnnotation: "INC-1234 hotfix"}
+			}),
+			UserInfo: authenticationv1.UserInfo{Username: "alice"},
+		},
+	}
+	counter := metricsBreakGlassTotal.WithLabelValues("Deployment", testNamespace, "alice")
+	before := testutil.ToFloat64(counter)
+
+	response := validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScanBreakGlass, response.AdmissionResponse.Result.Message)
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Warning MondooBreakGlass alice bypassed Mondoo admission enforcement: INC-1234 hotfix", <-recorder.Events)
+
+	// the annotation is ignored in permissive mode
+	validator.mode = mondoov1alpha2.Permissive
+	response = validator.Handle(context.TODO(), request)
+	assert.Equal(t, failedScanPermitted, response.AdmissionResponse.Result.Message)
+	assert.Empty(t, recorder.Events)
+}
+
+func TestWebhookBreakGlass_NotOverriding(t *testing.T) {
+	decoder := setupDecoder(t)
+	testserver := fakeserver.FakeServer()
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	validator := &webhookValidator{
+		decoder:    decoder,
+		mode:       mondoov1alpha2.Enforcing,
+		scanner:    clnt,
+		uniDecoder: serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		recorder:   recorder,
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Object: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Annotations = map[string]string{mondooBreakGlassAnnotation: "INC-1234 hotfix"}
+			}),
+			UserInfo: authenticationv1.UserInfo{Username: "carol"},
+		},
+	}
+	counter := metricsBreakGlassTotal.WithLabelValues("Deployment", testNamespace, "carol")
+	before := testutil.ToFloat64(counter)
+
+	// a passing resource is admitted without a bypass
+	response := validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, passedScan, response.AdmissionResponse.Result.Message)
+	assert.Equal(t, before, testutil.ToFloat64(counter))
+	assert.Empty(t, recorder.Events)
+
+	// exempted requesters are admitted without a bypass
+	failingServer := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok:         true,
+		WorstScore: &scanapiclient.Score{Type: scanapiclient.ValidScanResult, Value: 20},
+	})
+	defer failingServer.Close()
+	validator.scanner, err = scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{ApiEndpoint: failingServer.URL})
+	require.NoError(t, err)
+	validator.exemptions = exemptions{users: []string{"carol"}}
+
+	response = validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScanExempted, response.AdmissionResponse.Result.Message)
+	assert.Equal(t, before, testutil.ToFloat64(counter))
+	assert.Empty(t, recorder.Events)
+}
+
+func TestWebhookAuditMode(t *testing.T) {
+	decoder := setupDecoder(t)
+	testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok: true,
+		WorstScore: &scanapiclient.Score{
+			Type:  scanapiclient.ValidScanResult,
+			Value: 20,
+		},
+		Full: testFullReport(testCheck("Container should not run as root", 20)),
+	})
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	reporter := NewAuditReporter(nil, nil, "mondoo-client", "mondoo-operator")
+	validator := &webhookValidator{
+		decoder:       decoder,
+		mode:          mondoov1alpha2.Audit,
+		scanner:       clnt,
+		uniDecoder:    serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		recorder:      recorder,
+		auditReporter: reporter,
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Object: testExampleDeployment(),
+		},
+	}
+
+	response := validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScanAudited, response.AdmissionResponse.Result.Message)
+	assert.Equal(t, []string{
+		"Mondoo enforcing mode would deny this resource",
+		"Mondoo: failing check Container should not run as root (score 20)",
+	}, response.AdmissionResponse.Warnings)
+	require.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Warning MondooWouldDeny Mondoo enforcing mode would deny the resource: "+failedScan+": Container should not run as root (score 20)", <-recorder.Events)
+	assert.Equal(t, int64(1), reporter.pending)
+
+	// exempted requesters would have been admitted in enforcing mode, too
+	validator.exemptions = exemptions{users: []string{"alice"}}
+	request.UserInfo = authenticationv1.UserInfo{Username: "alice"}
+	response = validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, failedScanExempted, response.AdmissionResponse.Result.Message)
+	assert.Empty(t, recorder.Events)
+	assert.Equal(t, int64(1), reporter.pending)
+}
+
+func TestWebhookScanOptOut(t *testing.T) {
+	decoder := setupDecoder(t)
+	testserver := fakeserver.FakeServerWithResult(&scanapiclient.ScanResult{
+		Ok: true,
+		WorstScore: &scanapiclient.Score{
+			Type:  scanapiclient.ValidScanResult,
+			Value: 20,
+		},
+	})
+	defer testserver.Close()
+	clnt, err := scanapiclient.NewClient(scanapiclient.ScanApiClientOptions{
+		ApiEndpoint: testserver.URL,
+	})
+	require.NoError(t, err)
+
+	reporter := NewAuditReporter(nil, nil, "mondoo-client", "mondoo-operator")
+	validator := &webhookValidator{
+		decoder:       decoder,
+		mode:          mondoov1alpha2.Enforcing,
+		scanner:       clnt,
+		uniDecoder:    serializer.NewCodecFactory(clientgoscheme.Scheme).UniversalDeserializer(),
+		auditReporter: reporter,
+	}
+
+	request := admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind: metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Object: testExampleDeployment(func(d *appsv1.Deployment) {
+				d.Annotations = map[string]string{constants.MondooScanOptOutKey: "false"}
+			}),
+		},
+	}
+
+	// the failing object is admitted without a scan
+	response := validator.Handle(context.TODO(), request)
+	assert.True(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, defaultScanPass, response.AdmissionResponse.Result.Message)
+	assert.Equal(t, int64(1), reporter.pendingOptedOut)
+
+	request.Object = testExampleDeployment(func(d *appsv1.Deployment) {
+		d.Annotations = map[string]string{constants.MondooScanOptOutKey: "true"}
+	})
+	response = validator.Handle(context.TODO(), request)
+	assert.False(t, response.AdmissionResponse.Allowed)
+	assert.Equal(t, int64(1), reporter.pendingOptedOut)
+}
+
+func TestDiscoveryTargets(t *testing.T) {
+	workloads := []string{"pods", "deployments", "daemonsets", "statefulsets", "replicasets", "jobs", "cronjobs"}
+
+	assert.Equal(t, workloads, discoveryTargets("deployments"))
+	assert.Equal(t, append(workloads, "ingresses"), discoveryTargets("ingresses"))
+	assert.Equal(t, append(workloads, "admissionreviews"), discoveryTargets("clusterroles"))
+}
+
 var webhookPayload = mustRead("../../../tests/data/webhook-payload.json")
 
 func TestLabels(t *testing.T) {
@@ -250,6 +748,7 @@ func TestWebhookNamespaceFiltering(t *testing.T) {
 		expectReason string
 		excludeList  []string
 		includeList  []string
+		selector     string
 		object       runtime.RawExtension
 	}{
 		{
@@ -295,6 +794,25 @@ func TestWebhookNamespaceFiltering(t *testing.T) {
 			object:       testExamplePod(),
 			excludeList:  []string{"test*"},
 		},
+		{
+			name:         "namespace matches selector",
+			expectReason: passedScan,
+			object:       testExamplePod(),
+			selector:     "mondoo=enabled",
+		},
+		{
+			name:         "namespace does not match selector",
+			expectReason: defaultScanPass,
+			object:       testExamplePod(),
+			selector:     "mondoo=disabled",
+		},
+		{
+			name:         "excluded namespace matches selector",
+			expectReason: defaultScanPass,
+			object:       testExamplePod(),
+			excludeList:  []string{testNamespace},
+			selector:     "mondoo=enabled",
+		},
 	}
 
 	for _, test := range tests {
@@ -306,7 +824,18 @@ func TestWebhookNamespaceFiltering(t *testing.T) {
 			})
 			require.NoError(t, err)
 
+			namespace := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: testNamespace, Labels: map[string]string{"mondoo": "enabled"}},
+			}
+			var selector labels.Selector
+			if test.selector != "" {
+				selector, err = labels.Parse(test.selector)
+				require.NoError(t, err)
+			}
+
 			validator := &webhookValidator{
+				client:            fake.NewClientBuilder().WithObjects(namespace).Build(),
+				namespaceSelector: selector,
 				excludeNamespaces: test.excludeList,
 				includeNamespaces: test.includeList,
 				decoder:           decoder,
@@ -400,7 +929,7 @@ func testExampleJob(modifiers ...func(*batchv1.Job)) runtime.RawExtension {
 	}
 }
 
-func testExampleDeployment() runtime.RawExtension {
+func testExampleDeployment(modifiers ...func(*appsv1.Deployment)) runtime.RawExtension {
 	dep := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{Kind: "Deployment"},
 		ObjectMeta: metav1.ObjectMeta{
@@ -409,6 +938,10 @@ func testExampleDeployment() runtime.RawExtension {
 		},
 	}
 
+	for _, m := range modifiers {
+		m(dep)
+	}
+
 	data, err := json.Marshal(dep)
 	if err != nil {
 		panic(err)
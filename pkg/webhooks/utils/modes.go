@@ -17,7 +17,32 @@ func ModeStringToAdmissionMode(mode string) (mondoov1alpha2.AdmissionMode, error
 		return mondoov1alpha2.Enforcing, nil
 	case string(mondoov1alpha2.Permissive):
 		return mondoov1alpha2.Permissive, nil
+	case string(mondoov1alpha2.Audit):
+		return mondoov1alpha2.Audit, nil
+	case string(mondoov1alpha2.Async):
+		return mondoov1alpha2.Async, nil
 	default:
 		return mondoov1alpha2.Permissive, fmt.Errorf("mode %s is not valid", mode)
 	}
 }
+
+// FailurePolicyStringToAdmissionFailurePolicy will take a string and convert it to a known
+// admission failure policy, or sets an error on return if it is an unknown/invalid policy.
+// An empty string results in the default for the provided mode.
+func FailurePolicyStringToAdmissionFailurePolicy(policy string, mode mondoov1alpha2.AdmissionMode) (mondoov1alpha2.AdmissionFailurePolicy, error) {
+	switch policy {
+	case string(mondoov1alpha2.FailOpen):
+		return mondoov1alpha2.FailOpen, nil
+	case string(mondoov1alpha2.FailClosed):
+		return mondoov1alpha2.FailClosed, nil
+	case string(mondoov1alpha2.FailOpenWithEvent):
+		return mondoov1alpha2.FailOpenWithEvent, nil
+	case "":
+		if mode == mondoov1alpha2.Enforcing {
+			return mondoov1alpha2.FailClosed, nil
+		}
+		return mondoov1alpha2.FailOpen, nil
+	default:
+		return mondoov1alpha2.FailOpen, fmt.Errorf("failure policy %s is not valid", policy)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) Mondoo, Inc.
+// SPDX-License-Identifier: BUSL-1.1
+
+package utils
+
+import (
+	"fmt"
+
+	mondoov1alpha2 "go.mondoo.com/mondoo-operator/api/v1alpha2"
+)
+
+// severityRank orders the severities from least to most severe.
+var severityRank = map[mondoov1alpha2.AdmissionSeverity]int{
+	mondoov1alpha2.SeverityNone:     0,
+	mondoov1alpha2.SeverityLow:      1,
+	mondoov1alpha2.SeverityMedium:   2,
+	mondoov1alpha2.SeverityHigh:     3,
+	mondoov1alpha2.SeverityCritical: 4,
+}
+
+// SeverityStringToAdmissionSeverity will take a string and convert it to a known
+// admission severity, or sets an error on return if it is an unknown/invalid severity.
+// An empty string is valid and means that no maximum severity is configured.
+func SeverityStringToAdmissionSeverity(severity string) (mondoov1alpha2.AdmissionSeverity, error) {
+	if severity == "" {
+		return "", nil
+	}
+	s := mondoov1alpha2.AdmissionSeverity(severity)
+	if _, ok := severityRank[s]; !ok {
+		return "", fmt.Errorf("severity %s is not valid", severity)
+	}
+	return s, nil
+}
+
+// SeverityFromScore maps a Mondoo score (0-100) to a severity. The impact of a check is
+// the inverse of its score, so a low score means a severe finding.
+func SeverityFromScore(score uint32) mondoov1alpha2.AdmissionSeverity {
+	switch {
+	case score >= 100:
+		return mondoov1alpha2.SeverityNone
+	case score > 60:
+		return mondoov1alpha2.SeverityLow
+	case score > 30:
+		return mondoov1alpha2.SeverityMedium
+	case score > 10:
+		return mondoov1alpha2.SeverityHigh
+	default:
+		return mondoov1alpha2.SeverityCritical
+	}
+}
+
+// SeverityExceeds returns true if severity is more severe than max.
+func SeverityExceeds(severity, max mondoov1alpha2.AdmissionSeverity) bool {
+	return severityRank[severity] > severityRank[max]
+}